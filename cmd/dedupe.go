@@ -0,0 +1,96 @@
+/*
+Copyright © 2025 Daniel Vergara daniel.omar.vergara@gmail.com
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/database"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository/postgres"
+)
+
+var dedupeDryRun bool
+
+// dedupeCmd represents the dedupe command
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Merges relays stored under different spellings of the same URL",
+	Long: `Rewrites every relay URL to its canonical form (lowercase scheme and host, no default port,
+no trailing slash), merging the relays that turn out to be the same one along with their health checks.
+
+Relays with URLs that are not ws:// or wss:// are reported and left untouched.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbConfig := database.Config{
+			Host:     dbHost,
+			Port:     dbPort,
+			User:     dbUser,
+			Password: dbPass,
+			DBName:   dbName,
+		}
+
+		// Create a PostgreSQL database pool of connections given config data.
+		db, err := database.NewPostgresDB(dbConfig)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = db.Close()
+		}()
+
+		ctx := context.Background()
+		relayRepo := postgres.NewRelayRepository(db)
+
+		relays, err := relayRepo.List(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		urls := make([]string, len(relays))
+		for i, r := range relays {
+			urls[i] = r.URL
+		}
+
+		groups, invalid := relayurl.Duplicates(urls)
+
+		for _, u := range invalid {
+			log.Printf("skipping invalid relay url %q", u)
+		}
+
+		canonicals := make([]string, 0, len(groups))
+		for canonical := range groups {
+			canonicals = append(canonicals, canonical)
+		}
+		slices.Sort(canonicals)
+
+		for _, canonical := range canonicals {
+			duplicates := slices.DeleteFunc(groups[canonical], func(u string) bool { return u == canonical })
+
+			log.Printf("merging %q into %s", duplicates, canonical)
+
+			if dedupeDryRun {
+				continue
+			}
+
+			if err := relayRepo.MergeRelays(ctx, canonical, duplicates); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("%d relays merged or renamed, %d invalid relay urls\n", len(canonicals), len(invalid))
+
+		return nil
+	},
+}
+
+func init() {
+	dedupeCmd.Flags().BoolVar(&dedupeDryRun, "dry-run", false, "only print the relays that would be merged")
+
+	rootCmd.AddCommand(dedupeCmd)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2025 Daniel Vergara daniel.omar.vergara@gmail.com
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/database"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/discovery"
+)
+
+var (
+	discoverySeedRelays []string
+	discoveryLimit      int
+	discoveryTimeout    time.Duration
+)
+
+// discoverCmd represents the discover command
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Discovers new relays from NIP-65 relay lists, contact lists and other monitors' 30166 events",
+	Long: `Crawls the seed relays for kind 10002 relay lists, kind 3 contact list relay hints
+and the "d" tags of other monitors' kind 30166 events, and adds the relays found to the database.
+
+Seed relays are read from NOSTRICH_WATCH_MONITOR_DISCOVERY_RELAYS (comma separated) or the --relays flag.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := &slog.HandlerOptions{
+			Level:     slog.LevelDebug, // Set minimum log level
+			AddSource: true,            // Include source code location
+		}
+
+		logger := slog.New(slog.NewJSONHandler(os.Stdout, opts))
+
+		if len(discoverySeedRelays) == 0 {
+			return fmt.Errorf("no seed relays, set NOSTRICH_WATCH_MONITOR_DISCOVERY_RELAYS or --relays")
+		}
+
+		dbConfig := database.Config{
+			Host:     dbHost,
+			Port:     dbPort,
+			User:     dbUser,
+			Password: dbPass,
+			DBName:   dbName,
+		}
+
+		// Create a PostgreSQL database pool of connections given config data.
+		db, err := database.NewPostgresDB(dbConfig)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = db.Close()
+		}()
+
+		d := discovery.NewDiscoverer(
+			discovery.WithDB(db),
+			discovery.WithSeedRelays(discoverySeedRelays),
+			discovery.WithLimit(discoveryLimit),
+			discovery.WithTimeout(discoveryTimeout),
+			discovery.WithLogger(logger),
+		)
+
+		result, err := d.Discover(context.Background())
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf(
+			"Crawled %d events, found %d relays, %d of them new\n",
+			result.Events,
+			result.Candidates,
+			result.Created,
+		)
+
+		return nil
+	},
+}
+
+func init() {
+	discoverCmd.Flags().StringSliceVar(
+		&discoverySeedRelays,
+		"relays",
+		splitList(os.Getenv("NOSTRICH_WATCH_MONITOR_DISCOVERY_RELAYS")),
+		"seed relays to crawl",
+	)
+	discoverCmd.Flags().IntVar(&discoveryLimit, "limit", 500, "events of each kind requested per seed relay")
+	discoverCmd.Flags().DurationVar(&discoveryTimeout, "timeout", 30*time.Second, "how long to crawl the seed relays for each kind of event")
+
+	rootCmd.AddCommand(discoverCmd)
+}
+
+// splitList splits a comma separated list, such as relay URLs or check names, dropping the empty entries.
+func splitList(s string) []string {
+	var items []string
+
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
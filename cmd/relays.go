@@ -0,0 +1,315 @@
+/*
+Copyright © 2025 Daniel Vergara daniel.omar.vergara@gmail.com
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/database"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository/postgres"
+)
+
+var relaysStatuses []string
+
+// relaysCmd represents the relays command
+var relaysCmd = &cobra.Command{
+	Use:   "relays",
+	Short: "Manages the relays being monitored",
+}
+
+var relaysAddCmd = &cobra.Command{
+	Use:   "add <url>...",
+	Short: "Adds relays to monitor",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withRelayRepository(func(ctx context.Context, relayRepo repository.RelayRepository) error {
+			added, err := addRelays(ctx, relayRepo, args, domain.DiscoveredViaManual, cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%d relays added\n", added)
+
+			return nil
+		})
+	},
+}
+
+var relaysRemoveCmd = &cobra.Command{
+	Use:   "remove <url>...",
+	Short: "Stops monitoring relays for good, discovery won't add them back",
+	Long: `Stops monitoring relays for good. Removed relays are never checked nor shown again, but their history is kept
+and discovery won't add them back. They can be monitored again with relays add or relays enable.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setRelaysStatus(cmd, args, domain.RelayStatusRemoved)
+	},
+}
+
+var relaysDisableCmd = &cobra.Command{
+	Use:   "disable <url>...",
+	Short: "Stops checking relays, keeping their history",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setRelaysStatus(cmd, args, domain.RelayStatusDisabled)
+	},
+}
+
+var relaysEnableCmd = &cobra.Command{
+	Use:   "enable <url>...",
+	Short: "Checks disabled, retired or removed relays at the regular frequency again",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setRelaysStatus(cmd, args, domain.RelayStatusActive)
+	},
+}
+
+var relaysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the relays and their status",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withRelayRepository(func(ctx context.Context, relayRepo repository.RelayRepository) error {
+			relays, err := relayRepo.List(ctx, &repository.ListOption{Statuses: relaysStatuses})
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "URL\tSTATUS\tSOURCE\tONLINE\tLAST CHECK")
+
+			for _, r := range relays {
+				source := "-"
+				if r.DiscoveredVia != nil {
+					source = *r.DiscoveredVia
+				}
+
+				online, lastCheck := "-", "never"
+				if r.HealthCheck != nil {
+					if r.WebsocketSuccess != nil {
+						online = fmt.Sprintf("%t", *r.WebsocketSuccess)
+					}
+					lastCheck = r.HealthCheck.CreatedAt.Format(time.RFC3339)
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.URL, r.Status, source, online, lastCheck)
+			}
+
+			return w.Flush()
+		})
+	},
+}
+
+var relaysImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Adds the relays listed in a file, one URL per line",
+	Long: `Adds the relays listed in a file, one URL per line, or in the standard input when no file is given.
+Empty lines and lines starting with # are ignored. The output of relays export can be imported as is.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		in := cmd.InOrStdin()
+
+		if len(args) == 1 && args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", args[0], err)
+			}
+			defer func() {
+				_ = file.Close()
+			}()
+
+			in = file
+		}
+
+		var urls []string
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				urls = append(urls, line)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read relays: %w", err)
+		}
+
+		return withRelayRepository(func(ctx context.Context, relayRepo repository.RelayRepository) error {
+			added, err := addRelays(ctx, relayRepo, urls, domain.DiscoveredViaImport, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%d of %d relays imported\n", added, len(urls))
+
+			return nil
+		})
+	},
+}
+
+var relaysExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Prints the relay URLs, one per line",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withRelayRepository(func(ctx context.Context, relayRepo repository.RelayRepository) error {
+			relays, err := relayRepo.List(ctx, &repository.ListOption{Statuses: relaysStatuses})
+			if err != nil {
+				return err
+			}
+
+			for _, r := range relays {
+				fmt.Fprintln(cmd.OutOrStdout(), r.URL)
+			}
+
+			return nil
+		})
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{relaysListCmd, relaysExportCmd} {
+		c.Flags().StringSliceVar(
+			&relaysStatuses,
+			"status",
+			nil,
+			fmt.Sprintf("only the relays in these statuses (%s)", strings.Join(domain.RelayStatuses, ", ")),
+		)
+	}
+
+	relaysCmd.AddCommand(
+		relaysAddCmd,
+		relaysRemoveCmd,
+		relaysDisableCmd,
+		relaysEnableCmd,
+		relaysListCmd,
+		relaysImportCmd,
+		relaysExportCmd,
+	)
+
+	rootCmd.AddCommand(relaysCmd)
+}
+
+// withRelayRepository connects to the database and runs fn with a relay repository.
+func withRelayRepository(fn func(context.Context, repository.RelayRepository) error) error {
+	for _, status := range relaysStatuses {
+		if !slices.Contains(domain.RelayStatuses, status) {
+			return fmt.Errorf("unknown status %q, expected one of %s", status, strings.Join(domain.RelayStatuses, ", "))
+		}
+	}
+
+	dbConfig := database.Config{
+		Host:     dbHost,
+		Port:     dbPort,
+		User:     dbUser,
+		Password: dbPass,
+		DBName:   dbName,
+	}
+
+	// Create a PostgreSQL database pool of connections given config data.
+	db, err := database.NewPostgresDB(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	return fn(context.Background(), postgres.NewRelayRepository(db))
+}
+
+// addRelays creates the relays that don't exist yet, or monitors again the removed ones, and returns how many were added.
+// Invalid and already monitored URLs are reported to out and skipped.
+func addRelays(
+	ctx context.Context,
+	relayRepo repository.RelayRepository,
+	urls []string,
+	discoveredVia string,
+	out io.Writer,
+) (int, error) {
+	added := 0
+
+	for _, raw := range urls {
+		url, err := relayurl.Normalize(raw)
+		if err != nil {
+			fmt.Fprintf(out, "skipping %s: %v\n", raw, err)
+			continue
+		}
+
+		if relay, err := relayRepo.FindByURL(ctx, url); err == nil {
+			if relay.Status != domain.RelayStatusRemoved {
+				fmt.Fprintf(out, "skipping %s: already monitored\n", url)
+				continue
+			}
+
+			if err := relayRepo.SetStatus(ctx, url, domain.RelayStatusActive); err != nil {
+				return added, err
+			}
+
+			added++
+			continue
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return added, err
+		}
+
+		created, err := relayRepo.Create(ctx, domain.Relay{
+			URL:           url,
+			DiscoveredVia: &discoveredVia,
+			UpdatedAt:     time.Now(),
+		})
+		if err != nil {
+			return added, err
+		}
+
+		if created {
+			added++
+		}
+	}
+
+	return added, nil
+}
+
+// setRelaysStatus moves the given relays to status.
+func setRelaysStatus(cmd *cobra.Command, urls []string, status string) error {
+	return withRelayRepository(func(ctx context.Context, relayRepo repository.RelayRepository) error {
+		return forEachRelay(urls, func(url string) error {
+			if err := relayRepo.SetStatus(ctx, url, status); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is now %s\n", url, status)
+
+			return nil
+		})
+	})
+}
+
+// forEachRelay calls fn with the canonical form of every URL, stopping at the first error.
+func forEachRelay(urls []string, fn func(url string) error) error {
+	for _, raw := range urls {
+		url, err := relayurl.Normalize(raw)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(url); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
@@ -20,6 +21,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/database"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/repository/postgres"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/task"
 )
@@ -29,6 +32,25 @@ var (
 	healthCheckTimeInternval string
 	announcementUnitTime     string
 	announcementTimeInterval string
+	discoveryUnitTime        string
+	discoveryTimeInterval    string
+	discoveryRelays          []string
+	retiredUnitTime          string
+	retiredTimeInterval      string
+	retireAfterDays          string
+)
+
+const (
+	// defaultRetireAfterDays is how long a relay can fail every check before it's retired.
+	defaultRetireAfterDays = 7
+	// defaultRetiredInterval is how often retired relays are checked, in days.
+	defaultRetiredInterval = 1
+	// outboxSweepInterval is how often the outbox is swept for events whose delivery was never scheduled,
+	// or whose task was lost. Younger events are left to the tasks enqueued along with them.
+	outboxSweepInterval = 5 * time.Minute
+	// outboxSweepWindow is how old an event of the outbox can be and still be delivered,
+	// older events no longer describe the current state of the relays.
+	outboxSweepWindow = 24 * time.Hour
 )
 
 // schedulerCmd represents the scheduler command
@@ -86,7 +108,7 @@ var schedulerCmd = &cobra.Command{
 		}()
 
 		// Create a slice of jobs to keep track of them.
-		jobs := make([]gocron.Job, 0, 2)
+		jobs := make([]gocron.Job, 0, 5)
 
 		healthCheckTimeInternvalInt, err := strconv.Atoi(healthCheckTimeInternval)
 		if err != nil {
@@ -94,38 +116,123 @@ var schedulerCmd = &cobra.Command{
 			return err
 		}
 
+		retireAfter := defaultRetireAfterDays
+		if retireAfterDays != "" {
+			retireAfter, err = strconv.Atoi(retireAfterDays)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+		}
+
+		retiredTimeIntervalInt := defaultRetiredInterval
+		if retiredTimeInterval != "" {
+			retiredTimeIntervalInt, err = strconv.Atoi(retiredTimeInterval)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+		}
+
+		if retiredUnitTime == "" {
+			retiredUnitTime = "day"
+		}
+
+		healthCheckFrequency := determineGoCronDuration(healthCheckUnitTime, healthCheckTimeInternvalInt)
+		retiredFrequency := determineGoCronDuration(retiredUnitTime, retiredTimeIntervalInt)
+
+		// enqueueHealthChecks enqueues a health check for every relay in the given statuses,
+		// checked at the given frequency.
+		enqueueHealthChecks := func(frequency time.Duration, statuses ...string) error {
+			relays, err := relayRepo.List(ctx, &repository.ListOption{Statuses: statuses})
+			if err != nil {
+				logger.Error(fmt.Sprintf("error fetching relays for health checks: %v", err))
+				return err
+			}
+
+			logger.Info(fmt.Sprintf("Enqueuing health checks for %d %v relays", len(relays), statuses))
+
+			for _, r := range relays {
+				// Create a asynq task passing the type and the payload of the task.
+				relayTask, err := task.NewRelayHealthCheckTask(r.URL, frequency)
+				if err != nil {
+					logger.Error(err.Error())
+					continue
+				}
+
+				// Process the task immediately.
+				info, err := client.Enqueue(relayTask)
+				if err != nil {
+					logger.Error(fmt.Sprintf("error processing a task: %s", err))
+					continue
+				}
+
+				logger.Info(fmt.Sprintf("[*] Successfully enqueued the task: %+v", info))
+			}
+
+			return nil
+		}
+
+		// enqueueRetractions enqueues the retraction of the events about every relay no longer monitored.
+		enqueueRetractions := func() error {
+			urls, err := relayRepo.ListUnmonitoredRelaysWithEvents(ctx)
+			if err != nil {
+				logger.Error(fmt.Sprintf("error fetching relays no longer monitored: %v", err))
+				return err
+			}
+
+			for _, url := range urls {
+				retractTask, err := task.NewRelayRetractTask(url)
+				if err != nil {
+					logger.Error(err.Error())
+					continue
+				}
+
+				info, err := client.Enqueue(retractTask)
+				if errors.Is(err, asynq.ErrTaskIDConflict) {
+					continue
+				}
+				if err != nil {
+					logger.Error(fmt.Sprintf("error processing a task: %s", err))
+					continue
+				}
+
+				logger.Info(fmt.Sprintf("[*] Successfully enqueued the task: %+v", info))
+			}
+
+			return nil
+		}
+
 		healthChecksJob, err := s.NewJob(
-			gocron.DurationJob(
-				determineGoCronDuration(healthCheckUnitTime, healthCheckTimeInternvalInt),
-			),
+			gocron.DurationJob(healthCheckFrequency),
 			gocron.NewTask(func() error {
 				logger.Info("Running health check job")
-				relays, err := relayRepo.List(ctx, nil)
+
+				// Relays come back to the regular frequency as soon as they answer again,
+				// and leave it once they have failed every check for too long.
+				revived, err := relayRepo.ReviveRetiredRelays(ctx)
 				if err != nil {
-					logger.Error(fmt.Sprintf("error fetching relays for health checks: %v", err))
-					return err
+					logger.Error(fmt.Sprintf("error reviving retired relays: %v", err))
+				} else if revived > 0 {
+					logger.Info(fmt.Sprintf("%d retired relays are back online", revived))
 				}
 
-				logger.Info(fmt.Sprintf("Enqueuing health checks for %d relays", len(relays)))
-
-				for _, r := range relays {
-					// Create a asynq task passing the type and the payload of the task.
-					relayTask, err := task.NewRelayHealthCheckTask(r.URL)
-					if err != nil {
-						logger.Error(err.Error())
-						continue
-					}
-
-					// Process the task immediately.
-					info, err := client.Enqueue(relayTask)
-					if err != nil {
-						logger.Error(fmt.Sprintf("error processing a task: %s", err))
-						continue
-					}
+				retired, err := relayRepo.RetireDeadRelays(
+					ctx,
+					time.Now().AddDate(0, 0, -retireAfter),
+				)
+				if err != nil {
+					logger.Error(fmt.Sprintf("error retiring dead relays: %v", err))
+				} else if retired > 0 {
+					logger.Info(fmt.Sprintf("%d relays retired after %d days offline", retired, retireAfter))
+				}
 
-					logger.Info(fmt.Sprintf("[*] Successfully enqueued the task: %+v", info))
+				// Events about relays removed or disabled since the last run would otherwise be served until they expire.
+				if err := enqueueRetractions(); err != nil {
+					logger.Error(fmt.Sprintf("error retracting relays no longer monitored: %v", err))
 				}
-				return nil
+
+				return enqueueHealthChecks(healthCheckFrequency, domain.RelayStatusActive)
 			}),
 			gocron.WithContext(ctx),
 			gocron.WithName("Relays Health Check"),
@@ -137,6 +244,22 @@ var schedulerCmd = &cobra.Command{
 			jobs = append(jobs, healthChecksJob)
 		}
 
+		retiredChecksJob, err := s.NewJob(
+			gocron.DurationJob(retiredFrequency),
+			gocron.NewTask(func() error {
+				logger.Info("Running retired relays health check job")
+				return enqueueHealthChecks(retiredFrequency, domain.RelayStatusRetired)
+			}),
+			gocron.WithContext(ctx),
+			gocron.WithName("Retired Relays Health Check"),
+			gocron.WithTags("health-check", "monitoring", "retired"),
+		)
+		if err != nil {
+			logger.Error(fmt.Sprintf("error scheduling retired relays health checks job: %v", err))
+		} else {
+			jobs = append(jobs, retiredChecksJob)
+		}
+
 		announcementTimeIntervalInt, err := strconv.Atoi(announcementTimeInterval)
 		if err != nil {
 			logger.Error(err.Error())
@@ -187,6 +310,86 @@ var schedulerCmd = &cobra.Command{
 			jobs = append(jobs, jobAnnouncement)
 		}
 
+		outboxSweepJob, err := s.NewJob(
+			gocron.DurationJob(outboxSweepInterval),
+			gocron.NewTask(func() error {
+				now := time.Now()
+
+				events, err := relayRepo.ListPendingOutboxEvents(
+					ctx,
+					now.Add(-outboxSweepWindow),
+					now.Add(-outboxSweepInterval),
+				)
+				if err != nil {
+					logger.Error(fmt.Sprintf("error fetching pending outbox events: %v", err))
+					return err
+				}
+
+				if len(events) > 0 {
+					logger.Info(fmt.Sprintf("Enqueuing the publish of %d pending outbox events", len(events)))
+				}
+
+				for _, e := range events {
+					if err := task.EnqueueRelayPublishTask(ctx, client, e.EventID); err != nil {
+						logger.Error(err.Error())
+					}
+				}
+
+				return nil
+			}),
+			gocron.WithContext(ctx),
+			gocron.WithName("Outbox Sweep"),
+			gocron.WithTags("publishing"),
+		)
+		if err != nil {
+			logger.Error(fmt.Sprintf("error scheduling outbox sweep job: %v", err))
+		} else {
+			jobs = append(jobs, outboxSweepJob)
+		}
+
+		// Discovery is optional, it only runs when there are seed relays to crawl.
+		if len(discoveryRelays) > 0 {
+			discoveryTimeIntervalInt, err := strconv.Atoi(discoveryTimeInterval)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			jobDiscovery, err := s.NewJob(
+				gocron.DurationJob(
+					determineGoCronDuration(discoveryUnitTime, discoveryTimeIntervalInt),
+				),
+				gocron.NewTask(func() error {
+					// Create a asynq task passing the type and the payload of the task.
+					discoveryTask, err := task.NewRelayDiscoveryTask(discoveryRelays)
+					if err != nil {
+						logger.Error(err.Error())
+						return err
+					}
+
+					info, err := client.Enqueue(discoveryTask)
+					if err != nil {
+						logger.Error(fmt.Sprintf("error processing a task: %s", err))
+						return err
+					}
+
+					logger.Info(fmt.Sprintf("[*] Successfully enqueued the task: %+v", info))
+
+					return nil
+				}),
+				gocron.WithContext(ctx),
+				gocron.WithName("Relay Discovery"),
+				gocron.WithTags("discovery"),
+			)
+			if err != nil {
+				logger.Error(fmt.Sprintf("error scheduling relay discovery job: %v", err))
+			} else {
+				jobs = append(jobs, jobDiscovery)
+			}
+		} else {
+			logger.Info("no discovery seed relays configured, relay discovery is disabled")
+		}
+
 		// Start the scheduler.
 		s.Start()
 		logger.Info("scheduler started. Task will run every 15 minutes.")
@@ -220,12 +423,20 @@ func init() {
 	healthCheckTimeInternval = os.Getenv("NOSTRICH_WATCH_MONITOR_HEALTHCHECK_TIME_INTERVAL")
 	announcementUnitTime = os.Getenv("NOSTRICH_WATCH_MONITOR_ANNOUNCEMENT_UNIT_TIME")
 	announcementTimeInterval = os.Getenv("NOSTRICH_WATCH_MONITOR_ANNOUNCEMENT_TIME_INTERVAL")
+	discoveryUnitTime = os.Getenv("NOSTRICH_WATCH_MONITOR_DISCOVERY_UNIT_TIME")
+	discoveryTimeInterval = os.Getenv("NOSTRICH_WATCH_MONITOR_DISCOVERY_TIME_INTERVAL")
+	discoveryRelays = splitList(os.Getenv("NOSTRICH_WATCH_MONITOR_DISCOVERY_RELAYS"))
+	retiredUnitTime = os.Getenv("NOSTRICH_WATCH_MONITOR_RETIRED_UNIT_TIME")
+	retiredTimeInterval = os.Getenv("NOSTRICH_WATCH_MONITOR_RETIRED_TIME_INTERVAL")
+	retireAfterDays = os.Getenv("NOSTRICH_WATCH_MONITOR_RETIRE_AFTER_DAYS")
 
 	rootCmd.AddCommand(schedulerCmd)
 }
 
 func determineGoCronDuration(unitTime string, timeInterval int) time.Duration {
 	switch strings.ToLower(unitTime) {
+	case "day":
+		return time.Duration(timeInterval) * 24 * time.Hour
 	case "hour":
 		return time.Duration(timeInterval) * time.Hour
 	case "minute":
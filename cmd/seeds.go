@@ -17,6 +17,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/repository/postgres"
 )
 
@@ -36,9 +37,17 @@ func seedRelays(db *sqlx.DB) error {
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			relayURLs = append(relayURLs, line)
+		if line == "" {
+			continue
+		}
+
+		url, err := relayurl.Normalize(line)
+		if err != nil {
+			log.Printf("skipping %s: %v", line, err)
+			continue
 		}
+
+		relayURLs = append(relayURLs, url)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -48,6 +57,7 @@ func seedRelays(db *sqlx.DB) error {
 	log.Printf("Found %d relays in relays.txt to seed", len(relayURLs))
 
 	ctx := context.Background()
+	discoveredVia := domain.DiscoveredViaSeed
 
 	for _, url := range relayURLs {
 		info, err := nip11.Fetch(ctx, url)
@@ -88,11 +98,12 @@ func seedRelays(db *sqlx.DB) error {
 			Tags:           pq.StringArray(info.Tags),
 			LanguageTags:   pq.StringArray(info.LanguageTags),
 			RelayCountries: pq.StringArray(info.RelayCountries),
+			DiscoveredVia:  &discoveredVia,
 		}
 
 		relayRepo := postgres.NewRelayRepository(db)
 
-		if err := relayRepo.Create(ctx, relayInfo); err != nil {
+		if _, err := relayRepo.Create(ctx, relayInfo); err != nil {
 			log.Printf("failed to insert %s: %v", url, err)
 		}
 	}
@@ -5,18 +5,30 @@ package cmd
 
 import (
 	"log/slog"
+	"maps"
+	"net/url"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/database"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/healthcheck"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/task"
 )
 
 var (
 	monitorPrivateKey string
 	monitorRelay      string
+	monitorChecks     []string
+	sslExpiryWarning  string
+	geoIPDatabase     string
+	socksProxy        string
+	nipProbes         []string
+	publishRelays     []string
+	publishMaxAge     string
+	publishRules      []string
 )
 
 // workerCmd represents the worker command
@@ -48,13 +60,101 @@ var workerCmd = &cobra.Command{
 			_ = db.Close()
 		}()
 
+		timeout := 10 * time.Second
+
+		sslExpiryWarningDays := healthcheck.DefaultSSLExpiryWarningDays
+		if sslExpiryWarning != "" {
+			sslExpiryWarningDays, err = strconv.Atoi(sslExpiryWarning)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+		}
+
+		registry := healthcheck.DefaultRegistry(timeout)
+		registry.Register(healthcheck.NewSSLCheck(timeout, sslExpiryWarningDays))
+
+		// Relays are only geolocated when a GeoIP database is available.
+		if geoIPDatabase != "" {
+			locator, err := healthcheck.OpenGeoIPLocator(geoIPDatabase)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+			defer func() {
+				_ = locator.Close()
+			}()
+
+			registry.Register(healthcheck.NewDNSCheck(timeout, locator))
+		}
+
+		// Claimed NIPs are only probed for conformance when NOSTRICH_WATCH_MONITOR_NIP_PROBES lists some.
+		if len(nipProbes) > 0 {
+			var nips []int
+			for _, p := range nipProbes {
+				nip, err := strconv.Atoi(p)
+				if err != nil {
+					logger.Error(err.Error())
+					return err
+				}
+				nips = append(nips, nip)
+			}
+
+			conformance, err := healthcheck.NewConformanceCheck(timeout, nips)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			registry.Register(conformance)
+		}
+
+		// Only the checks listed in NOSTRICH_WATCH_MONITOR_CHECKS are performed and announced, all of them by default.
+		checks, err := registry.Enabled(monitorChecks)
+		if err != nil {
+			logger.Error(err.Error())
+			return err
+		}
+
+		// Relays on Tor, I2P and Lokinet are only checked through a SOCKS5 proxy.
+		var proxy *url.URL
+		if socksProxy != "" {
+			proxy, err = healthcheck.ParseProxyURL(socksProxy)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+		}
+
+		// Events reporting nothing new are only published again once they are older than
+		// NOSTRICH_WATCH_MONITOR_PUBLISH_MAX_AGE, changes of the tags are weighed by NOSTRICH_WATCH_MONITOR_PUBLISH_RULES.
+		policy := healthcheck.DefaultChangePolicy()
+		if publishMaxAge != "" {
+			policy.MaxAge, err = time.ParseDuration(publishMaxAge)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+		}
+
+		rules, err := healthcheck.ParseTagRules(publishRules)
+		if err != nil {
+			logger.Error(err.Error())
+			return err
+		}
+		maps.Copy(policy.Rules, rules)
+
 		th := task.NewTaskHandler(
 			db,
-			10*time.Second,
+			timeout,
 			monitorPrivateKey,
 			logger,
 			redisHost,
-			monitorRelay,
+			// The monitor's own relay first, then the relays clients read monitor events from.
+			append([]string{monitorRelay}, publishRelays...),
+			checks,
+			proxy,
+			policy,
 		)
 
 		if err := th.Run(); err != nil {
@@ -69,5 +169,13 @@ var workerCmd = &cobra.Command{
 func init() {
 	monitorPrivateKey = os.Getenv("NOSTRICH_WATCH_MONITOR_PRIVATE_KEY")
 	monitorRelay = os.Getenv("NOSTRICH_WATCH_MONITOR_RELAY")
+	monitorChecks = splitList(os.Getenv("NOSTRICH_WATCH_MONITOR_CHECKS"))
+	sslExpiryWarning = os.Getenv("NOSTRICH_WATCH_MONITOR_SSL_EXPIRY_WARNING_DAYS")
+	geoIPDatabase = os.Getenv("NOSTRICH_WATCH_MONITOR_GEOIP_DB")
+	socksProxy = os.Getenv("NOSTRICH_WATCH_MONITOR_PROXY")
+	nipProbes = splitList(os.Getenv("NOSTRICH_WATCH_MONITOR_NIP_PROBES"))
+	publishRelays = splitList(os.Getenv("NOSTRICH_WATCH_MONITOR_PUBLISH_RELAYS"))
+	publishMaxAge = os.Getenv("NOSTRICH_WATCH_MONITOR_PUBLISH_MAX_AGE")
+	publishRules = splitList(os.Getenv("NOSTRICH_WATCH_MONITOR_PUBLISH_RULES"))
 	rootCmd.AddCommand(workerCmd)
 }
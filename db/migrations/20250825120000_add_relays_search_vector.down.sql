@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_relays_search_vector;
+ALTER TABLE relays DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,9 @@
+-- Words of the name, description and URL of the relay, weighted in that order, to search relays by.
+-- Punctuation is dropped from the URL so its host labels and path are words of their own.
+ALTER TABLE relays ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('simple', regexp_replace(url, '[^[:alnum:]]+', ' ', 'g')), 'C')
+) STORED;
+
+CREATE INDEX idx_relays_search_vector ON relays USING GIN(search_vector);
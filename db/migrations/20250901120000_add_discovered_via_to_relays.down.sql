@@ -0,0 +1 @@
+ALTER TABLE relays DROP COLUMN IF EXISTS discovered_via;
@@ -0,0 +1,6 @@
+-- Where a relay was first learned from: the seeds file, a NIP-65 relay list,
+-- contact list relay hints, another monitor's 30166 events...
+ALTER TABLE relays ADD COLUMN discovered_via VARCHAR(50);
+
+-- Every relay so far came from relays.txt.
+UPDATE relays SET discovered_via = 'seed';
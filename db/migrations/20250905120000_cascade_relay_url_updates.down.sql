@@ -0,0 +1,4 @@
+ALTER TABLE health_checks DROP CONSTRAINT health_checks_relay_url_fkey;
+ALTER TABLE health_checks
+    ADD CONSTRAINT health_checks_relay_url_fkey
+    FOREIGN KEY (relay_url) REFERENCES relays(url) ON DELETE CASCADE;
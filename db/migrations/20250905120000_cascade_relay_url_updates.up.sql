@@ -0,0 +1,5 @@
+-- Let relay URLs be rewritten to their canonical form without losing their health checks.
+ALTER TABLE health_checks DROP CONSTRAINT health_checks_relay_url_fkey;
+ALTER TABLE health_checks
+    ADD CONSTRAINT health_checks_relay_url_fkey
+    FOREIGN KEY (relay_url) REFERENCES relays(url) ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_relays_status;
+ALTER TABLE relays DROP COLUMN IF EXISTS status;
@@ -0,0 +1,10 @@
+-- Lifecycle of a relay:
+--   active: checked at the regular frequency
+--   disabled: never checked, until enabled again by hand
+--   retired: dead for a while, checked at a lower frequency until it comes back
+--   removed: removed by hand, never checked nor shown, kept so discovery doesn't add it back
+ALTER TABLE relays
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'disabled', 'retired', 'removed'));
+
+CREATE INDEX idx_relays_status ON relays(status);
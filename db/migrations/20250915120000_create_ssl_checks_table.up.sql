@@ -0,0 +1,26 @@
+-- NIP-66 Relay Monitoring - TLS certificate checks of wss:// relays
+CREATE TABLE ssl_checks (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Foreign key to relays table, following URL rewrites like health_checks does
+    relay_url VARCHAR(500) NOT NULL REFERENCES relays(url) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    -- Test execution info
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    -- TLS handshake result
+    success BOOLEAN NOT NULL,
+    error TEXT, -- error message if the handshake or the verification failed
+
+    -- Leaf certificate details (nullable when no certificate was presented)
+    valid BOOLEAN, -- chain valid and current date within the validity period
+    chain_valid BOOLEAN, -- chain verified against the trusted roots for the relay host
+    issuer TEXT,
+    subject TEXT,
+    not_before TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    days_to_expiry INTEGER, -- negative once the certificate has expired
+    expiring_soon BOOLEAN -- within the warning window configured on the worker when checked
+);
+
+CREATE INDEX idx_ssl_checks_relay_created_at ON ssl_checks(relay_url, created_at);
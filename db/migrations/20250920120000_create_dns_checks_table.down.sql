@@ -0,0 +1,5 @@
+ALTER TABLE relays
+    DROP COLUMN IF EXISTS observed_country,
+    DROP COLUMN IF EXISTS observed_geohash;
+
+DROP TABLE IF EXISTS dns_checks;
@@ -0,0 +1,29 @@
+-- NIP-66 Relay Monitoring - DNS resolution checks
+CREATE TABLE dns_checks (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Foreign key to relays table, following URL rewrites like health_checks does
+    relay_url VARCHAR(500) NOT NULL REFERENCES relays(url) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    -- Test execution info
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    -- Resolution result
+    success BOOLEAN NOT NULL,
+    error TEXT, -- error message if the host could not be resolved
+    rtt_dns INTEGER, -- resolution time in milliseconds
+    ipv4 BOOLEAN NOT NULL DEFAULT FALSE, -- at least one A record
+    ipv6 BOOLEAN NOT NULL DEFAULT FALSE, -- at least one AAAA record
+    ips TEXT[],
+
+    -- Location of the resolved address, from the GeoIP database (nullable without one)
+    country VARCHAR(2),
+    geohash VARCHAR(12)
+);
+
+CREATE INDEX idx_dns_checks_relay_created_at ON dns_checks(relay_url, created_at);
+
+-- Latest observed location, kept apart from the self-reported relay_countries
+ALTER TABLE relays
+    ADD COLUMN observed_country VARCHAR(2),
+    ADD COLUMN observed_geohash VARCHAR(12);
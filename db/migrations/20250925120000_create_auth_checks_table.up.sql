@@ -0,0 +1,24 @@
+-- NIP-66 Relay Monitoring - NIP-42 authentication checks
+CREATE TABLE auth_checks (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Foreign key to relays table, following URL rewrites like health_checks does
+    relay_url VARCHAR(500) NOT NULL REFERENCES relays(url) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    -- Test execution info
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    -- Probe result
+    success BOOLEAN NOT NULL,
+    error TEXT, -- error message if the probe could not complete
+    challenge_received BOOLEAN NOT NULL DEFAULT FALSE, -- the relay sent an AUTH challenge
+    authenticated BOOLEAN NOT NULL DEFAULT FALSE, -- the relay accepted the monitor's AUTH event
+    read_auth_required BOOLEAN NOT NULL DEFAULT FALSE, -- a REQ was closed with "auth-required:"
+    write_auth_required BOOLEAN NOT NULL DEFAULT FALSE, -- an EVENT was refused with "auth-required:"
+
+    -- What NIP-11 declares, and whether it contradicts the observed behavior (nullable without a document)
+    nip11_auth_required BOOLEAN,
+    nip11_mismatch BOOLEAN
+);
+
+CREATE INDEX idx_auth_checks_relay_created_at ON auth_checks(relay_url, created_at);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_relays_limitation;
+
+ALTER TABLE relays
+    ALTER COLUMN relay_countries TYPE VARCHAR(10)[],
+    ALTER COLUMN language_tags TYPE VARCHAR(10)[],
+    ALTER COLUMN tags TYPE VARCHAR(50)[];
+
+ALTER TABLE relays
+    DROP COLUMN IF EXISTS limitation,
+    DROP COLUMN IF EXISTS fees,
+    DROP COLUMN IF EXISTS retention,
+    DROP COLUMN IF EXISTS payments_url;
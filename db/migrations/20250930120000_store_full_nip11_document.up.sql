@@ -0,0 +1,14 @@
+-- Keep the parts of the NIP-11 document that were dropped so far
+ALTER TABLE relays
+    ADD COLUMN limitation JSONB, -- NIP-11 "limitation" object
+    ADD COLUMN fees JSONB, -- NIP-11 "fees" object
+    ADD COLUMN retention JSONB, -- NIP-11 "retention" array
+    ADD COLUMN payments_url VARCHAR(500); -- URL to pay the relay fees
+
+-- Declared values are not bounded by NIP-11, a long tag must not prevent the whole document from being stored
+ALTER TABLE relays
+    ALTER COLUMN relay_countries TYPE TEXT[],
+    ALTER COLUMN language_tags TYPE TEXT[],
+    ALTER COLUMN tags TYPE TEXT[];
+
+CREATE INDEX idx_relays_limitation ON relays USING GIN(limitation);
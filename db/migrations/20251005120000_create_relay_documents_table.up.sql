@@ -0,0 +1,17 @@
+-- NIP-66 Relay Monitoring - NIP-11 document history
+CREATE TABLE relay_documents (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Foreign key to relays table, following URL rewrites like health_checks does
+    relay_url VARCHAR(500) NOT NULL REFERENCES relays(url) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    -- Versions are numbered from 1, a new one is only stored when the document changes
+    version INTEGER NOT NULL,
+    content_hash CHAR(64) NOT NULL, -- hex encoded SHA-256 of the document
+    document JSONB NOT NULL,
+
+    -- When this version was first seen
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (relay_url, version)
+);
@@ -0,0 +1,17 @@
+-- NIP-66 Relay Monitoring - NIP conformance probes
+CREATE TABLE nip_checks (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Foreign key to relays table, following URL rewrites like health_checks does
+    relay_url VARCHAR(500) NOT NULL REFERENCES relays(url) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    -- Test execution info
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    -- Probe result for a NIP the relay claims to support
+    nip INTEGER NOT NULL,
+    result VARCHAR(10) NOT NULL CHECK (result IN ('pass', 'fail', 'unknown')),
+    detail TEXT -- why the probe failed or could not be carried out
+);
+
+CREATE INDEX idx_nip_checks_relay_nip_created_at ON nip_checks(relay_url, nip, created_at);
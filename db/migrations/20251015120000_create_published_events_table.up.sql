@@ -0,0 +1,20 @@
+-- NIP-66 Relay Monitoring - Outcome of publishing the monitor's events
+CREATE TABLE published_events (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Event published, one row per relay it was sent to
+    event_id CHAR(64) NOT NULL,
+    kind INTEGER NOT NULL,
+
+    -- Publish target, not necessarily a monitored relay
+    relay_url VARCHAR(500) NOT NULL,
+
+    status VARCHAR(10) NOT NULL CHECK (status IN ('published', 'failed')),
+    error TEXT, -- last error when the relay never accepted the event
+    attempts INTEGER NOT NULL DEFAULT 1,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_published_events_event_id ON published_events(event_id);
+CREATE INDEX idx_published_events_relay_created_at ON published_events(relay_url, created_at);
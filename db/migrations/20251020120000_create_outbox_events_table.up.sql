@@ -0,0 +1,21 @@
+-- NIP-66 Relay Monitoring - Outbox of the monitor's signed events
+-- Events are stored in the same transaction as the check they report on, then delivered by relay:publish tasks.
+CREATE TABLE outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+
+    event_id CHAR(64) NOT NULL UNIQUE,
+    kind INTEGER NOT NULL,
+    relay_url VARCHAR(500), -- the relay the event is about, none for the monitor announcement
+    event JSONB NOT NULL, -- the signed event, as sent to relays
+
+    -- Events are given up on as failed after too many attempts,
+    -- or as expired when their NIP-40 expiration passed before they were delivered.
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'published', 'failed', 'expired')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT, -- why the last delivery attempt failed, if it did
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbox_events_pending ON outbox_events(created_at) WHERE status = 'pending';
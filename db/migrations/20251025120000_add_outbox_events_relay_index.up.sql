@@ -0,0 +1,2 @@
+-- The latest event about a relay is looked up on every check, to skip publishing events that report nothing new.
+CREATE INDEX idx_outbox_events_relay_kind ON outbox_events(relay_url, kind, created_at DESC);
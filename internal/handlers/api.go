@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/presentation"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/services"
+)
+
+// Error codes of the JSON API error envelope.
+const (
+	apiErrInvalidParameter = "invalid_parameter"
+	apiErrNotFound         = "not_found"
+	apiErrInternal         = "internal_error"
+)
+
+// HandleAPIListRelays returns a page of relays as JSON.
+// It accepts the same search, filters and sort parameters as the dashboard.
+func (rh *RelaysHandler) HandleAPIListRelays(w http.ResponseWriter, r *http.Request) {
+	filters, err := parseRelayFilters(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, err.Error())
+		return
+	}
+
+	relays, err := rh.service.GetRelays(r.Context(), filters)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, "cursor is invalid or doesn't match the sort")
+			return
+		}
+
+		writeAPIError(w, http.StatusInternalServerError, apiErrInternal, "failed to fetch relays")
+		return
+	}
+
+	data := make([]presentation.RelayResponse, len(relays))
+	for i, relay := range relays {
+		data[i] = ToRelayResponse(relay)
+	}
+
+	meta := newAPIMeta(*filters.Limit, *filters.Offset, len(data))
+	if filters.Cursor != "" {
+		// Paginating by cursor, an offset would point somewhere else.
+		meta.NextOffset = nil
+	}
+	meta.NextCursor = services.NextCursor(relays, filters)
+
+	writeAPIResponse(w, presentation.APIResponse{
+		Data: data,
+		Meta: meta,
+	})
+}
+
+// HandleAPIGetRelay returns a single relay, along with its stats over the requested window, as JSON.
+// The relay URL must be path escaped, e.g. /api/v1/relays/wss%3A%2F%2Frelay.example.com.
+func (rh *RelaysHandler) HandleAPIGetRelay(w http.ResponseWriter, r *http.Request) {
+	window, err := services.ParseStatsWindow(r.URL.Query().Get("window"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, err.Error())
+		return
+	}
+
+	relay, ok := rh.findAPIRelay(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := rh.service.GetRelayStats(r.Context(), relay.URL, window)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, apiErrInternal, "failed to compute relay stats")
+		return
+	}
+
+	resp := ToRelayResponse(relay)
+	resp.Stats = ToRelayStatsResponse(stats, window)
+
+	// Left out for relays never probed for NIP-42 authentication.
+	if authCheck, err := rh.service.GetLatestAuthCheck(r.Context(), relay.URL); err == nil {
+		resp.Auth = ToAuthCheckResponse(authCheck)
+	}
+
+	writeAPIResponse(w, presentation.APIResponse{Data: resp})
+}
+
+// HandleAPIListRelayChecks returns a page of the health checks of a relay within the requested window as JSON.
+func (rh *RelaysHandler) HandleAPIListRelayChecks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	window, err := services.ParseStatsWindow(q.Get("window"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, err.Error())
+		return
+	}
+
+	page, err := parsePage(q)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, err.Error())
+		return
+	}
+
+	relay, ok := rh.findAPIRelay(w, r)
+	if !ok {
+		return
+	}
+
+	checks, err := rh.service.GetHealthHistory(r.Context(), relay.URL, window, page)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, apiErrInternal, "failed to fetch health checks")
+		return
+	}
+
+	data := make([]presentation.HealthCheckResponse, len(checks))
+	for i, hc := range checks {
+		data[i] = *ToHealthCheckResponse(hc)
+	}
+
+	writeAPIResponse(w, presentation.APIResponse{
+		Data: data,
+		Meta: newAPIMeta(page.Limit, page.Offset, len(data)),
+	})
+}
+
+// HandleAPIListRelayDocuments returns a page of the NIP-11 document versions of a relay as JSON,
+// each one with the changes since the previous version.
+func (rh *RelaysHandler) HandleAPIListRelayDocuments(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, err.Error())
+		return
+	}
+
+	relay, ok := rh.findAPIRelay(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := rh.service.GetDocumentHistory(r.Context(), relay.URL, page)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, apiErrInternal, "failed to fetch document history")
+		return
+	}
+
+	data := make([]presentation.RelayDocumentResponse, len(versions))
+	for i, v := range versions {
+		data[i] = ToRelayDocumentResponse(v)
+	}
+
+	writeAPIResponse(w, presentation.APIResponse{
+		Data: data,
+		Meta: newAPIMeta(page.Limit, page.Offset, len(data)),
+	})
+}
+
+// HandleAPIListNIPs returns how many relays claim support for each NIP as JSON, along with its trend.
+func (rh *RelaysHandler) HandleAPIListNIPs(w http.ResponseWriter, r *http.Request) {
+	matrix, err := rh.service.GetNIPSupportMatrix(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, apiErrInternal, "failed to fetch nip support")
+		return
+	}
+
+	data := make([]presentation.NIPAdoptionResponse, len(matrix.NIPs))
+	for i, a := range matrix.NIPs {
+		data[i] = ToNIPAdoptionResponse(a)
+	}
+
+	writeAPIResponse(w, presentation.APIResponse{Data: data})
+}
+
+// HandleAPIListNIPRelays returns a page of the active relays claiming support for a NIP as JSON,
+// most available over the requested window first.
+func (rh *RelaysHandler) HandleAPIListNIPRelays(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	nip, err := strconv.Atoi(r.PathValue("nip"))
+	if err != nil || nip < 0 {
+		writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, "nip must be a positive number")
+		return
+	}
+
+	window, err := services.ParseStatsWindow(q.Get("window"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, err.Error())
+		return
+	}
+
+	page, err := parsePage(q)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, err.Error())
+		return
+	}
+
+	relays, err := rh.service.GetRelaysByNIP(r.Context(), nip, window, page)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, apiErrInternal, "failed to fetch relays")
+		return
+	}
+
+	data := make([]presentation.RelayResponse, len(relays))
+	for i, relay := range relays {
+		data[i] = ToRelayResponse(relay.Relay)
+		data[i].Stats = ToRelayStatsResponse(relay.Stats, window)
+	}
+
+	writeAPIResponse(w, presentation.APIResponse{
+		Data: data,
+		Meta: newAPIMeta(page.Limit, page.Offset, len(data)),
+	})
+}
+
+// findAPIRelay looks up the relay named by the {url} path value.
+// It writes the error response and returns false when the relay can't be found.
+func (rh *RelaysHandler) findAPIRelay(w http.ResponseWriter, r *http.Request) (domain.Relay, bool) {
+	relayURL := r.PathValue("url")
+	if relayURL == "" {
+		writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, "relay url is required")
+		return domain.Relay{}, false
+	}
+
+	relay, err := rh.service.GetRelayByURL(r.Context(), relayURL)
+	if err != nil {
+		if errors.Is(err, relayurl.ErrInvalid) {
+			writeAPIError(w, http.StatusBadRequest, apiErrInvalidParameter, err.Error())
+			return domain.Relay{}, false
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			writeAPIError(w, http.StatusNotFound, apiErrNotFound, "relay "+strconv.Quote(relayURL)+" not found")
+			return domain.Relay{}, false
+		}
+
+		writeAPIError(w, http.StatusInternalServerError, apiErrInternal, "failed to fetch relay")
+		return domain.Relay{}, false
+	}
+
+	return relay, true
+}
+
+// newAPIMeta returns the pagination metadata of a page holding count results.
+func newAPIMeta(limit, offset, count int) *presentation.APIMeta {
+	meta := &presentation.APIMeta{
+		Limit:  limit,
+		Offset: offset,
+		Count:  count,
+	}
+
+	// A full page means there may be more results.
+	if count == limit {
+		next := offset + limit
+		meta.NextOffset = &next
+	}
+
+	return meta
+}
+
+func writeAPIResponse(w http.ResponseWriter, resp presentation.APIResponse) {
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, presentation.APIErrorResponse{
+		Error: presentation.APIError{Code: code, Message: message},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
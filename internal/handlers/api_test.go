@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/presentation"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/services"
+)
+
+// fakeRelayRepository keeps relays and their health checks in memory.
+// Only the methods used by the JSON API are implemented, the others panic.
+type fakeRelayRepository struct {
+	repository.RelayRepository
+
+	relays []domain.Relay
+	checks []domain.HealthCheck
+	auth   map[string]domain.AuthCheck
+	err    error
+}
+
+// List returns the relays sorted by URL, paginated by limit, offset or cursor.
+func (f *fakeRelayRepository) List(_ context.Context, opts *repository.ListOption) ([]domain.Relay, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	relays := slices.Clone(f.relays)
+	slices.SortFunc(relays, func(a, b domain.Relay) int { return strings.Compare(a.URL, b.URL) })
+
+	if opts.Cursor != "" {
+		cursor, err := repository.DecodeCursor(opts.Cursor, opts.Sort, opts.Desc)
+		if err != nil {
+			return nil, err
+		}
+
+		relays = slices.DeleteFunc(relays, func(r domain.Relay) bool { return r.URL <= cursor.URL })
+	}
+
+	if opts.Offset != nil {
+		relays = relays[min(*opts.Offset, len(relays)):]
+	}
+	if opts.Limit != nil {
+		relays = relays[:min(*opts.Limit, len(relays))]
+	}
+
+	for i := range relays {
+		relays[i].SortKey = &relays[i].URL
+	}
+
+	return relays, nil
+}
+
+func (f *fakeRelayRepository) FindByURL(_ context.Context, url string) (domain.Relay, error) {
+	if f.err != nil {
+		return domain.Relay{}, f.err
+	}
+
+	for _, r := range f.relays {
+		if r.URL == url {
+			return r, nil
+		}
+	}
+
+	return domain.Relay{}, sql.ErrNoRows
+}
+
+func (f *fakeRelayRepository) GetStats(_ context.Context, url string, _ time.Time) (domain.RelayStats, error) {
+	return domain.RelayStats{RelayURL: url}, nil
+}
+
+func (f *fakeRelayRepository) FindLatestAuthCheck(_ context.Context, url string) (domain.AuthCheck, error) {
+	check, ok := f.auth[url]
+	if !ok {
+		return domain.AuthCheck{}, sql.ErrNoRows
+	}
+
+	return check, nil
+}
+
+func (f *fakeRelayRepository) ListHealthChecks(
+	_ context.Context,
+	url string,
+	opts *repository.HealthCheckListOption,
+) ([]domain.HealthCheck, error) {
+	var checks []domain.HealthCheck
+	for _, hc := range f.checks {
+		if hc.RelayURL == url {
+			checks = append(checks, hc)
+		}
+	}
+
+	checks = checks[min(*opts.Offset, len(checks)):]
+	return checks[:min(*opts.Limit, len(checks))], nil
+}
+
+func newAPITestServer(repo *fakeRelayRepository) http.Handler {
+	handler := NewRelaysHandler(services.NewRelayService(repo, slog.New(slog.DiscardHandler)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/relays", handler.HandleAPIListRelays)
+	mux.HandleFunc("GET /api/v1/relays/{url}", handler.HandleAPIGetRelay)
+	mux.HandleFunc("GET /api/v1/relays/{url}/checks", handler.HandleAPIListRelayChecks)
+
+	return mux
+}
+
+// apiGet requests the path and decodes the JSON body into v, returning the status code.
+func apiGet(t *testing.T, h http.Handler, path string, v any) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(v))
+
+	return rec.Code
+}
+
+func seedRelays(urls ...string) []domain.Relay {
+	relays := make([]domain.Relay, len(urls))
+	for i, u := range urls {
+		relays[i] = domain.Relay{URL: u}
+	}
+	return relays
+}
+
+func TestAPIListRelaysPagination(t *testing.T) {
+	h := newAPITestServer(&fakeRelayRepository{
+		relays: seedRelays("wss://c.example.com", "wss://a.example.com", "wss://b.example.com"),
+	})
+
+	var page struct {
+		Data []presentation.RelayResponse `json:"data"`
+		Meta presentation.APIMeta         `json:"meta"`
+	}
+
+	require.Equal(t, http.StatusOK, apiGet(t, h, "/api/v1/relays?limit=2", &page))
+	require.Len(t, page.Data, 2)
+	require.Equal(t, "wss://a.example.com", page.Data[0].URL)
+	require.Equal(t, "wss://b.example.com", page.Data[1].URL)
+	require.Equal(t, 2, page.Meta.Limit)
+	require.Equal(t, 0, page.Meta.Offset)
+	require.Equal(t, 2, page.Meta.Count)
+	require.NotNil(t, page.Meta.NextOffset)
+	require.Equal(t, 2, *page.Meta.NextOffset)
+	require.NotEmpty(t, page.Meta.NextCursor)
+
+	// The cursor picks up after the last relay of the previous page.
+	cursor := page.Meta.NextCursor
+	page.Meta = presentation.APIMeta{}
+	require.Equal(t, http.StatusOK, apiGet(t, h, "/api/v1/relays?limit=2&cursor="+url.QueryEscape(cursor), &page))
+	require.Len(t, page.Data, 1)
+	require.Equal(t, "wss://c.example.com", page.Data[0].URL)
+	require.Equal(t, 1, page.Meta.Count)
+	require.Nil(t, page.Meta.NextOffset)
+	require.Empty(t, page.Meta.NextCursor)
+}
+
+func TestAPIListRelaysErrors(t *testing.T) {
+	relays := seedRelays("wss://a.example.com", "wss://b.example.com")
+	cursor := repository.Cursor{Sort: repository.SortByURL, Key: &relays[0].URL, URL: relays[0].URL}.Encode()
+
+	tests := []struct {
+		name   string
+		query  string
+		err    error
+		status int
+		code   string
+	}{
+		{
+			name:   "limit out of range",
+			query:  "limit=1000",
+			status: http.StatusBadRequest,
+			code:   apiErrInvalidParameter,
+		},
+		{
+			name:   "unknown sort",
+			query:  "sort=size",
+			status: http.StatusBadRequest,
+			code:   apiErrInvalidParameter,
+		},
+		{
+			name:   "cursor and offset",
+			query:  "offset=10&cursor=" + cursor,
+			status: http.StatusBadRequest,
+			code:   apiErrInvalidParameter,
+		},
+		{
+			name:   "malformed cursor",
+			query:  "cursor=not-a-cursor",
+			status: http.StatusBadRequest,
+			code:   apiErrInvalidParameter,
+		},
+		{
+			name:   "cursor of another sort",
+			query:  "sort=name&cursor=" + cursor,
+			status: http.StatusBadRequest,
+			code:   apiErrInvalidParameter,
+		},
+		{
+			name:   "repository failure",
+			err:    errors.New("connection refused"),
+			status: http.StatusInternalServerError,
+			code:   apiErrInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newAPITestServer(&fakeRelayRepository{relays: relays, err: tt.err})
+
+			var resp presentation.APIErrorResponse
+			require.Equal(t, tt.status, apiGet(t, h, "/api/v1/relays?"+tt.query, &resp))
+			require.Equal(t, tt.code, resp.Error.Code)
+			require.NotEmpty(t, resp.Error.Message)
+		})
+	}
+}
+
+func TestAPIGetRelay(t *testing.T) {
+	relays := seedRelays("wss://relay.example.com", "wss://removed.example.com", "wss://unprobed.example.com")
+	relays[1].Status = domain.RelayStatusRemoved
+	h := newAPITestServer(&fakeRelayRepository{
+		relays: relays,
+		auth: map[string]domain.AuthCheck{
+			"wss://relay.example.com": {
+				RelayURL:          "wss://relay.example.com",
+				Success:           true,
+				WriteAuthRequired: true,
+				NIP11AuthRequired: &[]bool{false}[0],
+				NIP11Mismatch:     &[]bool{true}[0],
+			},
+		},
+	})
+
+	var relay struct {
+		Data presentation.RelayResponse `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, apiGet(t, h, "/api/v1/relays/"+url.PathEscape("wss://relay.example.com"), &relay))
+	require.Equal(t, "wss://relay.example.com", relay.Data.URL)
+	require.NotNil(t, relay.Data.Stats)
+	require.NotNil(t, relay.Data.Auth)
+	require.True(t, relay.Data.Auth.WriteAuthRequired)
+	require.True(t, *relay.Data.Auth.NIP11Mismatch)
+
+	relay.Data = presentation.RelayResponse{}
+	require.Equal(t, http.StatusOK, apiGet(t, h, "/api/v1/relays/"+url.PathEscape("wss://unprobed.example.com"), &relay))
+	require.Nil(t, relay.Data.Auth)
+
+	var resp presentation.APIErrorResponse
+	require.Equal(t, http.StatusNotFound, apiGet(t, h, "/api/v1/relays/"+url.PathEscape("wss://unknown.example.com"), &resp))
+	require.Equal(t, apiErrNotFound, resp.Error.Code)
+	require.Contains(t, resp.Error.Message, "wss://unknown.example.com")
+
+	resp = presentation.APIErrorResponse{}
+	require.Equal(t, http.StatusNotFound, apiGet(t, h, "/api/v1/relays/"+url.PathEscape("wss://removed.example.com"), &resp))
+	require.Equal(t, apiErrNotFound, resp.Error.Code)
+
+	resp = presentation.APIErrorResponse{}
+	require.Equal(t, http.StatusBadRequest, apiGet(t, h, "/api/v1/relays/not-a-relay", &resp))
+	require.Equal(t, apiErrInvalidParameter, resp.Error.Code)
+
+	resp = presentation.APIErrorResponse{}
+	require.Equal(t, http.StatusBadRequest, apiGet(t, h, "/api/v1/relays/"+url.PathEscape("wss://relay.example.com")+"?window=1y", &resp))
+	require.Equal(t, apiErrInvalidParameter, resp.Error.Code)
+}
+
+func TestAPIListRelayChecks(t *testing.T) {
+	now := time.Now()
+	h := newAPITestServer(&fakeRelayRepository{
+		relays: seedRelays("wss://relay.example.com"),
+		checks: []domain.HealthCheck{
+			{RelayURL: "wss://relay.example.com", CreatedAt: &now},
+			{RelayURL: "wss://relay.example.com", CreatedAt: &now},
+			{RelayURL: "wss://other.example.com", CreatedAt: &now},
+		},
+	})
+
+	var page struct {
+		Data []presentation.HealthCheckResponse `json:"data"`
+		Meta presentation.APIMeta               `json:"meta"`
+	}
+	require.Equal(t, http.StatusOK, apiGet(t, h, "/api/v1/relays/"+url.PathEscape("wss://relay.example.com")+"/checks?limit=1", &page))
+	require.Len(t, page.Data, 1)
+	require.Equal(t, 1, page.Meta.Count)
+	require.NotNil(t, page.Meta.NextOffset)
+	require.Equal(t, 1, *page.Meta.NextOffset)
+	require.Empty(t, page.Meta.NextCursor)
+
+	// Only limit and offset matter, the relay list parameters are ignored.
+	page.Meta = presentation.APIMeta{}
+	require.Equal(t, http.StatusOK, apiGet(t, h, "/api/v1/relays/"+url.PathEscape("wss://relay.example.com")+"/checks?offset=1&sort=size&order=up&cursor=x", &page))
+	require.Len(t, page.Data, 1)
+	require.Equal(t, 1, page.Meta.Offset)
+
+	var resp presentation.APIErrorResponse
+	require.Equal(t, http.StatusBadRequest, apiGet(t, h, "/api/v1/relays/"+url.PathEscape("wss://relay.example.com")+"/checks?limit=0", &resp))
+	require.Equal(t, apiErrInvalidParameter, resp.Error.Code)
+
+	resp = presentation.APIErrorResponse{}
+	require.Equal(t, http.StatusNotFound, apiGet(t, h, "/api/v1/relays/"+url.PathEscape("wss://unknown.example.com")+"/checks", &resp))
+	require.Equal(t, apiErrNotFound, resp.Error.Code)
+}
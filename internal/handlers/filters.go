@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/presentation"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/services"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// classifications are the relay classes the dashboard can filter by.
+var classifications = []string{"Public", "Paid", "WoT", "Private"}
+
+// networks are the networks relays can be filtered by.
+var networks = []string{
+	relayurl.NetworkClearnet,
+	relayurl.NetworkTor,
+	relayurl.NetworkI2P,
+	relayurl.NetworkLoki,
+}
+
+// requirements are the NIP-66 requirements relays can be filtered by, "!" keeps the relays without it.
+var requirements = []string{
+	"auth", "!auth",
+	"payment", "!payment",
+	"writes", "!writes",
+	"pow", "!pow",
+}
+
+// sortKeys are the columns relays can be sorted by.
+var sortKeys = []string{
+	repository.SortByURL,
+	repository.SortByName,
+	repository.SortByRTT,
+	repository.SortByLastCheck,
+	repository.SortByRandom,
+	repository.SortByRelevance,
+}
+
+// parsePage reads the limit and offset out of the query string of a request, ignoring any other parameter.
+func parsePage(q url.Values) (*services.Page, error) {
+	page := &services.Page{Limit: defaultPageLimit}
+
+	if v := q.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxPageLimit {
+			return nil, fmt.Errorf("limit must be a number between 1 and %d", maxPageLimit)
+		}
+		page.Limit = l
+	}
+
+	if v := q.Get("offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			return nil, fmt.Errorf("offset must be a positive number")
+		}
+		page.Offset = o
+	}
+
+	return page, nil
+}
+
+// parseRelayFilters builds the relay filters out of the query string of a request.
+// Shared by the dashboard and the JSON API so both accept the same parameters.
+func parseRelayFilters(q url.Values) (*services.RelayFilters, error) {
+	page, err := parsePage(q)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := &services.RelayFilters{
+		Limit:    &page.Limit,
+		Offset:   &page.Offset,
+		Software: q.Get("software"),
+		Country:  q.Get("country"),
+		Search:   strings.TrimSpace(q.Get("q")),
+		Language: q.Get("language"),
+		Cursor:   q.Get("cursor"),
+	}
+
+	for _, raw := range q["url"] {
+		u, err := relayurl.Normalize(raw)
+		if err != nil {
+			return nil, fmt.Errorf("url %q is not a valid relay url", raw)
+		}
+		filters.URLs = append(filters.URLs, u)
+	}
+
+	if filters.Cursor != "" && page.Offset > 0 {
+		return nil, fmt.Errorf("cursor and offset can't be used together")
+	}
+
+	if v := q.Get("classification"); v != "" {
+		if !slices.Contains(classifications, v) {
+			return nil, fmt.Errorf("classification must be one of %s", strings.Join(classifications, ", "))
+		}
+		filters.Classification = v
+	}
+
+	if v := q.Get("network"); v != "" {
+		if !slices.Contains(networks, v) {
+			return nil, fmt.Errorf("network must be one of %s", strings.Join(networks, ", "))
+		}
+		filters.Network = v
+	}
+
+	for _, v := range q["requirement"] {
+		if !slices.Contains(requirements, v) {
+			return nil, fmt.Errorf("requirement must be one of %s", strings.Join(requirements, ", "))
+		}
+		filters.Requirements = append(filters.Requirements, v)
+	}
+
+	if v := q.Get("sort"); v != "" {
+		if !slices.Contains(sortKeys, v) {
+			return nil, fmt.Errorf("sort must be one of %s", strings.Join(sortKeys, ", "))
+		}
+		filters.Sort = v
+	}
+
+	if filters.Search != "" && filters.Sort == "" {
+		// Searches list the best matches first unless asked otherwise.
+		filters.Sort = repository.SortByRelevance
+	}
+
+	if filters.Sort == repository.SortByRelevance && filters.Search == "" {
+		return nil, fmt.Errorf("sort relevance requires q")
+	}
+
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		filters.Desc = true
+	default:
+		return nil, fmt.Errorf("order must be asc or desc")
+	}
+
+	if v := q.Get("online"); v != "" {
+		online, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("online must be true or false")
+		}
+		filters.Online = &online
+	}
+
+	if v := q.Get("nip"); v != "" {
+		nip, err := strconv.Atoi(v)
+		if err != nil || nip < 0 {
+			return nil, fmt.Errorf("nip must be a positive number")
+		}
+		filters.NIP = &nip
+	}
+
+	return filters, nil
+}
+
+// ToRelayFiltersViewModel converts the parsed filters back to the values shown in the dashboard filters form.
+func ToRelayFiltersViewModel(
+	filters *services.RelayFilters,
+	facets domain.RelayFacets,
+) presentation.RelayFiltersViewModel {
+	vm := presentation.RelayFiltersViewModel{
+		Search:         filters.Search,
+		Classification: filters.Classification,
+		Country:        filters.Country,
+		Language:       filters.Language,
+		Network:        filters.Network,
+		Software:       filters.Software,
+		Sort:           filters.Sort,
+		Desc:           filters.Desc,
+		Limit:          defaultPageLimit,
+
+		NIPOptions:      toFacetOptions(facets.NIPs),
+		CountryOptions:  toFacetOptions(facets.Countries),
+		LanguageOptions: toFacetOptions(facets.Languages),
+		SoftwareOptions: toFacetOptions(facets.Software),
+	}
+
+	if filters.Limit != nil {
+		vm.Limit = *filters.Limit
+	}
+
+	if filters.Online != nil {
+		vm.Online = strconv.FormatBool(*filters.Online)
+	}
+
+	if filters.NIP != nil {
+		vm.NIP = strconv.Itoa(*filters.NIP)
+	}
+
+	// Relevance is the default sort of searches, leaving it implicit keeps the form valid once the search is cleared.
+	if vm.Sort == repository.SortByRelevance {
+		vm.Sort = ""
+		vm.Desc = false
+	}
+
+	// The dashboard filters by a single requirement at a time.
+	if len(filters.Requirements) > 0 {
+		vm.Requirement = filters.Requirements[0]
+	}
+
+	return vm
+}
+
+func toFacetOptions(facets []domain.Facet) []presentation.FacetOption {
+	options := make([]presentation.FacetOption, len(facets))
+	for i, f := range facets {
+		options[i] = presentation.FacetOption{
+			Value: f.Value,
+			Label: fmt.Sprintf("%s (%d)", f.Value, f.Count),
+		}
+	}
+
+	return options
+}
@@ -1,16 +1,32 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/presentation"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/services"
 	"github.com/danvergara/nostrich_watch_monitor/web/views"
 	"github.com/danvergara/nostrich_watch_monitor/web/views/components"
 )
 
+// dashboardStatsWindow is the window used to compute the uptime shown in the dashboard table.
+const dashboardStatsWindow = services.StatsWindowDay
+
+// mapGeohashPrecision is the geohash length of the area shown on the relay location map, about 39km x 20km.
+const mapGeohashPrecision = 4
+
+// healthHistoryPageSize is the number of past checks listed per page on the relay detail page.
+const healthHistoryPageSize = 20
+
+// detailDocumentVersions is the number of NIP-11 document versions shown on the relay detail page.
+const detailDocumentVersions = 10
+
 type RelaysHandler struct {
 	service services.RelayService
 }
@@ -20,26 +36,36 @@ func NewRelaysHandler(service services.RelayService) *RelaysHandler {
 }
 
 func (rh *RelaysHandler) HandleRelayIndex(w http.ResponseWriter, r *http.Request) {
-	limit := 10
-	offset := 0
-	filters := &services.RelayFilters{
-		Limit:  &limit,
-		Offset: &offset,
+	filters, err := parseRelayFilters(r.URL.Query())
+	if err != nil {
+		// Ignore malformed shared links and show the default listing instead
+		filters, _ = parseRelayFilters(url.Values{})
 	}
 
+	// Facets only populate the filter dropdowns, the dashboard works without them.
+	facets, err := rh.service.GetRelayFacets(r.Context())
+	if err != nil {
+		facets = domain.RelayFacets{}
+	}
+	filtersVM := ToRelayFiltersViewModel(filters, facets)
+
 	relays, err := rh.service.GetRelays(r.Context(), filters)
 	if err != nil {
 		// Show dashboard with empty table - template will show error state via EmptyState component
-		if err := views.Dashboard(ToRelayTableViewModels([]domain.Relay{})).Render(r.Context(), w); err != nil {
+		if err := views.Dashboard(ToRelayTableViewModels([]domain.Relay{}, nil), filtersVM, "").Render(r.Context(), w); err != nil {
 			// If even empty dashboard fails, try once more (template rendering rarely fails twice)
-			_ = views.Dashboard(ToRelayTableViewModels([]domain.Relay{})).Render(r.Context(), w)
+			_ = views.Dashboard(ToRelayTableViewModels([]domain.Relay{}, nil), filtersVM, "").Render(r.Context(), w)
 		}
 		return
 	}
 
-	if err := views.Dashboard(ToRelayTableViewModels(relays)).Render(r.Context(), w); err != nil {
+	stats := rh.relaysStats(r, relays)
+
+	nextCursor := services.NextCursor(relays, filters)
+
+	if err := views.Dashboard(ToRelayTableViewModels(relays, stats), filtersVM, nextCursor).Render(r.Context(), w); err != nil {
 		// Same approach - show empty dashboard instead of breaking the page
-		_ = views.Dashboard(ToRelayTableViewModels([]domain.Relay{})).Render(r.Context(), w)
+		_ = views.Dashboard(ToRelayTableViewModels([]domain.Relay{}, nil), filtersVM, "").Render(r.Context(), w)
 	}
 }
 
@@ -56,45 +82,119 @@ func (rh *RelaysHandler) HandleRelayDetail(w http.ResponseWriter, r *http.Reques
 
 	relay, err := rh.service.GetRelayByURL(r.Context(), relayURL)
 	if err != nil {
+		message := "Relay not found or temporarily unavailable"
+		if errors.Is(err, relayurl.ErrInvalid) {
+			message = "Invalid relay URL, only ws:// and wss:// URLs are supported"
+		}
+
 		// Create error state relay view instead of breaking the page
-		errorRelay := createErrorRelayViewModel(
-			relayURL,
-			"Relay not found or temporarily unavailable",
-		)
+		errorRelay := createErrorRelayViewModel(relayURL, message)
 		_ = views.RelayDetail(errorRelay).Render(r.Context(), w)
 		return
 	}
 
-	if err := views.RelayDetail(ToRelayDetailViewModel(relay)).Render(r.Context(), w); err != nil {
+	window, err := services.ParseStatsWindow(r.URL.Query().Get("window"))
+	if err != nil {
+		window = services.StatsWindowDay
+	}
+
+	// Stats are a nice to have, render the relay without them if they can't be computed.
+	stats, err := rh.service.GetRelayStats(r.Context(), relay.URL, window)
+	if err != nil {
+		stats = domain.RelayStats{RelayURL: relay.URL}
+	}
+
+	vm := ToRelayDetailViewModel(relay, stats, string(window))
+
+	// Only wss:// relays have a certificate, the card is left out for the others.
+	if sslCheck, err := rh.service.GetLatestSSLCheck(r.Context(), relay.URL); err == nil {
+		vm.SSL = ToSSLViewModel(sslCheck)
+	}
+
+	// The card is left out for relays never probed for NIP-42 authentication.
+	if authCheck, err := rh.service.GetLatestAuthCheck(r.Context(), relay.URL); err == nil {
+		vm.Auth = ToAuthViewModel(authCheck)
+	}
+
+	// Claimed NIPs are shown without their conformance when it can't be fetched.
+	if nipChecks, err := rh.service.GetLatestNIPChecks(r.Context(), relay.URL); err == nil {
+		vm.NIPChecks = ToNIPCheckViewModels(nipChecks)
+	}
+
+	// The history is a nice to have as well, the card is left out when it can't be fetched.
+	if versions, err := rh.service.GetDocumentHistory(r.Context(), relay.URL, &services.Page{
+		Limit: detailDocumentVersions,
+	}); err == nil {
+		vm.Documents = ToDocumentVersionViewModels(versions)
+	}
+
+	if err := views.RelayDetail(vm).Render(r.Context(), w); err != nil {
 		// Same approach - show error state instead of breaking
 		errorRelay := createErrorRelayViewModel(relayURL, "Error loading relay details")
 		_ = views.RelayDetail(errorRelay).Render(r.Context(), w)
 	}
 }
 
-func (rh *RelaysHandler) HandleRelayRows(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
+// HandleSoftwareInventory renders the relay implementations and versions found across relays.
+func (rh *RelaysHandler) HandleSoftwareInventory(w http.ResponseWriter, r *http.Request) {
+	window, err := services.ParseStatsWindow(r.URL.Query().Get("window"))
+	if err != nil {
+		window = services.StatsWindowDay
+	}
 
-	limit := 10 // default
-	offset := 0 // default
+	inventory, err := rh.service.GetSoftwareInventory(r.Context(), window)
+	if err != nil {
+		// Show an empty report instead of breaking the page
+		inventory = services.SoftwareInventory{Window: window}
+	}
 
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil {
-			limit = l
-		}
+	if err := views.Software(ToSoftwareInventoryViewModel(inventory)).Render(r.Context(), w); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// HandleNIPs renders how many relays claim support for each NIP,
+// along with the relays supporting the NIP given in the nip parameter, if any.
+func (rh *RelaysHandler) HandleNIPs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	matrix, err := rh.service.GetNIPSupportMatrix(r.Context())
+	if err != nil {
+		// Show an empty matrix instead of breaking the page
+		matrix = services.NIPSupportMatrix{}
 	}
 
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil {
-			offset = o
+	vm := presentation.NIPMatrixViewModel{
+		Relays: matrix.Relays,
+		NIPs:   ToNIPAdoptionViewModels(matrix),
+	}
+
+	if nip, err := strconv.Atoi(q.Get("nip")); err == nil && nip >= 0 {
+		window, err := services.ParseStatsWindow(q.Get("window"))
+		if err != nil {
+			window = services.StatsWindowDay
 		}
+
+		relays, err := rh.service.GetRelaysByNIP(r.Context(), nip, window, &services.Page{Limit: maxPageLimit})
+		if err != nil {
+			relays = nil
+		}
+		vm.Selected = ToNIPRelaysViewModel(nip, window, relays)
 	}
 
-	filters := &services.RelayFilters{
-		Limit:  &limit,
-		Offset: &offset,
+	if err := views.NIPs(vm).Render(r.Context(), w); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (rh *RelaysHandler) HandleRelayRows(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters, the same filters as the dashboard so infinite scroll keeps them
+	filters, err := parseRelayFilters(r.URL.Query())
+	if err != nil {
+		if err := components.ErrorRow("Invalid filters. Please try again.").Render(r.Context(), w); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
 	}
 
 	relays, err := rh.service.GetRelays(r.Context(), filters)
@@ -107,9 +207,13 @@ func (rh *RelaysHandler) HandleRelayRows(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	stats := rh.relaysStats(r, relays)
+	filtersVM := ToRelayFiltersViewModel(filters, domain.RelayFacets{})
+	nextCursor := services.NextCursor(relays, filters)
+
 	// Return only the table rows, not the full page
 	// Use graceful error handling instead of http.Error
-	if err := components.RelayTableRows(ToRelayTableViewModels(relays), offset+limit).Render(r.Context(), w); err != nil {
+	if err := components.RelayTableRows(ToRelayTableViewModels(relays, stats), filtersVM, nextCursor).Render(r.Context(), w); err != nil {
 		// Return error row that preserves table structure
 		if err := components.ErrorRow("Failed to render relay data. Please try again.").Render(r.Context(), w); err != nil {
 			// Final fallback
@@ -119,6 +223,81 @@ func (rh *RelaysHandler) HandleRelayRows(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (rh *RelaysHandler) HandleRelayHealthHistory(w http.ResponseWriter, r *http.Request) {
+	relayURL, err := relayurl.Normalize(r.URL.Query().Get("url"))
+	if err != nil {
+		vm := presentation.HealthHistoryViewModel{RelayURL: r.URL.Query().Get("url"), Page: 1}
+		_ = components.HealthHistoryCard(vm, "Invalid relay URL.").Render(r.Context(), w)
+		return
+	}
+
+	window, err := services.ParseStatsWindow(r.URL.Query().Get("window"))
+	if err != nil {
+		window = services.StatsWindowDay
+	}
+
+	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+
+	vm := presentation.HealthHistoryViewModel{
+		RelayURL: relayURL,
+		Window:   string(window),
+		Page:     page,
+		HasPrev:  page > 1,
+	}
+
+	until := time.Now()
+	since := until.Add(-window.Duration())
+
+	// The sparkline covers the whole window, the list only the requested page.
+	checks, err := rh.service.GetHealthHistory(r.Context(), relayURL, window, nil)
+	if err != nil {
+		_ = components.HealthHistoryCard(vm, "Failed to load the health history. Please try again.").
+			Render(r.Context(), w)
+		return
+	}
+	vm.Sparkline = buildSparkline(checks, since, until)
+
+	// Ask for one extra check to know whether there is a next page.
+	pageChecks, err := rh.service.GetHealthHistory(r.Context(), relayURL, window, &services.Page{
+		Limit:  healthHistoryPageSize + 1,
+		Offset: (page - 1) * healthHistoryPageSize,
+	})
+	if err != nil {
+		_ = components.HealthHistoryCard(vm, "Failed to load the health history. Please try again.").
+			Render(r.Context(), w)
+		return
+	}
+
+	if len(pageChecks) > healthHistoryPageSize {
+		vm.HasNext = true
+		pageChecks = pageChecks[:healthHistoryPageSize]
+	}
+	vm.Checks = ToHealthCheckRowViewModels(pageChecks)
+
+	if err := components.HealthHistoryCard(vm, "").Render(r.Context(), w); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// relaysStats returns the dashboard stats of the given relays indexed by URL.
+// Stats are a nice to have, so a failure renders the relays without uptime.
+func (rh *RelaysHandler) relaysStats(r *http.Request, relays []domain.Relay) map[string]domain.RelayStats {
+	urls := make([]string, len(relays))
+	for i, relay := range relays {
+		urls[i] = relay.URL
+	}
+
+	stats, err := rh.service.GetRelaysStats(r.Context(), urls, dashboardStatsWindow)
+	if err != nil {
+		return nil
+	}
+
+	return stats
+}
+
 // Helper function to create error state relay
 func createErrorRelayViewModel(url, errorMessage string) presentation.RelayDetailViewModel {
 	return presentation.RelayDetailViewModel{
@@ -128,6 +307,7 @@ func createErrorRelayViewModel(url, errorMessage string) presentation.RelayDetai
 		IsOnline:       false,
 		Classification: "Unknown",
 		LastCheckTime:  "Never",
+		StatsWindow:    string(services.StatsWindowDay),
 		// All other fields will be zero values, template should handle gracefully
 	}
 }
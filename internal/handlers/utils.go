@@ -1,17 +1,27 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/geohash"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/presentation"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/services"
 )
 
-// ToRelayDetailViewModel converts a domain.Relay to presentation.RelayDetailViewModel
-func ToRelayDetailViewModel(relay domain.Relay) presentation.RelayDetailViewModel {
+// ToRelayDetailViewModel converts a domain.Relay and its aggregated stats to presentation.RelayDetailViewModel
+func ToRelayDetailViewModel(
+	relay domain.Relay,
+	stats domain.RelayStats,
+	window string,
+) presentation.RelayDetailViewModel {
 	vm := presentation.RelayDetailViewModel{
 		// Basic Info
 		URL: relay.URL,
@@ -43,8 +53,21 @@ func ToRelayDetailViewModel(relay domain.Relay) presentation.RelayDetailViewMode
 
 		// Classification (derived from tags)
 		Classification: deriveClassification(relay.Tags),
+
+		// Observed Location
+		ObservedCountry: safeString(relay.ObservedCountry),
+		ObservedGeohash: safeString(relay.ObservedGeohash),
 	}
 
+	// Limitations, fees and retention
+	vm.Limitation = toLimitationViewModel(relay.Limitation)
+	vm.Fees = formatFees(relay.Fees)
+	vm.Retention = formatRetention(relay.Retention)
+	vm.PaymentsURL = safeString(relay.PaymentsURL)
+
+	vm.MapURL = buildMapURL(vm.ObservedGeohash)
+	vm.LocationMismatch = locationMismatch(vm.ObservedCountry, vm.Countries)
+
 	// Current Status (from embedded health check)
 	if relay.HealthCheck != nil {
 		vm.IsOnline = safeBool(relay.WebsocketSuccess)
@@ -57,9 +80,395 @@ func ToRelayDetailViewModel(relay domain.Relay) presentation.RelayDetailViewMode
 		vm.CurrentRTTNIP11 = relay.RTTNIP11
 	}
 
+	// Aggregated Health Data
+	vm.StatsWindow = window
+	vm.UptimePercent = stats.UptimePercent
+	vm.AvgRTTOpen = stats.AvgRTTOpen
+	vm.AvgRTTRead = stats.AvgRTTRead
+	vm.AvgRTTWrite = stats.AvgRTTWrite
+	vm.AvgRTTNIP11 = stats.AvgRTTNIP11
+	vm.TotalChecks = stats.TotalChecks
+	vm.FailedChecks = stats.FailedChecks
+
 	return vm
 }
 
+// ToSSLViewModel converts the latest domain.SSLCheck of a relay to presentation.SSLViewModel
+func ToSSLViewModel(check domain.SSLCheck) *presentation.SSLViewModel {
+	vm := &presentation.SSLViewModel{
+		Success:    check.Success,
+		Valid:      safeBool(check.Valid),
+		ChainValid: safeBool(check.ChainValid),
+		Issuer:     safeString(check.Issuer),
+		Subject:    safeString(check.Subject),
+		Error:      safeString(check.Error),
+	}
+
+	if check.CreatedAt != nil {
+		vm.CheckTime = FormatRelativeTime(*check.CreatedAt)
+	}
+	if check.ExpiresAt != nil {
+		vm.ExpiresAt = check.ExpiresAt.Format("Jan 2, 2006")
+	}
+	if check.DaysToExpiry != nil {
+		vm.DaysToExpiry = *check.DaysToExpiry
+	}
+	if check.ExpiringSoon != nil {
+		vm.ExpiringSoon = *check.ExpiringSoon
+	}
+
+	return vm
+}
+
+// ToAuthViewModel converts the latest domain.AuthCheck of a relay to presentation.AuthViewModel
+func ToAuthViewModel(check domain.AuthCheck) *presentation.AuthViewModel {
+	vm := &presentation.AuthViewModel{
+		Success:           check.Success,
+		ChallengeReceived: check.ChallengeReceived,
+		Authenticated:     check.Authenticated,
+		ReadAuthRequired:  check.ReadAuthRequired,
+		WriteAuthRequired: check.WriteAuthRequired,
+		NIP11Declared:     check.NIP11AuthRequired != nil,
+		NIP11AuthRequired: safeBool(check.NIP11AuthRequired),
+		NIP11Mismatch:     safeBool(check.NIP11Mismatch),
+		Error:             safeString(check.Error),
+	}
+
+	if check.CreatedAt != nil {
+		vm.CheckTime = FormatRelativeTime(*check.CreatedAt)
+	}
+
+	return vm
+}
+
+// ToNIPCheckViewModels converts the latest domain.NIPCheck of each NIP probed on a relay to presentation.NIPCheckViewModel
+func ToNIPCheckViewModels(checks []domain.NIPCheck) map[int]presentation.NIPCheckViewModel {
+	vms := make(map[int]presentation.NIPCheckViewModel, len(checks))
+
+	for _, check := range checks {
+		vm := presentation.NIPCheckViewModel{
+			Result: check.Result,
+			Detail: safeString(check.Detail),
+		}
+
+		if check.CreatedAt != nil {
+			vm.CheckTime = FormatRelativeTime(*check.CreatedAt)
+		}
+
+		vms[check.NIP] = vm
+	}
+
+	return vms
+}
+
+// ToDocumentVersionViewModels converts the NIP-11 document history of a relay to presentation.DocumentVersionViewModel
+func ToDocumentVersionViewModels(versions []services.DocumentVersion) []presentation.DocumentVersionViewModel {
+	vms := make([]presentation.DocumentVersionViewModel, len(versions))
+
+	for i, v := range versions {
+		vm := presentation.DocumentVersionViewModel{
+			Version: v.Version,
+			Hash:    v.ContentHash,
+			Changes: make([]presentation.DocumentChangeViewModel, len(v.Changes)),
+		}
+
+		if len(vm.Hash) > 12 {
+			vm.Hash = vm.Hash[:12]
+		}
+		if v.CreatedAt != nil {
+			vm.SeenAt = FormatRelativeTime(*v.CreatedAt)
+		}
+
+		for j, c := range v.Changes {
+			vm.Changes[j] = presentation.DocumentChangeViewModel{
+				Path: c.Path,
+				Old:  formatDocumentValue(c.Old),
+				New:  formatDocumentValue(c.New),
+			}
+		}
+
+		vms[i] = vm
+	}
+
+	return vms
+}
+
+// formatDocumentValue renders a NIP-11 field for display, strings without their quotes.
+func formatDocumentValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	return string(raw)
+}
+
+// ToSoftwareInventoryViewModel converts a services.SoftwareInventory to presentation.SoftwareInventoryViewModel
+func ToSoftwareInventoryViewModel(inventory services.SoftwareInventory) presentation.SoftwareInventoryViewModel {
+	vm := presentation.SoftwareInventoryViewModel{
+		Window:          string(inventory.Window),
+		Relays:          inventory.Relays,
+		Implementations: make([]presentation.SoftwareImplementationViewModel, len(inventory.Implementations)),
+	}
+
+	for i, impl := range inventory.Implementations {
+		ivm := presentation.SoftwareImplementationViewModel{
+			Name:           impl.Name,
+			Relays:         impl.Relays,
+			SharePercent:   percentOf(impl.Relays, inventory.Relays),
+			LatestVersion:  impl.LatestVersion,
+			OutdatedRelays: impl.OutdatedRelays,
+			UptimePercent:  impl.UptimePercent,
+			HasUptime:      impl.TotalChecks > 0,
+			AvgRTTOpen:     impl.AvgRTTOpen,
+			Versions:       make([]presentation.SoftwareVersionViewModel, len(impl.Versions)),
+		}
+
+		for j, v := range impl.Versions {
+			version := v.Version
+			if version == "" {
+				version = "Unknown"
+			}
+
+			ivm.Versions[j] = presentation.SoftwareVersionViewModel{
+				Version:       version,
+				Relays:        v.Relays,
+				SharePercent:  percentOf(v.Relays, impl.Relays),
+				Outdated:      v.Outdated,
+				UptimePercent: v.UptimePercent,
+				HasUptime:     v.TotalChecks > 0,
+				AvgRTTOpen:    v.AvgRTTOpen,
+			}
+		}
+
+		vm.Implementations[i] = ivm
+	}
+
+	return vm
+}
+
+// NIP adoption trend dimensions, in SVG user units.
+const (
+	nipTrendWidth  = 120
+	nipTrendHeight = 24
+)
+
+// ToNIPAdoptionViewModels converts the NIP support matrix to presentation.NIPAdoptionViewModel
+func ToNIPAdoptionViewModels(matrix services.NIPSupportMatrix) []presentation.NIPAdoptionViewModel {
+	vms := make([]presentation.NIPAdoptionViewModel, len(matrix.NIPs))
+
+	for i, a := range matrix.NIPs {
+		vm := presentation.NIPAdoptionViewModel{
+			NIP:          a.NIP,
+			Relays:       a.Relays,
+			SharePercent: a.SharePercent,
+		}
+
+		if len(a.Trend) > 1 {
+			points := make([]string, len(a.Trend))
+			for j, p := range a.Trend {
+				x := float64(j) * nipTrendWidth / float64(len(a.Trend)-1)
+				y := nipTrendHeight * (1 - p.SharePercent/100)
+				points[j] = fmt.Sprintf("%.1f,%.1f", x, y)
+			}
+
+			vm.TrendPoints = strings.Join(points, " ")
+			vm.TrendChange = a.Trend[len(a.Trend)-1].SharePercent - a.Trend[0].SharePercent
+		}
+
+		vms[i] = vm
+	}
+
+	return vms
+}
+
+// ToNIPRelaysViewModel converts the relays supporting a NIP to presentation.NIPRelaysViewModel
+func ToNIPRelaysViewModel(nip int, window services.StatsWindow, relays []services.RelayWithStats) *presentation.NIPRelaysViewModel {
+	vm := &presentation.NIPRelaysViewModel{
+		NIP:    nip,
+		Window: string(window),
+		Relays: make([]presentation.RelayTableViewModel, len(relays)),
+	}
+
+	for i, r := range relays {
+		vm.Relays[i] = ToRelayTableViewModel(r.Relay, r.Stats)
+	}
+
+	return vm
+}
+
+// percentOf returns part as a percentage of total, zero when total is zero.
+func percentOf(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return 100 * float64(part) / float64(total)
+}
+
+// toLimitationViewModel converts the NIP-11 limitation of a relay, nil when not declared.
+func toLimitationViewModel(l *domain.RelayLimitation) *presentation.LimitationViewModel {
+	if l == nil {
+		return nil
+	}
+
+	return &presentation.LimitationViewModel{
+		PaymentRequired:  l.PaymentRequired,
+		AuthRequired:     l.AuthRequired,
+		RestrictedWrites: l.RestrictedWrites,
+		MinPowDifficulty: l.MinPowDifficulty,
+		MaxMessageLength: l.MaxMessageLength,
+		MaxContentLength: l.MaxContentLength,
+		MaxSubscriptions: l.MaxSubscriptions,
+		MaxLimit:         l.MaxLimit,
+		MaxEventTags:     l.MaxEventTags,
+		OldestEvent:      formatSeconds(l.CreatedAtLowerLimit),
+		NewestEvent:      formatSeconds(l.CreatedAtUpperLimit),
+	}
+}
+
+// formatFees describes every fee a relay charges, one per line.
+func formatFees(fees *domain.RelayFees) []string {
+	if fees == nil {
+		return nil
+	}
+
+	var lines []string
+	for _, f := range fees.Admission {
+		lines = append(lines, fmt.Sprintf("Admission: %d %s", f.Amount, f.Unit))
+	}
+	for _, f := range fees.Subscription {
+		line := fmt.Sprintf("Subscription: %d %s", f.Amount, f.Unit)
+		if period := formatSeconds(int64(f.Period)); period != "" {
+			line += " per " + period
+		}
+		lines = append(lines, line)
+	}
+	for _, f := range fees.Publication {
+		line := fmt.Sprintf("Publication: %d %s per event", f.Amount, f.Unit)
+		if len(f.Kinds) > 0 {
+			line += " of kinds " + joinInts(f.Kinds)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// formatRetention describes every retention rule of a relay, one per line.
+func formatRetention(retention domain.RelayRetention) []string {
+	var lines []string
+
+	for _, r := range retention {
+		kinds := "All kinds"
+		if len(r.Kinds) > 0 {
+			ranges := make([]string, 0, len(r.Kinds))
+			for _, k := range r.Kinds {
+				ranges = append(ranges, joinIntsWith(k, "-"))
+			}
+			kinds = "Kinds " + strings.Join(ranges, ", ")
+		}
+
+		var limits []string
+		if r.Count > 0 {
+			limits = append(limits, fmt.Sprintf("%d events", r.Count))
+		}
+		if period := formatSeconds(r.Time); period != "" {
+			limits = append(limits, period)
+		}
+		if len(limits) == 0 {
+			limits = append(limits, "not stored")
+		}
+
+		lines = append(lines, kinds+": "+strings.Join(limits, ", "))
+	}
+
+	return lines
+}
+
+// formatSeconds returns a number of seconds in its largest whole unit, e.g. "30 days".
+// It's empty for zero, which NIP-11 uses for undeclared limits.
+func formatSeconds(seconds int64) string {
+	units := []struct {
+		name    string
+		seconds int64
+	}{
+		{"year", 365 * 24 * 3600},
+		{"day", 24 * 3600},
+		{"hour", 3600},
+		{"minute", 60},
+		{"second", 1},
+	}
+
+	if seconds <= 0 {
+		return ""
+	}
+
+	for _, u := range units {
+		if seconds%u.seconds == 0 {
+			n := seconds / u.seconds
+			if n == 1 {
+				return "1 " + u.name
+			}
+			return fmt.Sprintf("%d %ss", n, u.name)
+		}
+	}
+
+	return ""
+}
+
+func joinInts(values []int) string {
+	return joinIntsWith(values, ", ")
+}
+
+func joinIntsWith(values []int, sep string) string {
+	s := make([]string, 0, len(values))
+	for _, v := range values {
+		s = append(s, strconv.Itoa(v))
+	}
+
+	return strings.Join(s, sep)
+}
+
+// buildMapURL returns an embeddable OpenStreetMap URL showing the area around the given geohash.
+// It's empty when the geohash is empty or invalid.
+func buildMapURL(hash string) string {
+	point, err := geohash.Decode(hash)
+	if err != nil {
+		return ""
+	}
+	lat, lon := point.Center()
+
+	// Zoom out to the enclosing geohash cell, the location is city level at best.
+	area := point
+	if len(hash) > mapGeohashPrecision {
+		area, _ = geohash.Decode(hash[:mapGeohashPrecision])
+	}
+
+	q := url.Values{}
+	q.Set("bbox", fmt.Sprintf("%.4f,%.4f,%.4f,%.4f", area.MinLon, area.MinLat, area.MaxLon, area.MaxLat))
+	q.Set("layer", "mapnik")
+	q.Set("marker", fmt.Sprintf("%.4f,%.4f", lat, lon))
+
+	return "https://www.openstreetmap.org/export/embed.html?" + q.Encode()
+}
+
+// locationMismatch reports whether the observed country is missing from the self-reported ones.
+// Relays reporting no countries, or the "*" wildcard, never mismatch.
+func locationMismatch(observed string, reported []string) bool {
+	if observed == "" || len(reported) == 0 {
+		return false
+	}
+
+	for _, country := range reported {
+		if country == "*" || strings.EqualFold(country, observed) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // FormatRelativeTime converts a time to a human-readable relative format
 func FormatRelativeTime(t time.Time) string {
 	now := time.Now()
@@ -143,18 +552,25 @@ func deriveClassification(tags pq.StringArray) string {
 }
 
 // ToRelayTableViewModels converts a slice of domain.Relay to a slice of presentation.RelayTableViewModel
-func ToRelayTableViewModels(relays []domain.Relay) []presentation.RelayTableViewModel {
+// The stats are indexed by relay URL, relays without stats are rendered without uptime.
+func ToRelayTableViewModels(
+	relays []domain.Relay,
+	stats map[string]domain.RelayStats,
+) []presentation.RelayTableViewModel {
 	viewModels := make([]presentation.RelayTableViewModel, len(relays))
 
 	for i, relay := range relays {
-		viewModels[i] = ToRelayTableViewModel(relay)
+		viewModels[i] = ToRelayTableViewModel(relay, stats[relay.URL])
 	}
 
 	return viewModels
 }
 
-// ToRelayTableViewModel converts a single domain.Relay to presentation.RelayTableViewModel
-func ToRelayTableViewModel(relay domain.Relay) presentation.RelayTableViewModel {
+// ToRelayTableViewModel converts a single domain.Relay and its stats to presentation.RelayTableViewModel
+func ToRelayTableViewModel(
+	relay domain.Relay,
+	stats domain.RelayStats,
+) presentation.RelayTableViewModel {
 	vm := presentation.RelayTableViewModel{
 		URL: relay.URL,
 		Name: func() string {
@@ -165,6 +581,8 @@ func ToRelayTableViewModel(relay domain.Relay) presentation.RelayTableViewModel
 			return relay.URL
 		}(),
 		Classification: deriveClassification(relay.Tags),
+		UptimePercent:  stats.UptimePercent,
+		HasUptime:      stats.TotalChecks > 0,
 	}
 
 	// Current Status (from embedded health check)
@@ -182,3 +600,229 @@ func ToRelayTableViewModel(relay domain.Relay) presentation.RelayTableViewModel
 
 	return vm
 }
+
+// Sparkline dimensions, in SVG user units.
+const (
+	sparklineWidth        = 600
+	sparklineHeight       = 80
+	sparklineStatusHeight = 8
+)
+
+// ToHealthCheckRowViewModels converts a slice of domain.HealthCheck to a slice of presentation.HealthCheckRowViewModel
+func ToHealthCheckRowViewModels(checks []domain.HealthCheck) []presentation.HealthCheckRowViewModel {
+	rows := make([]presentation.HealthCheckRowViewModel, len(checks))
+
+	for i, hc := range checks {
+		rows[i] = presentation.HealthCheckRowViewModel{
+			IsOnline: safeBool(hc.WebsocketSuccess),
+			RTTOpen:  hc.RTTOpen,
+			RTTNIP11: hc.RTTNIP11,
+			Error: func() string {
+				if hc.WebsocketError != nil {
+					return *hc.WebsocketError
+				}
+				return safeString(hc.Nip11Error)
+			}(),
+		}
+
+		if hc.CreatedAt != nil {
+			rows[i].CheckTime = FormatRelativeTime(*hc.CreatedAt)
+		}
+	}
+
+	return rows
+}
+
+// buildSparkline lays out the given health checks, most recent first, on a time axis going from since to until.
+func buildSparkline(checks []domain.HealthCheck, since, until time.Time) presentation.SparklineViewModel {
+	sl := presentation.SparklineViewModel{
+		Width:        sparklineWidth,
+		Height:       sparklineHeight,
+		StatusY:      sparklineHeight - sparklineStatusHeight,
+		StatusHeight: sparklineStatusHeight,
+	}
+
+	span := until.Sub(since).Seconds()
+	if span <= 0 {
+		return sl
+	}
+
+	// Keep only the checks with a timestamp, oldest first.
+	ordered := make([]domain.HealthCheck, 0, len(checks))
+	for i := len(checks) - 1; i >= 0; i-- {
+		if checks[i].CreatedAt != nil {
+			ordered = append(ordered, checks[i])
+		}
+	}
+
+	for _, hc := range ordered {
+		if hc.RTTOpen != nil && *hc.RTTOpen > sl.MaxRTT {
+			sl.MaxRTT = *hc.RTTOpen
+		}
+	}
+
+	xOf := func(t time.Time) float64 {
+		return t.Sub(since).Seconds() / span * sparklineWidth
+	}
+
+	// Leave a small margin between the RTT line and the status strip.
+	chartHeight := float64(sl.StatusY - 2)
+
+	var segment []string
+	for i, hc := range ordered {
+		x := xOf(*hc.CreatedAt)
+
+		// Each check colors the strip until the next one.
+		next := float64(sparklineWidth)
+		if i+1 < len(ordered) {
+			next = xOf(*ordered[i+1].CreatedAt)
+		}
+
+		online := safeBool(hc.WebsocketSuccess)
+		sl.Status = append(sl.Status, presentation.SparklineStatus{
+			X:        fmt.Sprintf("%.2f", x),
+			Width:    fmt.Sprintf("%.2f", max(next-x, 1)),
+			IsOnline: online,
+		})
+
+		if !online || hc.RTTOpen == nil || sl.MaxRTT == 0 {
+			if len(segment) > 0 {
+				sl.Segments = append(sl.Segments, strings.Join(segment, " "))
+				segment = nil
+			}
+			continue
+		}
+
+		y := chartHeight - float64(*hc.RTTOpen)/float64(sl.MaxRTT)*(chartHeight-2)
+		segment = append(segment, fmt.Sprintf("%.2f,%.2f", x, y))
+	}
+
+	if len(segment) > 0 {
+		sl.Segments = append(sl.Segments, strings.Join(segment, " "))
+	}
+
+	return sl
+}
+
+// ToRelayResponse converts a domain.Relay to presentation.RelayResponse
+func ToRelayResponse(relay domain.Relay) presentation.RelayResponse {
+	resp := presentation.RelayResponse{
+		URL:         relay.URL,
+		Name:        safeString(relay.Name),
+		Description: safeString(relay.Description),
+		PubKey:      safeString(relay.PubKey),
+		Contact:     safeString(relay.Contact),
+
+		Software:       safeString(relay.Software),
+		Version:        safeString(relay.Version),
+		SupportedNIPs:  int64ArrayToIntSlice(relay.SupportedNIPs),
+		Countries:      nonNilStrings(relay.RelayCountries),
+		LanguageTags:   nonNilStrings(relay.LanguageTags),
+		Tags:           nonNilStrings(relay.Tags),
+		Classification: deriveClassification(relay.Tags),
+
+		Icon:           safeString(relay.Icon),
+		Banner:         safeString(relay.Banner),
+		PrivacyPolicy:  safeString(relay.PrivacyPolicy),
+		TermsOfService: safeString(relay.TermsOfService),
+		PostingPolicy:  safeString(relay.PostingPolicy),
+
+		Status: relay.Status,
+	}
+
+	if relay.HealthCheck != nil {
+		resp.IsOnline = safeBool(relay.WebsocketSuccess)
+		resp.LatestCheck = ToHealthCheckResponse(*relay.HealthCheck)
+	}
+
+	return resp
+}
+
+// ToHealthCheckResponse converts a domain.HealthCheck to presentation.HealthCheckResponse
+func ToHealthCheckResponse(hc domain.HealthCheck) *presentation.HealthCheckResponse {
+	return &presentation.HealthCheckResponse{
+		CheckedAt:        hc.CreatedAt,
+		WebsocketSuccess: safeBool(hc.WebsocketSuccess),
+		WebsocketError:   hc.WebsocketError,
+		NIP11Success:     hc.Nip11Success,
+		NIP11Error:       hc.Nip11Error,
+		RTTOpen:          hc.RTTOpen,
+		RTTRead:          hc.RTTRead,
+		RTTWrite:         hc.RTTWrite,
+		RTTNIP11:         hc.RTTNIP11,
+	}
+}
+
+// ToAuthCheckResponse converts a domain.AuthCheck to presentation.AuthCheckResponse
+func ToAuthCheckResponse(check domain.AuthCheck) *presentation.AuthCheckResponse {
+	return &presentation.AuthCheckResponse{
+		CheckedAt:         check.CreatedAt,
+		Success:           check.Success,
+		Error:             check.Error,
+		ChallengeReceived: check.ChallengeReceived,
+		Authenticated:     check.Authenticated,
+		ReadAuthRequired:  check.ReadAuthRequired,
+		WriteAuthRequired: check.WriteAuthRequired,
+		NIP11AuthRequired: check.NIP11AuthRequired,
+		NIP11Mismatch:     check.NIP11Mismatch,
+	}
+}
+
+// ToRelayDocumentResponse converts a services.DocumentVersion to presentation.RelayDocumentResponse
+func ToRelayDocumentResponse(v services.DocumentVersion) presentation.RelayDocumentResponse {
+	resp := presentation.RelayDocumentResponse{
+		Version:     v.Version,
+		ContentHash: v.ContentHash,
+		SeenAt:      v.CreatedAt,
+		Document:    v.Document,
+		Changes:     make([]presentation.DocumentChangeResponse, len(v.Changes)),
+	}
+
+	for i, c := range v.Changes {
+		resp.Changes[i] = presentation.DocumentChangeResponse{Path: c.Path, Old: c.Old, New: c.New}
+	}
+
+	return resp
+}
+
+// ToNIPAdoptionResponse converts a services.NIPAdoption to presentation.NIPAdoptionResponse
+func ToNIPAdoptionResponse(a services.NIPAdoption) presentation.NIPAdoptionResponse {
+	resp := presentation.NIPAdoptionResponse{
+		NIP:          a.NIP,
+		Relays:       a.Relays,
+		SharePercent: a.SharePercent,
+		Trend:        make([]presentation.NIPTrendPointResponse, len(a.Trend)),
+	}
+
+	for i, p := range a.Trend {
+		resp.Trend[i] = presentation.NIPTrendPointResponse{
+			Date:         p.Day,
+			Relays:       p.Relays,
+			SharePercent: p.SharePercent,
+		}
+	}
+
+	return resp
+}
+
+// ToRelayStatsResponse converts a domain.RelayStats to presentation.RelayStatsResponse
+func ToRelayStatsResponse(stats domain.RelayStats, window services.StatsWindow) *presentation.RelayStatsResponse {
+	return &presentation.RelayStatsResponse{
+		Window:        string(window),
+		TotalChecks:   stats.TotalChecks,
+		FailedChecks:  stats.FailedChecks,
+		UptimePercent: stats.UptimePercent,
+		AvgRTTOpen:    stats.AvgRTTOpen,
+		AvgRTTRead:    stats.AvgRTTRead,
+		AvgRTTWrite:   stats.AvgRTTWrite,
+		AvgRTTNIP11:   stats.AvgRTTNIP11,
+	}
+}
+
+// nonNilStrings converts a PostgreSQL string array to a Go string slice that encodes as [] instead of null
+func nonNilStrings(arr pq.StringArray) []string {
+	if arr == nil {
+		return []string{}
+	}
+	return []string(arr)
+}
@@ -16,5 +16,16 @@ func addRoutes(mux *http.ServeMux, _ *config.Config, fs fs.FS, handler handlers.
 
 	mux.HandleFunc("/", handler.HandleRelayIndex)
 	mux.HandleFunc("/relay", handler.HandleRelayDetail)
+	mux.HandleFunc("/relay/history", handler.HandleRelayHealthHistory)
+	mux.HandleFunc("/software", handler.HandleSoftwareInventory)
+	mux.HandleFunc("/nips", handler.HandleNIPs)
 	mux.HandleFunc("/api/relays", handler.HandleRelayRows) // New endpoint
+
+	// JSON API, relay URLs are path escaped (wss%3A%2F%2Frelay.example.com).
+	mux.HandleFunc("GET /api/v1/relays", handler.HandleAPIListRelays)
+	mux.HandleFunc("GET /api/v1/relays/{url}", handler.HandleAPIGetRelay)
+	mux.HandleFunc("GET /api/v1/relays/{url}/checks", handler.HandleAPIListRelayChecks)
+	mux.HandleFunc("GET /api/v1/relays/{url}/documents", handler.HandleAPIListRelayDocuments)
+	mux.HandleFunc("GET /api/v1/nips", handler.HandleAPIListNIPs)
+	mux.HandleFunc("GET /api/v1/nips/{nip}/relays", handler.HandleAPIListNIPRelays)
 }
@@ -0,0 +1,367 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository/postgres"
+)
+
+const (
+	// kindRelayDiscovery is the NIP-66 relay discovery event, its "d" tag is the relay URL.
+	kindRelayDiscovery = 30166
+
+	defaultTimeout = 30 * time.Second
+	defaultLimit   = 500
+
+	// lookupBatchSize is the number of candidates checked against the database at once.
+	lookupBatchSize = 500
+	// resolveTimeout is how long resolving the host of a new candidate can take.
+	resolveTimeout = 5 * time.Second
+)
+
+// sources maps the kinds crawled to the source recorded for the relays found in them.
+// Other monitors come first, their relays are known to be alive at some point.
+var sources = []struct {
+	kind   int
+	source string
+}{
+	{kindRelayDiscovery, domain.DiscoveredViaNIP66},
+	{nostr.KindRelayListMetadata, domain.DiscoveredViaNIP65},
+	{nostr.KindFollowList, domain.DiscoveredViaContacts},
+}
+
+// Result summarizes a discovery run.
+type Result struct {
+	Events     int // events fetched from the seed relays
+	Candidates int // distinct relay URLs found in them
+	Created    int // candidates that were not monitored yet
+}
+
+// Discoverer crawls seed relays for relay URLs published by users and other monitors.
+type Discoverer struct {
+	relayRepo  repository.RelayRepository
+	seedRelays []string
+	timeout    time.Duration
+	limit      int
+	logger     *slog.Logger
+
+	// lookupIP resolves the host of new candidates, net.DefaultResolver unless replaced by tests.
+	lookupIP func(ctx context.Context, host string) ([]net.IP, error)
+}
+
+// Option is a functional option type that allows us to configure the Discoverer.
+type Option func(*Discoverer)
+
+// NewDiscoverer returns a Discoverer instance given the necessary parameters.
+func NewDiscoverer(options ...Option) *Discoverer {
+	d := &Discoverer{
+		timeout: defaultTimeout,
+		limit:   defaultLimit,
+		logger:  slog.Default(),
+		lookupIP: func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		},
+	}
+
+	for _, opt := range options {
+		opt(d)
+	}
+
+	return d
+}
+
+// WithDB is a functional option to set database pool of connection.
+func WithDB(db *sqlx.DB) Option {
+	return func(d *Discoverer) {
+		d.relayRepo = postgres.NewRelayRepository(db)
+	}
+}
+
+// WithRelayRepository is a functional option to set the repository new relays are stored in.
+func WithRelayRepository(relayRepo repository.RelayRepository) Option {
+	return func(d *Discoverer) {
+		d.relayRepo = relayRepo
+	}
+}
+
+// WithSeedRelays is a functional option to set the relays crawled for relay URLs.
+func WithSeedRelays(seedRelays []string) Option {
+	return func(d *Discoverer) {
+		d.seedRelays = seedRelays
+	}
+}
+
+// WithTimeout is a functional option to set how long the seed relays are crawled for each kind.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Discoverer) {
+		d.timeout = timeout
+	}
+}
+
+// WithLimit is a functional option to set the number of events of each kind requested per seed relay.
+func WithLimit(limit int) Option {
+	return func(d *Discoverer) {
+		d.limit = limit
+	}
+}
+
+// WithLogger is a functional option to set the logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *Discoverer) {
+		d.logger = logger
+	}
+}
+
+// Discover crawls the seed relays and inserts the relays that are not monitored yet.
+func (d *Discoverer) Discover(ctx context.Context) (Result, error) {
+	var result Result
+
+	if len(d.seedRelays) == 0 {
+		return result, fmt.Errorf("no seed relays to discover from")
+	}
+
+	if d.relayRepo == nil {
+		return result, fmt.Errorf("no relay repository to store discovered relays")
+	}
+
+	pool := nostr.NewSimplePool(ctx)
+	defer pool.Close("discovery finished")
+
+	// Relay URL to the source it was first seen in.
+	candidates := make(map[string]string)
+	var order []string
+
+	for _, s := range sources {
+		result.Events += d.fetchKind(ctx, pool, s.kind, func(raw string) {
+			u, ok := NormalizeURL(raw)
+			if !ok {
+				return
+			}
+
+			if _, seen := candidates[u]; !seen {
+				candidates[u] = s.source
+				order = append(order, u)
+			}
+		})
+	}
+
+	result.Candidates = len(order)
+
+	d.logger.Info(
+		fmt.Sprintf(
+			"🔎 found %d relay candidates in %d events from %d seed relays",
+			result.Candidates,
+			result.Events,
+			len(d.seedRelays),
+		),
+	)
+
+	for start := 0; start < len(order); start += lookupBatchSize {
+		batch := order[start:min(start+lookupBatchSize, len(order))]
+
+		created, err := d.createMissing(ctx, batch, candidates)
+		result.Created += created
+		if err != nil {
+			return result, err
+		}
+	}
+
+	d.logger.Info(fmt.Sprintf("✅ discovered %d new relays", result.Created))
+
+	return result, nil
+}
+
+// fetchKind crawls the seed relays for the events of a kind, calling found with every relay URL they reference.
+// Each kind gets its own timeout, so a slow kind doesn't leave the next ones without time.
+// It returns the number of events fetched.
+func (d *Discoverer) fetchKind(ctx context.Context, pool *nostr.SimplePool, kind int, found func(raw string)) int {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	events := 0
+
+	for ev := range pool.FetchMany(ctx, d.seedRelays, nostr.Filter{Kinds: []int{kind}, Limit: d.limit}) {
+		events++
+
+		for _, raw := range RelayURLs(ev.Event) {
+			found(raw)
+		}
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		d.logger.Warn(fmt.Sprintf("⚠️ timed out fetching kind %d events from the seed relays after %s", kind, d.timeout))
+	}
+
+	return events
+}
+
+// createMissing inserts the relays of the batch that are not in the database yet.
+func (d *Discoverer) createMissing(
+	ctx context.Context,
+	batch []string,
+	candidates map[string]string,
+) (int, error) {
+	// Relays in any status are known, so the ones removed by hand are not added back.
+	existing, err := d.relayRepo.List(ctx, &repository.ListOption{URLs: batch})
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up discovered relays: %w", err)
+	}
+
+	known := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		known[r.URL] = true
+	}
+
+	created := 0
+
+	for _, u := range batch {
+		if known[u] {
+			continue
+		}
+
+		if !d.resolvesPublic(ctx, u) {
+			continue
+		}
+
+		source := candidates[u]
+
+		inserted, err := d.relayRepo.Create(ctx, domain.Relay{
+			URL:           u,
+			DiscoveredVia: &source,
+			UpdatedAt:     time.Now(),
+		})
+		if err != nil {
+			d.logger.Error(fmt.Sprintf("❌ failed to insert discovered relay %s: %v", u, err))
+			continue
+		}
+
+		// Another process may have inserted it since it was looked up.
+		if inserted {
+			created++
+		}
+	}
+
+	return created, nil
+}
+
+// resolvesPublic tells whether the host of a candidate only resolves to public addresses,
+// so a public name pointing into the monitor's network isn't probed. Hosts on overlay networks are not resolved.
+func (d *Discoverer) resolvesPublic(ctx context.Context, relayURL string) bool {
+	if relayurl.Network(relayURL) != relayurl.NetworkClearnet {
+		return true
+	}
+
+	u, err := url.Parse(relayURL)
+	if err != nil {
+		return false
+	}
+
+	// Addresses were already checked by NormalizeURL.
+	if net.ParseIP(u.Hostname()) != nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	ips, err := d.lookupIP(ctx, u.Hostname())
+	if err != nil {
+		d.logger.Warn(fmt.Sprintf("⚠️ skipping discovered relay %s, its host can't be resolved: %v", relayURL, err))
+		return false
+	}
+
+	for _, ip := range ips {
+		if !publicIP(ip) {
+			d.logger.Warn(fmt.Sprintf("⚠️ skipping discovered relay %s, its host resolves to %s", relayURL, ip))
+			return false
+		}
+	}
+
+	return len(ips) > 0
+}
+
+// RelayURLs returns the relay URLs referenced by an event, as they were published.
+func RelayURLs(ev *nostr.Event) []string {
+	var urls []string
+
+	switch ev.Kind {
+	case nostr.KindRelayListMetadata:
+		for _, tag := range ev.Tags {
+			if len(tag) >= 2 && tag[0] == "r" {
+				urls = append(urls, tag[1])
+			}
+		}
+	case nostr.KindFollowList:
+		// Relay hints of contact lists are a JSON object keyed by relay URL in the content.
+		var relays map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(ev.Content), &relays); err != nil {
+			return nil
+		}
+
+		for u := range relays {
+			urls = append(urls, u)
+		}
+	case kindRelayDiscovery:
+		if d := ev.Tags.GetD(); d != "" {
+			urls = append(urls, d)
+		}
+	}
+
+	return urls
+}
+
+// NormalizeURL returns the canonical form of a relay URL,
+// or false when it's not a public websocket URL worth monitoring.
+func NormalizeURL(raw string) (string, bool) {
+	canonical, err := relayurl.Normalize(raw)
+	if err != nil {
+		return "", false
+	}
+
+	u, err := url.Parse(canonical)
+	if err != nil {
+		return "", false
+	}
+
+	// URLs come from anyone's events, the monitor must not be made to connect to its own network.
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicIP(ip) {
+			return "", false
+		}
+
+		return canonical, true
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".local") || !strings.Contains(host, ".") {
+		return "", false
+	}
+
+	return canonical, true
+}
+
+// publicIP tells whether the address is reachable on the internet,
+// rather than loopback, private, link-local, unspecified or multicast.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
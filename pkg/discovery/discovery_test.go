@@ -0,0 +1,219 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
+)
+
+// fakeRelayRepository records the relays created by discovery, only the relays in known exist beforehand.
+// The relays in stored show up between the lookup and the insert.
+type fakeRelayRepository struct {
+	repository.RelayRepository
+
+	known   []domain.Relay
+	stored  map[string]bool
+	created []string
+}
+
+func (f *fakeRelayRepository) List(_ context.Context, opts *repository.ListOption) ([]domain.Relay, error) {
+	var relays []domain.Relay
+	for _, r := range f.known {
+		if slices.Contains(opts.URLs, r.URL) && (len(opts.Statuses) == 0 || slices.Contains(opts.Statuses, r.Status)) {
+			relays = append(relays, r)
+		}
+	}
+
+	return relays, nil
+}
+
+func (f *fakeRelayRepository) Create(_ context.Context, relay domain.Relay) (bool, error) {
+	if f.stored[relay.URL] {
+		return false, nil
+	}
+
+	f.created = append(f.created, relay.URL)
+	return true, nil
+}
+
+func TestRelayURLs(t *testing.T) {
+	tests := []struct {
+		name  string
+		event nostr.Event
+		want  []string
+	}{
+		{
+			name: "NIP-65 relay list",
+			event: nostr.Event{
+				Kind: nostr.KindRelayListMetadata,
+				Tags: nostr.Tags{
+					{"r", "wss://relay.damus.io"},
+					{"r", "wss://nos.lol", "write"},
+					{"p", "abc"},
+					{"r"},
+				},
+			},
+			want: []string{"wss://nos.lol", "wss://relay.damus.io"},
+		},
+		{
+			name: "contact list relay hints",
+			event: nostr.Event{
+				Kind:    nostr.KindFollowList,
+				Content: `{"wss://relay.damus.io":{"read":true,"write":true},"wss://nos.lol":{"read":true,"write":false}}`,
+			},
+			want: []string{"wss://nos.lol", "wss://relay.damus.io"},
+		},
+		{
+			name:  "contact list without relay hints",
+			event: nostr.Event{Kind: nostr.KindFollowList, Content: ""},
+			want:  nil,
+		},
+		{
+			name: "monitor relay discovery event",
+			event: nostr.Event{
+				Kind: kindRelayDiscovery,
+				Tags: nostr.Tags{{"d", "wss://relay.damus.io"}, {"rtt-open", "100"}},
+			},
+			want: []string{"wss://relay.damus.io"},
+		},
+		{
+			name:  "unrelated kind",
+			event: nostr.Event{Kind: nostr.KindTextNote, Tags: nostr.Tags{{"r", "wss://relay.damus.io"}}},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RelayURLs(&tt.event)
+			sort.Strings(got)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+		ok   bool
+	}{
+		{"wss://relay.damus.io", "wss://relay.damus.io", true},
+		{"  WSS://Relay.Damus.io/ ", "wss://relay.damus.io", true},
+		{"ws://relay.example.com:8080/nostr", "ws://relay.example.com:8080/nostr", true},
+		{"relay.damus.io", "", false},
+		{"https://relay.damus.io", "", false},
+		{"wss://localhost:7777", "", false},
+		{"wss://myrelay", "", false},
+		{"ws://127.0.0.1:7777", "", false},
+		{"wss://10.0.0.5", "", false},
+		{"wss://192.168.1.10", "", false},
+		{"ws://169.254.169.254", "", false},
+		{"ws://0.0.0.0", "", false},
+		{"ws://224.0.0.1", "", false},
+		{"ws://[::1]", "", false},
+		{"ws://[fe80::1]", "", false},
+		{"ws://[fc00::1]", "", false},
+		{"ws://[::ffff:127.0.0.1]", "", false},
+		{"wss://203.0.113.7", "wss://203.0.113.7", true},
+		{"wss://[2606:4700::1111]", "wss://[2606:4700::1111]", true},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := NormalizeURL(tt.raw)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCreateMissingSkipsPrivateHosts(t *testing.T) {
+	repo := &fakeRelayRepository{}
+
+	d := NewDiscoverer(
+		WithRelayRepository(repo),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	d.lookupIP = func(_ context.Context, host string) ([]net.IP, error) {
+		switch host {
+		case "public.example.com":
+			return []net.IP{net.ParseIP("203.0.113.7")}, nil
+		case "internal.example.com":
+			return []net.IP{net.ParseIP("10.0.0.1")}, nil
+		case "mixed.example.com":
+			return []net.IP{net.ParseIP("203.0.113.7"), net.ParseIP("127.0.0.1")}, nil
+		case "metadata.example.com":
+			return []net.IP{net.ParseIP("169.254.169.254")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	batch := []string{
+		"wss://public.example.com",
+		"wss://internal.example.com",
+		"wss://mixed.example.com",
+		"wss://metadata.example.com",
+		"wss://gone.example.com",
+		"ws://abcdef.onion",
+	}
+	candidates := map[string]string{}
+	for _, u := range batch {
+		candidates[u] = domain.DiscoveredViaNIP65
+	}
+
+	_, err := d.createMissing(context.Background(), batch, candidates)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wss://public.example.com", "ws://abcdef.onion"}, repo.created)
+}
+
+func TestCreateMissingCountsInsertedRelays(t *testing.T) {
+	repo := &fakeRelayRepository{stored: map[string]bool{"wss://b.example.com": true}}
+
+	d := NewDiscoverer(
+		WithRelayRepository(repo),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	d.lookupIP = func(context.Context, string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("203.0.113.7")}, nil
+	}
+
+	batch := []string{"wss://a.example.com", "wss://b.example.com"}
+	created, err := d.createMissing(context.Background(), batch, map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created)
+}
+
+func TestCreateMissingSkipsRemovedRelays(t *testing.T) {
+	repo := &fakeRelayRepository{known: []domain.Relay{
+		{URL: "wss://removed.example.com", Status: domain.RelayStatusRemoved},
+		{URL: "wss://disabled.example.com", Status: domain.RelayStatusDisabled},
+	}}
+
+	d := NewDiscoverer(
+		WithRelayRepository(repo),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	d.lookupIP = func(context.Context, string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("203.0.113.7")}, nil
+	}
+
+	batch := []string{"wss://removed.example.com", "wss://disabled.example.com", "wss://new.example.com"}
+	created, err := d.createMissing(context.Background(), batch, map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created)
+	assert.Equal(t, []string{"wss://new.example.com"}, repo.created)
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"time"
+)
+
+// AuthCheck is a struct that maps the auth_checks table on the PostgreSQL database.
+// It represents the NIP-42 authentication behavior observed on a relay.
+type AuthCheck struct {
+	RelayURL          string     `db:"relay_url"`
+	CreatedAt         *time.Time `db:"created_at"`
+	Success           bool       `db:"success"`
+	Error             *string    `db:"error"`
+	ChallengeReceived bool       `db:"challenge_received"`
+	Authenticated     bool       `db:"authenticated"`
+	ReadAuthRequired  bool       `db:"read_auth_required"`
+	WriteAuthRequired bool       `db:"write_auth_required"`
+	NIP11AuthRequired *bool      `db:"nip11_auth_required"`
+	NIP11Mismatch     *bool      `db:"nip11_mismatch"`
+}
+
+// AuthRequired tells whether reads or writes were observed to be gated behind authentication.
+func (c AuthCheck) AuthRequired() bool {
+	return c.ReadAuthRequired || c.WriteAuthRequired
+}
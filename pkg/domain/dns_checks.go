@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DNSCheck is a struct that maps the dns_checks table on the PostgreSQL database.
+// It represents the resolution of the relay host and where the resolved address is located.
+type DNSCheck struct {
+	RelayURL  string         `db:"relay_url"`
+	CreatedAt *time.Time     `db:"created_at"`
+	Success   bool           `db:"success"`
+	Error     *string        `db:"error"`
+	RTTDNS    *int           `db:"rtt_dns"`
+	IPv4      bool           `db:"ipv4"`
+	IPv6      bool           `db:"ipv6"`
+	IPs       pq.StringArray `db:"ips"`
+	Country   *string        `db:"country"`
+	Geohash   *string        `db:"geohash"`
+}
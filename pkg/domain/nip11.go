@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// RelayLimitation is the "limitation" object of a NIP-11 document, stored as JSONB.
+type RelayLimitation struct {
+	MaxMessageLength    int   `json:"max_message_length,omitempty"`
+	MaxSubscriptions    int   `json:"max_subscriptions,omitempty"`
+	MaxLimit            int   `json:"max_limit,omitempty"`
+	DefaultLimit        int   `json:"default_limit,omitempty"`
+	MaxSubidLength      int   `json:"max_subid_length,omitempty"`
+	MaxEventTags        int   `json:"max_event_tags,omitempty"`
+	MaxContentLength    int   `json:"max_content_length,omitempty"`
+	MinPowDifficulty    int   `json:"min_pow_difficulty,omitempty"`
+	CreatedAtLowerLimit int64 `json:"created_at_lower_limit,omitempty"`
+	CreatedAtUpperLimit int64 `json:"created_at_upper_limit,omitempty"`
+	AuthRequired        bool  `json:"auth_required"`
+	PaymentRequired     bool  `json:"payment_required"`
+	RestrictedWrites    bool  `json:"restricted_writes"`
+}
+
+// PowRequired tells whether events need a proof of work to be accepted.
+func (l RelayLimitation) PowRequired() bool {
+	return l.MinPowDifficulty > 0
+}
+
+func (l RelayLimitation) Value() (driver.Value, error) { return jsonValue(l) }
+func (l *RelayLimitation) Scan(src any) error          { return jsonScan(src, l) }
+
+// RelayFees is the "fees" object of a NIP-11 document, stored as JSONB.
+type RelayFees struct {
+	Admission    []AdmissionFee    `json:"admission,omitempty"`
+	Subscription []SubscriptionFee `json:"subscription,omitempty"`
+	Publication  []PublicationFee  `json:"publication,omitempty"`
+}
+
+// AdmissionFee is paid once to be allowed on the relay.
+type AdmissionFee struct {
+	Amount int    `json:"amount"`
+	Unit   string `json:"unit"`
+}
+
+// SubscriptionFee is paid every period, in seconds.
+type SubscriptionFee struct {
+	Amount int    `json:"amount"`
+	Unit   string `json:"unit"`
+	Period int    `json:"period"`
+}
+
+// PublicationFee is paid for every event of the given kinds.
+type PublicationFee struct {
+	Kinds  []int  `json:"kinds"`
+	Amount int    `json:"amount"`
+	Unit   string `json:"unit"`
+}
+
+// Empty tells whether no fee at all is declared.
+func (f RelayFees) Empty() bool {
+	return len(f.Admission) == 0 && len(f.Subscription) == 0 && len(f.Publication) == 0
+}
+
+func (f RelayFees) Value() (driver.Value, error) { return jsonValue(f) }
+func (f *RelayFees) Scan(src any) error          { return jsonScan(src, f) }
+
+// RelayRetention is the "retention" array of a NIP-11 document, stored as JSONB.
+type RelayRetention []RetentionRule
+
+// RetentionRule tells how long, in seconds, or how many events of the given kinds are kept.
+// Kinds holds single kinds or [from, to] ranges of kinds, none means every kind.
+type RetentionRule struct {
+	Time  int64   `json:"time,omitempty"`
+	Count int     `json:"count,omitempty"`
+	Kinds [][]int `json:"kinds,omitempty"`
+}
+
+func (r RelayRetention) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+
+	return jsonValue(r)
+}
+
+func (r *RelayRetention) Scan(src any) error { return jsonScan(src, r) }
+
+// jsonValue encodes a value for a JSONB column.
+func jsonValue(v any) (driver.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// jsonScan decodes a JSONB column, NULL leaves dest untouched.
+func jsonScan(src any, dest any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", src, dest)
+	}
+}
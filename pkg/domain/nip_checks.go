@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+)
+
+// Results of a NIP conformance probe, stored in NIPCheck.Result.
+const (
+	NIPCheckPass    = "pass"
+	NIPCheckFail    = "fail"
+	NIPCheckUnknown = "unknown" // the probe could not be carried out, e.g. writes are restricted
+)
+
+// NIPCheck is a struct that maps the nip_checks table on the PostgreSQL database.
+// It represents whether a relay was observed to behave as a NIP it claims to support requires.
+type NIPCheck struct {
+	RelayURL  string     `db:"relay_url"`
+	CreatedAt *time.Time `db:"created_at"`
+	NIP       int        `db:"nip"`
+	Result    string     `db:"result"`
+	Detail    *string    `db:"detail"`
+}
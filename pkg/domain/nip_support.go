@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"time"
+)
+
+// NIPSupport is the number of relays claiming support for a NIP in their NIP-11 document at a point in time.
+type NIPSupport struct {
+	Day    time.Time `db:"day"`
+	NIP    int       `db:"nip"`
+	Relays int       `db:"relays"`
+	// Total is the number of relays with a NIP-11 document at that time, whatever NIPs they claim.
+	Total int `db:"total"`
+}
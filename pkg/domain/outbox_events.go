@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of an event in the outbox, stored in OutboxEvent.Status.
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusPublished = "published"
+	OutboxStatusFailed    = "failed"  // given up on after OutboxMaxAttempts
+	OutboxStatusExpired   = "expired" // expired (NIP-40) before being delivered
+)
+
+// OutboxMaxAttempts is how many times the delivery of an event of the outbox is attempted before giving up on it.
+const OutboxMaxAttempts = 5
+
+// OutboxEvent is a struct that maps the outbox_events table on the PostgreSQL database.
+// It represents a signed event of the monitor waiting to be, or already, delivered to the publish targets.
+type OutboxEvent struct {
+	EventID     string          `db:"event_id"`
+	Kind        int             `db:"kind"`
+	RelayURL    *string         `db:"relay_url"` // the relay the event is about, if any
+	Event       json.RawMessage `db:"event"`
+	Status      string          `db:"status"`
+	Attempts    int             `db:"attempts"`
+	LastError   *string         `db:"last_error"`
+	CreatedAt   *time.Time      `db:"created_at"`
+	PublishedAt *time.Time      `db:"published_at"`
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+)
+
+// Statuses of an event sent to a publish target, stored in PublishedEvent.Status.
+const (
+	PublishStatusPublished = "published"
+	PublishStatusFailed    = "failed"
+)
+
+// PublishedEvent is a struct that maps the published_events table on the PostgreSQL database.
+// It represents the outcome of sending one of the monitor's events to one of its publish targets.
+type PublishedEvent struct {
+	EventID   string     `db:"event_id"`
+	Kind      int        `db:"kind"`
+	RelayURL  string     `db:"relay_url"`
+	Status    string     `db:"status"`
+	Error     *string    `db:"error"`
+	Attempts  int        `db:"attempts"`
+	CreatedAt *time.Time `db:"created_at"`
+}
@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RelayDocument is a struct that maps the relay_documents table on the PostgreSQL database.
+// It represents a version of the NIP-11 document of a relay, only stored when the document changes.
+type RelayDocument struct {
+	RelayURL    string          `db:"relay_url"`
+	Version     int             `db:"version"`
+	ContentHash string          `db:"content_hash"`
+	Document    json.RawMessage `db:"document"`
+	CreatedAt   *time.Time      `db:"created_at"`
+}
+
+// NewRelayDocument returns the given NIP-11 document of a relay along with its content hash.
+// The version is assigned when the document is stored.
+func NewRelayDocument(relayURL string, document json.RawMessage, seenAt time.Time) RelayDocument {
+	sum := sha256.Sum256(document)
+
+	return RelayDocument{
+		RelayURL:    relayURL,
+		ContentHash: hex.EncodeToString(sum[:]),
+		Document:    document,
+		CreatedAt:   &seenAt,
+	}
+}
+
+// DocumentChange is a field of a NIP-11 document that differs between two versions.
+type DocumentChange struct {
+	// Path of the field, e.g. "version" or "limitation.payment_required".
+	Path string
+	// Old is nil when the field was added.
+	Old json.RawMessage
+	// New is nil when the field was removed.
+	New json.RawMessage
+}
+
+// DiffDocuments returns the fields that differ from previous to current, ordered by path.
+// Objects are compared field by field, arrays as a whole.
+func DiffDocuments(previous, current json.RawMessage) ([]DocumentChange, error) {
+	old, err := flattenDocument(previous)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous document: %w", err)
+	}
+
+	cur, err := flattenDocument(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current document: %w", err)
+	}
+
+	var changes []DocumentChange
+
+	for path, value := range cur {
+		if before, ok := old[path]; !ok || !bytes.Equal(before, value) {
+			changes = append(changes, DocumentChange{Path: path, Old: before, New: value})
+		}
+	}
+
+	for path, value := range old {
+		if _, ok := cur[path]; !ok {
+			changes = append(changes, DocumentChange{Path: path, Old: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// flattenDocument maps the path of every leaf of a JSON document to its value.
+// Re-encoding the leaves gives a canonical form, insensitive to whitespace and key order.
+func flattenDocument(document json.RawMessage) (map[string]json.RawMessage, error) {
+	var v any
+	if err := json.Unmarshal(document, &v); err != nil {
+		return nil, err
+	}
+
+	leaves := map[string]json.RawMessage{}
+
+	var walk func(prefix string, v any) error
+	walk = func(prefix string, v any) error {
+		if obj, ok := v.(map[string]any); ok && len(obj) > 0 {
+			for key, child := range obj {
+				path := key
+				if prefix != "" {
+					path = prefix + "." + key
+				}
+
+				if err := walk(path, child); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		leaves[prefix] = b
+
+		return nil
+	}
+
+	if err := walk("", v); err != nil {
+		return nil, err
+	}
+
+	return leaves, nil
+}
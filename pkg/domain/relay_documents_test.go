@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRelayDocument(t *testing.T) {
+	a := NewRelayDocument("wss://relay.example.com", json.RawMessage(`{"name":"a"}`), time.Now())
+	b := NewRelayDocument("wss://relay.example.com", json.RawMessage(`{"name":"b"}`), time.Now())
+
+	require.Len(t, a.ContentHash, 64)
+	require.NotEqual(t, a.ContentHash, b.ContentHash)
+	require.Equal(t, a.ContentHash, NewRelayDocument("wss://other.example.com", a.Document, time.Now()).ContentHash)
+}
+
+func TestDiffDocuments(t *testing.T) {
+	previous := json.RawMessage(`{
+		"software": "strfry",
+		"version": "1.0.0",
+		"supported_nips": [1, 11],
+		"limitation": {"payment_required": false, "max_limit": 500},
+		"posting_policy": "https://relay.example.com/policy"
+	}`)
+	current := json.RawMessage(`{
+		"limitation": {"max_limit": 500, "payment_required": true},
+		"software": "strfry",
+		"supported_nips": [1, 11, 42],
+		"version": "1.0.1",
+		"payments_url": "https://relay.example.com/pay"
+	}`)
+
+	changes, err := DiffDocuments(previous, current)
+	require.NoError(t, err)
+	require.Equal(t, []DocumentChange{
+		{Path: "limitation.payment_required", Old: json.RawMessage(`false`), New: json.RawMessage(`true`)},
+		{Path: "payments_url", New: json.RawMessage(`"https://relay.example.com/pay"`)},
+		{Path: "posting_policy", Old: json.RawMessage(`"https://relay.example.com/policy"`)},
+		{Path: "supported_nips", Old: json.RawMessage(`[1,11]`), New: json.RawMessage(`[1,11,42]`)},
+		{Path: "version", Old: json.RawMessage(`"1.0.0"`), New: json.RawMessage(`"1.0.1"`)},
+	}, changes)
+
+	// Key order and whitespace are not changes.
+	changes, err = DiffDocuments(previous, json.RawMessage(`{"version":"1.0.0","software":"strfry",
+		"supported_nips":[1,11],"posting_policy":"https://relay.example.com/policy",
+		"limitation":{"max_limit":500,"payment_required":false}}`))
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	_, err = DiffDocuments(previous, json.RawMessage(`{`))
+	require.Error(t, err)
+}
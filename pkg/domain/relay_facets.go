@@ -0,0 +1,15 @@
+package domain
+
+// Facet is a distinct value of a relay attribute and the number of relays sharing it.
+type Facet struct {
+	Value string `db:"value"`
+	Count int    `db:"count"`
+}
+
+// RelayFacets represents the values relays can be filtered by on the dashboard.
+type RelayFacets struct {
+	NIPs      []Facet
+	Countries []Facet
+	Languages []Facet
+	Software  []Facet
+}
@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"time"
+)
+
+// RelayStats is a struct that maps the aggregated health_checks of a relay over a time window.
+// It represents the uptime and the average latency of the given relay.
+type RelayStats struct {
+	RelayURL      string    `db:"relay_url"`
+	FirstCheckAt  time.Time `db:"first_check_at"`
+	TotalChecks   int       `db:"total_checks"`
+	FailedChecks  int       `db:"failed_checks"`
+	UptimePercent float64   `db:"uptime_percent"`
+	AvgRTTOpen    *int      `db:"avg_rtt_open"`
+	AvgRTTRead    *int      `db:"avg_rtt_read"`
+	AvgRTTWrite   *int      `db:"avg_rtt_write"`
+	AvgRTTNIP11   *int      `db:"avg_rtt_nip11"`
+}
@@ -6,6 +6,30 @@ import (
 	"github.com/lib/pq"
 )
 
+// Sources a relay can be discovered from, stored in Relay.DiscoveredVia.
+const (
+	DiscoveredViaSeed     = "seed"     // relays.txt
+	DiscoveredViaNIP65    = "nip65"    // kind 10002 relay lists
+	DiscoveredViaContacts = "contacts" // relay hints of kind 3 contact lists
+	DiscoveredViaNIP66    = "nip66"    // "d" tags of other monitors' kind 30166 events
+	DiscoveredViaManual   = "manual"   // monitor relays add
+	DiscoveredViaImport   = "import"   // monitor relays import
+)
+
+// Lifecycle statuses of a relay, stored in Relay.Status.
+const (
+	RelayStatusActive   = "active"   // checked at the regular frequency
+	RelayStatusDisabled = "disabled" // not checked at all
+	RelayStatusRetired  = "retired"  // dead for a while, checked at a lower frequency
+	RelayStatusRemoved  = "removed"  // removed by hand, never checked, listed nor discovered again
+)
+
+// RelayStatuses are all the statuses a relay can be in.
+var RelayStatuses = []string{RelayStatusActive, RelayStatusDisabled, RelayStatusRetired, RelayStatusRemoved}
+
+// VisibleRelayStatuses are the statuses of the relays shown on the dashboard and the API.
+var VisibleRelayStatuses = []string{RelayStatusActive, RelayStatusDisabled, RelayStatusRetired}
+
 // Relay is a struct that maps the relays table on the PostgreSQL database.
 // It represents the NIP-11 relay information with database tags for sqlx.
 type Relay struct {
@@ -31,8 +55,28 @@ type Relay struct {
 	Tags          pq.StringArray `db:"tags"`
 	PostingPolicy *string        `db:"posting_policy"`
 
+	// Limitation, Fees and Retention are kept as declared in NIP-11, nil when not declared.
+	Limitation  *RelayLimitation `db:"limitation"`
+	Fees        *RelayFees       `db:"fees"`
+	Retention   RelayRetention   `db:"retention"`
+	PaymentsURL *string          `db:"payments_url"`
+
+	// ObservedCountry and ObservedGeohash locate the relay address as resolved by the monitor,
+	// unlike RelayCountries which is self-reported in NIP-11.
+	ObservedCountry *string `db:"observed_country"`
+	ObservedGeohash *string `db:"observed_geohash"`
+
+	// Status is the lifecycle status of the relay, see RelayStatusActive.
+	Status string `db:"status"`
+
+	// DiscoveredVia is the source the relay was first found in.
+	DiscoveredVia *string `db:"discovered_via"`
+
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
 
+	// SortKey is the value the relay was sorted by when listed, used to build pagination cursors.
+	SortKey *string `db:"sort_key"`
+
 	*HealthCheck `db:"health_checks"`
 }
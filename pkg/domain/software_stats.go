@@ -0,0 +1,14 @@
+package domain
+
+// SoftwareStats is a struct that maps the aggregated health_checks of the relays
+// reporting the same software and version in NIP-11, over a time window.
+type SoftwareStats struct {
+	Software         string `db:"software"`
+	Version          string `db:"version"`
+	Relays           int    `db:"relays"`
+	TotalChecks      int    `db:"total_checks"`
+	SuccessfulChecks int    `db:"successful_checks"`
+	// RTTOpenSum and RTTOpenCount let averages be combined across groups.
+	RTTOpenSum   int64 `db:"rtt_open_sum"`
+	RTTOpenCount int   `db:"rtt_open_count"`
+}
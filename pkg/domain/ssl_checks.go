@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+)
+
+// SSLCheck is a struct that maps the ssl_checks table on the PostgreSQL database.
+// It represents the TLS certificate presented by a wss:// relay.
+type SSLCheck struct {
+	RelayURL     string     `db:"relay_url"`
+	CreatedAt    *time.Time `db:"created_at"`
+	Success      bool       `db:"success"`
+	Error        *string    `db:"error"`
+	Valid        *bool      `db:"valid"`
+	ChainValid   *bool      `db:"chain_valid"`
+	Issuer       *string    `db:"issuer"`
+	Subject      *string    `db:"subject"`
+	NotBefore    *time.Time `db:"not_before"`
+	ExpiresAt    *time.Time `db:"expires_at"`
+	DaysToExpiry *int       `db:"days_to_expiry"`
+	ExpiringSoon *bool      `db:"expiring_soon"` // within the warning window of the worker
+}
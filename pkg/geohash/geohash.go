@@ -0,0 +1,105 @@
+// Package geohash encodes coordinates as geohashes, the format NIP-66 uses for the location of relays,
+// and decodes them back to the area they cover.
+package geohash
+
+import (
+	"errors"
+	"strings"
+)
+
+// base32 is the geohash alphabet, it skips "a", "i", "l" and "o".
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// ErrInvalid is returned when decoding a string that is not a geohash.
+var ErrInvalid = errors.New("invalid geohash")
+
+// Box is the area covered by a geohash.
+type Box struct {
+	MinLat, MaxLat float64
+	MinLon, MaxLon float64
+}
+
+// Center returns the coordinates of the center of the box.
+func (b Box) Center() (lat, lon float64) {
+	return (b.MinLat + b.MaxLat) / 2, (b.MinLon + b.MaxLon) / 2
+}
+
+// Encode returns the geohash of the given coordinates with the given number of characters.
+func Encode(lat, lon float64, precision int) string {
+	var sb strings.Builder
+
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+
+	// Bits alternate between longitude and latitude, starting with longitude.
+	even := true
+	bit, ch := 0, 0
+
+	for sb.Len() < precision {
+		if even {
+			ch = ch<<1 | bisect(&lonRange, lon)
+		} else {
+			ch = ch<<1 | bisect(&latRange, lat)
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			sb.WriteByte(base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+
+	return sb.String()
+}
+
+// Decode returns the area covered by the given geohash.
+func Decode(hash string) (Box, error) {
+	if hash == "" {
+		return Box{}, ErrInvalid
+	}
+
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	even := true
+
+	for _, c := range strings.ToLower(hash) {
+		idx := strings.IndexRune(base32, c)
+		if idx < 0 {
+			return Box{}, ErrInvalid
+		}
+
+		for mask := 16; mask > 0; mask >>= 1 {
+			r := &latRange
+			if even {
+				r = &lonRange
+			}
+
+			mid := (r[0] + r[1]) / 2
+			if idx&mask != 0 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+	}
+
+	return Box{
+		MinLat: latRange[0],
+		MaxLat: latRange[1],
+		MinLon: lonRange[0],
+		MaxLon: lonRange[1],
+	}, nil
+}
+
+// bisect halves the range towards v and returns 1 when v falls in the upper half.
+func bisect(r *[2]float64, v float64) int {
+	mid := (r[0] + r[1]) / 2
+	if v >= mid {
+		r[0] = mid
+		return 1
+	}
+
+	r[1] = mid
+	return 0
+}
@@ -0,0 +1,48 @@
+package geohash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		lat, lon  float64
+		precision int
+		want      string
+	}{
+		{57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{42.6, -5.6, 5, "ezs42"},
+		{-25.382708, -49.265506, 6, "6gkzwg"},
+		{0, 0, 1, "s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, Encode(tt.lat, tt.lon, tt.precision))
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	box, err := Decode("ezs42")
+	require.NoError(t, err)
+
+	lat, lon := box.Center()
+	assert.InDelta(t, 42.605, lat, 0.01)
+	assert.InDelta(t, -5.603, lon, 0.01)
+
+	// Decoding the encoded coordinates gives back a box containing them.
+	box, err = Decode(Encode(57.64911, 10.40744, 6))
+	require.NoError(t, err)
+	assert.True(t, box.MinLat <= 57.64911 && 57.64911 <= box.MaxLat)
+	assert.True(t, box.MinLon <= 10.40744 && 10.40744 <= box.MaxLon)
+
+	_, err = Decode("")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = Decode("abc")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,192 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+)
+
+const (
+	// CheckAuth is the name of the NIP-42 authentication check.
+	CheckAuth = "auth"
+	// authRequiredPrefix is the machine-readable prefix relays use to refuse unauthenticated clients.
+	authRequiredPrefix = "auth-required:"
+	// authSubscriptionID is the id of the subscription probing whether reads are gated.
+	authSubscriptionID = "nostrich-watch-auth"
+)
+
+// authCheck probes how a relay handles NIP-42 authentication, instead of trusting what its NIP-11 document says.
+// It runs on a dedicated connection, so authenticating doesn't change what the other checks observe.
+type authCheck struct {
+	timeout time.Duration
+}
+
+// NewAuthCheck returns the check observing whether reads and writes are gated behind NIP-42 authentication.
+func NewAuthCheck(timeout time.Duration) Check {
+	return authCheck{timeout: timeout}
+}
+
+func (c authCheck) Name() string           { return CheckAuth }
+func (c authCheck) Timeout() time.Duration { return c.timeout }
+
+func (c authCheck) Run(ctx context.Context, run *CheckRun) error {
+	result := &domain.AuthCheck{
+		RelayURL:  run.RelayURL,
+		CreatedAt: &run.Result.CreatedAt,
+	}
+	run.Auth = result
+
+	if err := c.probe(ctx, run, result); err != nil {
+		result.Error = nullString(err.Error())
+		return err
+	}
+
+	result.Success = true
+
+	// Compare what was observed with what the relay declares, if it declares anything.
+	if run.Info != nil && run.Info.Limitation != nil {
+		declared := run.Info.Limitation.AuthRequired
+		mismatch := declared != result.AuthRequired()
+
+		result.NIP11AuthRequired = &declared
+		result.NIP11Mismatch = &mismatch
+
+		if mismatch {
+			run.rc.logger.Warn(
+				fmt.Sprintf(
+					"⚠️ %s declares auth_required=%t in NIP-11 but was observed with auth_required=%t",
+					run.RelayURL,
+					declared,
+					result.AuthRequired(),
+				),
+			)
+		}
+	}
+
+	run.rc.logger.Info(
+		fmt.Sprintf(
+			"✅ Probed NIP-42 auth on %s (challenge: %t, authenticated: %t, read gated: %t, write gated: %t)",
+			run.RelayURL,
+			result.ChallengeReceived,
+			result.Authenticated,
+			result.ReadAuthRequired,
+			result.WriteAuthRequired,
+		),
+	)
+
+	return nil
+}
+
+// probe reads and writes without authenticating, then answers the AUTH challenge of the relay, if any.
+func (c authCheck) probe(ctx context.Context, run *CheckRun, result *domain.AuthCheck) error {
+	pub, err := nostr.GetPublicKey(run.rc.privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to derive the monitor's public key: %w", err)
+	}
+
+	s, err := dialRelay(ctx, run.rc.httpClient, run.RelayURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() {
+		_ = s.Close()
+	}()
+
+	// Read the monitor's own probe event.
+	if err := s.send(ctx, &nostr.ReqEnvelope{
+		SubscriptionID: authSubscriptionID,
+		Filters:        nostr.Filters{{Kinds: []int{probeKind}, Authors: []string{pub}, Limit: 1}},
+	}); err != nil {
+		return err
+	}
+
+	env, err := s.await(ctx, func(env nostr.Envelope) bool {
+		switch env := env.(type) {
+		case *nostr.EOSEEnvelope:
+			return string(*env) == authSubscriptionID
+		case *nostr.ClosedEnvelope:
+			return env.SubscriptionID == authSubscriptionID
+		}
+		return false
+	})
+	if err != nil {
+		return fmt.Errorf("no answer to REQ: %w", err)
+	}
+
+	if closed, ok := env.(*nostr.ClosedEnvelope); ok {
+		result.ReadAuthRequired = strings.HasPrefix(closed.Reason, authRequiredPrefix)
+	} else {
+		closeSub := nostr.CloseEnvelope(authSubscriptionID)
+		if err := s.send(ctx, &closeSub); err != nil {
+			return err
+		}
+	}
+
+	// Write a new version of the probe event.
+	probe := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      probeKind,
+		Tags:      nostr.Tags{{"d", probeIdentifier}},
+	}
+	if err := probe.Sign(run.rc.privateKey); err != nil {
+		return fmt.Errorf("failed to sign the write probe: %w", err)
+	}
+
+	ok, err := s.submit(ctx, probe.ID, &nostr.EventEnvelope{Event: probe})
+	if err != nil {
+		return fmt.Errorf("no answer to EVENT: %w", err)
+	}
+	result.WriteAuthRequired = !ok.OK && strings.HasPrefix(ok.Reason, authRequiredPrefix)
+
+	// Relays may only send their challenge once something was refused.
+	if s.challenge == "" && result.AuthRequired() {
+		if _, err := s.await(ctx, func(env nostr.Envelope) bool {
+			_, isAuth := env.(*nostr.AuthEnvelope)
+			return isAuth
+		}); err != nil {
+			return fmt.Errorf("auth required but no AUTH challenge sent: %w", err)
+		}
+	}
+
+	if s.challenge == "" {
+		return nil
+	}
+	result.ChallengeReceived = true
+
+	auth := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindClientAuthentication,
+		Tags:      nostr.Tags{{"relay", run.RelayURL}, {"challenge", s.challenge}},
+	}
+	if err := auth.Sign(run.rc.privateKey); err != nil {
+		return fmt.Errorf("failed to sign the AUTH event: %w", err)
+	}
+
+	ok, err = s.submit(ctx, auth.ID, &nostr.AuthEnvelope{Event: auth})
+	if err != nil {
+		return fmt.Errorf("no answer to AUTH: %w", err)
+	}
+	result.Authenticated = ok.OK
+
+	return nil
+}
+
+func (c authCheck) Tags(run *CheckRun) nostr.Tags {
+	tags := nostr.Tags{}
+	if run.Auth == nil || !run.Auth.Success {
+		return tags
+	}
+
+	if run.Auth.AuthRequired() {
+		return append(tags, nostr.Tag{"R", "auth"})
+	}
+
+	return append(tags, nostr.Tag{"R", "!auth"})
+}
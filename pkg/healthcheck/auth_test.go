@@ -0,0 +1,93 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip11"
+	"github.com/stretchr/testify/require"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+)
+
+// newAuthCheckRun returns the state of a check run on the mock relay, with a monitor key to authenticate with.
+func newAuthCheckRun(t *testing.T, mr *mockRelay) *CheckRun {
+	t.Helper()
+
+	run := newCheckRun(t, mr.URL())
+	run.rc.privateKey = nostr.GeneratePrivateKey()
+
+	return run
+}
+
+func TestAuthCheckOpenRelay(t *testing.T) {
+	mr := newMockRelay(t)
+	run := newAuthCheckRun(t, mr)
+	run.Info = &nip11.RelayInformationDocument{Limitation: &nip11.RelayLimitationDocument{AuthRequired: false}}
+
+	c := NewAuthCheck(5 * time.Second)
+	require.NoError(t, c.Run(context.Background(), run))
+
+	require.True(t, run.Auth.Success)
+	require.False(t, run.Auth.ChallengeReceived)
+	require.False(t, run.Auth.AuthRequired())
+	require.False(t, *run.Auth.NIP11Mismatch)
+	require.Equal(t, nostr.Tags{{"R", "!auth"}}, c.Tags(run))
+}
+
+func TestAuthCheckGatedRelay(t *testing.T) {
+	mr := newMockRelay(t)
+	mr.challenge = "challenge"
+	mr.requireAuth = true
+
+	run := newAuthCheckRun(t, mr)
+
+	c := NewAuthCheck(5 * time.Second)
+	require.NoError(t, c.Run(context.Background(), run))
+
+	require.True(t, run.Auth.Success)
+	require.True(t, run.Auth.ChallengeReceived)
+	require.True(t, run.Auth.Authenticated)
+	require.True(t, run.Auth.ReadAuthRequired)
+	require.True(t, run.Auth.WriteAuthRequired)
+	require.Empty(t, mr.stored())
+
+	// Without a NIP-11 document there's nothing to contradict.
+	require.Nil(t, run.Auth.NIP11Mismatch)
+	require.Equal(t, nostr.Tags{{"R", "auth"}}, c.Tags(run))
+}
+
+func TestAuthCheckChallengeWithoutGating(t *testing.T) {
+	mr := newMockRelay(t)
+	mr.challenge = "challenge"
+
+	run := newAuthCheckRun(t, mr)
+	run.Info = &nip11.RelayInformationDocument{Limitation: &nip11.RelayLimitationDocument{AuthRequired: true}}
+
+	c := NewAuthCheck(5 * time.Second)
+	require.NoError(t, c.Run(context.Background(), run))
+
+	// Authentication is offered but not required, which contradicts the document.
+	require.True(t, run.Auth.ChallengeReceived)
+	require.True(t, run.Auth.Authenticated)
+	require.False(t, run.Auth.AuthRequired())
+	require.True(t, *run.Auth.NIP11AuthRequired)
+	require.True(t, *run.Auth.NIP11Mismatch)
+	require.Len(t, mr.stored(), 1)
+}
+
+func TestNIP11TagsDeferToObservedAuth(t *testing.T) {
+	run := &CheckRun{
+		Result: &HealthCheck{},
+		Info:   &nip11.RelayInformationDocument{Limitation: &nip11.RelayLimitationDocument{AuthRequired: true}},
+	}
+
+	c := NewNIP11Check(time.Second)
+	require.Contains(t, c.Tags(run), nostr.Tag{"R", "auth"})
+
+	run.Auth = &domain.AuthCheck{Success: true}
+	require.NotContains(t, c.Tags(run), nostr.Tag{"R", "auth"})
+	require.Contains(t, c.Tags(run), nostr.Tag{"R", "!payment"})
+}
@@ -0,0 +1,337 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip11"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
+)
+
+// ErrRelayUnreachable is returned by a check when the relay cannot be reached at all.
+// No further checks are run after it, the failed attempt is stored as it is.
+var ErrRelayUnreachable = errors.New("relay unreachable")
+
+// Check is an independent probe run against a relay during a health check.
+type Check interface {
+	// Name is the NIP-66 "c" value of the check, announced in the 10166 event.
+	Name() string
+	// Timeout is how long the check is allowed to run.
+	Timeout() time.Duration
+	// Run probes the relay and records its results on the check run.
+	Run(ctx context.Context, run *CheckRun) error
+	// Tags returns the tags the check contributes to the 30166 event.
+	Tags(run *CheckRun) nostr.Tags
+}
+
+// ClearnetCheck is implemented by checks that only make sense for relays on the clearnet,
+// such as resolving their address. They are skipped for relays on Tor, I2P or Lokinet.
+type ClearnetCheck interface {
+	Check
+	ClearnetOnly() bool
+}
+
+// runsOn tells whether the check applies to relays on the given network.
+func runsOn(c Check, network string) bool {
+	if cc, ok := c.(ClearnetCheck); ok && cc.ClearnetOnly() {
+		return network == relayurl.NetworkClearnet
+	}
+
+	return true
+}
+
+// CheckRun holds the state shared by the checks performed on a single relay.
+// Checks run in registry order, so a check can use whatever the previous ones left here.
+type CheckRun struct {
+	RelayURL string
+	// Network is the network the relay is reached on, see relayurl.Network.
+	Network string
+	Result  *HealthCheck
+	// Relay is the open connection to the checked relay, set by the ws check.
+	Relay *RelayConn
+	// Probe is the event published by the write check.
+	Probe *nostr.Event
+	// Info is the NIP-11 document, set by the nip11 check when it succeeds.
+	Info *nip11.RelayInformationDocument
+	// SupportedNIPs are the NIPs parsed from the NIP-11 document.
+	SupportedNIPs []int
+	// SSL is the certificate inspection result, set by the ssl check on wss:// relays.
+	SSL *domain.SSLCheck
+	// DNS is the resolution result, set by the dns check.
+	DNS *domain.DNSCheck
+	// Auth is the NIP-42 authentication probe result, set by the auth check.
+	Auth *domain.AuthCheck
+	// NIPChecks are the results of the conformance check, one per claimed NIP probed.
+	NIPChecks []domain.NIPCheck
+
+	rc *RelayChecker
+}
+
+// Registry holds the checks performed by a RelayChecker, in the order they run.
+type Registry struct {
+	checks []Check
+}
+
+// NewRegistry returns a Registry with the given checks.
+func NewRegistry(checks ...Check) *Registry {
+	r := &Registry{}
+
+	for _, c := range checks {
+		r.Register(c)
+	}
+
+	return r
+}
+
+// DefaultRegistry returns a Registry with the checks performed by default, all sharing the same timeout.
+func DefaultRegistry(timeout time.Duration) *Registry {
+	return NewRegistry(
+		NewDNSCheck(timeout, nil),
+		NewSSLCheck(timeout, DefaultSSLExpiryWarningDays),
+		NewWebSocketCheck(timeout),
+		NewWriteCheck(timeout),
+		NewReadCheck(timeout),
+		NewNIP11Check(timeout),
+		NewAuthCheck(timeout),
+	)
+}
+
+// Register adds a check to the registry.
+// A check with the same name as an already registered one replaces it, keeping its position.
+func (r *Registry) Register(c Check) {
+	for i, registered := range r.checks {
+		if registered.Name() == c.Name() {
+			r.checks[i] = c
+			return
+		}
+	}
+
+	r.checks = append(r.checks, c)
+}
+
+// Lookup returns the registered check with the given name.
+func (r *Registry) Lookup(name string) (Check, bool) {
+	for _, c := range r.checks {
+		if c.Name() == name {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// Checks returns the registered checks, in the order they run.
+func (r *Registry) Checks() []Check {
+	return r.checks
+}
+
+// Enabled returns a new Registry with only the checks of the given names, in registry order.
+// No names means every registered check is enabled.
+func (r *Registry) Enabled(names []string) (*Registry, error) {
+	if len(names) == 0 {
+		return NewRegistry(r.checks...), nil
+	}
+
+	enabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := r.Lookup(name); !ok {
+			return nil, fmt.Errorf("unknown check %q", name)
+		}
+		enabled[name] = true
+	}
+
+	subset := NewRegistry()
+	for _, c := range r.checks {
+		if enabled[c.Name()] {
+			subset.Register(c)
+		}
+	}
+
+	return subset, nil
+}
+
+// announcementTags returns the "c" and "timeout" tags of the 10166 event for the registered checks.
+func (r *Registry) announcementTags() nostr.Tags {
+	tags := make(nostr.Tags, 0, 2*len(r.checks))
+
+	for _, c := range r.checks {
+		tags = append(tags, nostr.Tag{"c", c.Name()})
+	}
+
+	for _, c := range r.checks {
+		tags = append(
+			tags,
+			nostr.Tag{"timeout", strconv.FormatInt(int64(c.Timeout().Seconds()), 10), timeoutLabel(c)},
+		)
+	}
+
+	return tags
+}
+
+// timeoutLabel returns the name the timeout of a check is announced with.
+// The websocket check measures the "open" round trip, so that is its label.
+func timeoutLabel(c Check) string {
+	if c.Name() == CheckWebSocket {
+		return "open"
+	}
+
+	return c.Name()
+}
+
+// Names of the built-in checks.
+const (
+	CheckWebSocket = "ws"
+	CheckWrite     = "write"
+	CheckRead      = "read"
+	CheckNIP11     = "nip11"
+)
+
+// webSocketCheck opens a websocket connection to the relay, used by the checks that follow.
+type webSocketCheck struct {
+	timeout time.Duration
+}
+
+// NewWebSocketCheck returns the check opening a websocket connection to the relay.
+func NewWebSocketCheck(timeout time.Duration) Check {
+	return webSocketCheck{timeout: timeout}
+}
+
+func (c webSocketCheck) Name() string           { return CheckWebSocket }
+func (c webSocketCheck) Timeout() time.Duration { return c.timeout }
+
+func (c webSocketCheck) Run(ctx context.Context, run *CheckRun) error {
+	relay, err := run.rc.testConnection(ctx, c.timeout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRelayUnreachable, err)
+	}
+
+	run.Relay = relay
+
+	return nil
+}
+
+func (c webSocketCheck) Tags(run *CheckRun) nostr.Tags {
+	return nostr.Tags{{"rtt-open", strconv.Itoa(nullInt(run.Result.RTTOpen))}}
+}
+
+// writeCheck publishes a throwaway event over the open connection.
+type writeCheck struct {
+	timeout time.Duration
+}
+
+// NewWriteCheck returns the check measuring the write round trip.
+func NewWriteCheck(timeout time.Duration) Check {
+	return writeCheck{timeout: timeout}
+}
+
+func (c writeCheck) Name() string           { return CheckWrite }
+func (c writeCheck) Timeout() time.Duration { return c.timeout }
+
+func (c writeCheck) Run(ctx context.Context, run *CheckRun) error {
+	if run.Relay == nil {
+		return fmt.Errorf("no open connection to %s", run.RelayURL)
+	}
+
+	probe, err := run.rc.testWrite(ctx, run.Relay, c.timeout)
+	if err != nil {
+		return err
+	}
+
+	run.Probe = &probe
+
+	return nil
+}
+
+func (c writeCheck) Tags(run *CheckRun) nostr.Tags {
+	return addRTT(nostr.Tags{}, "rtt-write", run.Result.RTTWrite)
+}
+
+// readCheck requests the monitor's probe event, whether or not the write check could publish it,
+// so relays refusing writes still get their read round trip measured.
+type readCheck struct {
+	timeout time.Duration
+}
+
+// NewReadCheck returns the check measuring the read round trip.
+func NewReadCheck(timeout time.Duration) Check {
+	return readCheck{timeout: timeout}
+}
+
+func (c readCheck) Name() string           { return CheckRead }
+func (c readCheck) Timeout() time.Duration { return c.timeout }
+
+func (c readCheck) Run(ctx context.Context, run *CheckRun) error {
+	if run.Relay == nil {
+		return fmt.Errorf("no open connection to %s", run.RelayURL)
+	}
+
+	filter, err := run.rc.probeFilter()
+	if err != nil {
+		return err
+	}
+
+	return run.rc.testRead(ctx, run.Relay, c.timeout, filter)
+}
+
+func (c readCheck) Tags(run *CheckRun) nostr.Tags {
+	return addRTT(nostr.Tags{}, "rtt-read", run.Result.RTTRead)
+}
+
+// nip11Check fetches the NIP-11 information document of the relay.
+type nip11Check struct {
+	timeout time.Duration
+}
+
+// NewNIP11Check returns the check fetching the NIP-11 information document.
+func NewNIP11Check(timeout time.Duration) Check {
+	return nip11Check{timeout: timeout}
+}
+
+func (c nip11Check) Name() string           { return CheckNIP11 }
+func (c nip11Check) Timeout() time.Duration { return c.timeout }
+
+func (c nip11Check) Run(ctx context.Context, run *CheckRun) error {
+	info, err := run.rc.testNIP11(ctx, c.timeout)
+	if err != nil {
+		return err
+	}
+
+	supportedNIPs, err := convertAnyToInt(info.SupportedNIPs)
+	if err != nil {
+		// A document we cannot parse is a partial result, the connection result still counts.
+		run.Result.NIP11Success = false
+		run.Result.NIP11Error = err.Error()
+		return fmt.Errorf("failed to parse supported NIPs: %w", err)
+	}
+
+	run.Info = &info
+	run.SupportedNIPs = supportedNIPs
+
+	return nil
+}
+
+func (c nip11Check) Tags(run *CheckRun) nostr.Tags {
+	tags := nostr.Tags{}
+	if run.Info == nil {
+		return tags
+	}
+
+	// Add Supported NIPs to the Tags field.
+	tags = addSupportedNIPs(tags, run.SupportedNIPs)
+
+	// Add payment and auth requirements, if any.
+	// The observed auth requirement, when probed, prevails over the declared one.
+	tags = addLimitations(tags, toRelayLimitation(run.Info.Limitation), run.Auth == nil || !run.Auth.Success)
+
+	// Add "Topics" From NIP-11 "Informational Document" nip11.tags[].
+	tags = addTopics(tags, []string(run.Info.Tags))
+
+	// Add Supported languages by the relay of interest.
+	return addLanguages(tags, []string(run.Info.LanguageTags))
+}
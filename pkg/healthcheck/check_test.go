@@ -0,0 +1,110 @@
+package healthcheck
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCheck is a check doing nothing but contributing a fixed tag.
+type fakeCheck struct {
+	name    string
+	timeout time.Duration
+}
+
+func (c fakeCheck) Name() string                                 { return c.name }
+func (c fakeCheck) Timeout() time.Duration                       { return c.timeout }
+func (c fakeCheck) Run(ctx context.Context, run *CheckRun) error { return nil }
+func (c fakeCheck) Tags(run *CheckRun) nostr.Tags                { return nostr.Tags{{"x", c.name}} }
+
+// newCheckRun returns the state of a check run on the given relay, logging to stdout.
+func newCheckRun(t *testing.T, relayURL string) *CheckRun {
+	t.Helper()
+
+	return &CheckRun{
+		RelayURL: relayURL,
+		Result:   &HealthCheck{RelayURL: relayURL, CreatedAt: time.Now()},
+		rc:       NewRelayChecker(WithLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))),
+	}
+}
+
+func checkNames(r *Registry) []string {
+	var names []string
+	for _, c := range r.Checks() {
+		names = append(names, c.Name())
+	}
+
+	return names
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	r := DefaultRegistry(5 * time.Second)
+
+	require.Equal(t, []string{"dns", "ssl", "ws", "write", "read", "nip11", "auth"}, checkNames(r))
+
+	for _, c := range r.Checks() {
+		require.Equal(t, 5*time.Second, c.Timeout())
+	}
+}
+
+func TestRegistryRegisterReplacesByName(t *testing.T) {
+	r := NewRegistry(
+		fakeCheck{name: "ws", timeout: time.Second},
+		fakeCheck{name: "ssl", timeout: time.Second},
+	)
+	r.Register(fakeCheck{name: "ws", timeout: 3 * time.Second})
+
+	require.Equal(t, []string{"ws", "ssl"}, checkNames(r))
+
+	c, ok := r.Lookup("ws")
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, c.Timeout())
+
+	_, ok = r.Lookup("dns")
+	require.False(t, ok)
+}
+
+func TestRegistryEnabled(t *testing.T) {
+	r := DefaultRegistry(time.Second)
+
+	all, err := r.Enabled(nil)
+	require.NoError(t, err)
+	require.Equal(t, checkNames(r), checkNames(all))
+
+	// The registry order is kept, whatever the order of the names.
+	subset, err := r.Enabled([]string{"nip11", "ws"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ws", "nip11"}, checkNames(subset))
+
+	_, err = r.Enabled([]string{"ws", "geo"})
+	require.Error(t, err)
+}
+
+func TestRegistryAnnouncementTags(t *testing.T) {
+	r := NewRegistry(
+		NewWebSocketCheck(10*time.Second),
+		NewNIP11Check(5*time.Second),
+		fakeCheck{name: "ssl", timeout: 2 * time.Second},
+	)
+
+	require.EqualValues(t, nostr.Tags{
+		{"c", "ws"},
+		{"c", "nip11"},
+		{"c", "ssl"},
+		{"timeout", "10", "open"},
+		{"timeout", "5", "nip11"},
+		{"timeout", "2", "ssl"},
+	}, r.announcementTags())
+}
+
+func TestNIP11CheckTags(t *testing.T) {
+	c := NewNIP11Check(time.Second)
+
+	// Nothing is contributed without a NIP-11 document.
+	require.Empty(t, c.Tags(&CheckRun{Result: &HealthCheck{}}))
+}
@@ -0,0 +1,335 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+)
+
+const (
+	// CheckConformance is the name of the NIP conformance check.
+	CheckConformance = "conformance"
+	// conformanceIdentifier is the "d" tag of the events published to probe NIP-01 filters.
+	conformanceIdentifier = "nostrich-watch-conformance"
+	// deletionIdentifier is the "d" tag of the event deleted to probe NIP-09.
+	deletionIdentifier = "nostrich-watch-deletion"
+	// expirationIdentifier is the "d" tag of the already expired event used to probe NIP-40.
+	expirationIdentifier = "nostrich-watch-expiration"
+	// searchIdentifier is the "d" tag of the event searched for to probe NIP-50.
+	searchIdentifier = "nostrich-watch-search"
+)
+
+// ConformanceNIPs are the NIPs the conformance check knows how to probe.
+var ConformanceNIPs = []int{1, 9, 40, 45, 50}
+
+// nipProbe probes a single NIP on the open connection, returning the result and why, if it didn't pass.
+type nipProbe func(ctx context.Context, run *CheckRun, relay *RelayConn) (string, string)
+
+// conformanceCheck verifies that a relay behaves as the NIPs it claims in its NIP-11 document require,
+// instead of taking supported_nips on trust. Only claimed NIPs are probed.
+// It reuses the connection opened by the ws check and runs after the nip11 one.
+type conformanceCheck struct {
+	timeout time.Duration
+	nips    []int
+	probes  map[int]nipProbe
+}
+
+// NewConformanceCheck returns the check probing the given NIPs, each probe allowed to run for timeout.
+// It fails for NIPs not in ConformanceNIPs.
+func NewConformanceCheck(timeout time.Duration, nips []int) (Check, error) {
+	c := conformanceCheck{
+		timeout: timeout,
+		probes:  map[int]nipProbe{},
+	}
+
+	available := map[int]nipProbe{
+		1:  c.probeFilters,
+		9:  c.probeDeletion,
+		40: c.probeExpiration,
+		45: c.probeCount,
+		50: c.probeSearch,
+	}
+
+	for _, nip := range nips {
+		probe, ok := available[nip]
+		if !ok {
+			return nil, fmt.Errorf("no conformance probe for NIP-%02d", nip)
+		}
+
+		if _, ok := c.probes[nip]; !ok {
+			c.nips = append(c.nips, nip)
+			c.probes[nip] = probe
+		}
+	}
+
+	slices.Sort(c.nips)
+
+	return c, nil
+}
+
+func (c conformanceCheck) Name() string { return CheckConformance }
+
+// Timeout covers every probe, they run one after the other.
+func (c conformanceCheck) Timeout() time.Duration {
+	return c.timeout * time.Duration(max(len(c.nips), 1))
+}
+
+func (c conformanceCheck) Run(ctx context.Context, run *CheckRun) error {
+	if run.Relay == nil {
+		return fmt.Errorf("no open connection to %s", run.RelayURL)
+	}
+
+	for _, nip := range c.nips {
+		// Claims are what is verified, NIPs the relay doesn't claim are not probed.
+		if !slices.Contains(run.SupportedNIPs, nip) {
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		result, detail := c.probes[nip](probeCtx, run, run.Relay)
+		cancel()
+
+		run.NIPChecks = append(run.NIPChecks, domain.NIPCheck{
+			RelayURL:  run.RelayURL,
+			CreatedAt: &run.Result.CreatedAt,
+			NIP:       nip,
+			Result:    result,
+			Detail:    nullString(detail),
+		})
+
+		switch result {
+		case domain.NIPCheckPass:
+			run.rc.logger.Info(fmt.Sprintf("✅ %s conforms to NIP-%02d", run.RelayURL, nip))
+		case domain.NIPCheckFail:
+			run.rc.logger.Warn(
+				fmt.Sprintf("⚠️ %s claims NIP-%02d but doesn't conform to it: %s", run.RelayURL, nip, detail),
+			)
+		default:
+			run.rc.logger.Warn(
+				fmt.Sprintf("⚠️ could not probe NIP-%02d on %s: %s", nip, run.RelayURL, detail),
+			)
+		}
+	}
+
+	return nil
+}
+
+// Tags contributes nothing, NIP-66 has no tag for observed conformance.
+func (c conformanceCheck) Tags(run *CheckRun) nostr.Tags {
+	return nostr.Tags{}
+}
+
+// probeFilters publishes an event, then checks that filters matching it return it and filters not matching it don't.
+func (c conformanceCheck) probeFilters(ctx context.Context, run *CheckRun, relay *RelayConn) (string, string) {
+	ev, err := c.publish(ctx, run, relay, conformanceIdentifier, "", nil)
+	if err != nil {
+		return domain.NIPCheckUnknown, fmt.Sprintf("probe event refused: %v", err)
+	}
+
+	matching := map[string]nostr.Filter{
+		"ids":         {IDs: []string{ev.ID}},
+		"authors":     {Authors: []string{ev.PubKey}, Kinds: []int{ev.Kind}, Tags: nostr.TagMap{"d": {conformanceIdentifier}}},
+		"since/until": {IDs: []string{ev.ID}, Since: &ev.CreatedAt, Until: &ev.CreatedAt},
+	}
+
+	before, after := ev.CreatedAt-1, ev.CreatedAt+1
+	notMatching := map[string]nostr.Filter{
+		"kinds": {IDs: []string{ev.ID}, Kinds: []int{ev.Kind + 1}},
+		"#d":    {IDs: []string{ev.ID}, Tags: nostr.TagMap{"d": {conformanceIdentifier + "-other"}}},
+		"since": {IDs: []string{ev.ID}, Since: &after},
+		"until": {IDs: []string{ev.ID}, Until: &before},
+	}
+
+	for _, name := range sortedKeys(matching) {
+		served, err := c.serves(ctx, relay, matching[name], ev.ID)
+		if err != nil {
+			return domain.NIPCheckFail, fmt.Sprintf("%s filter: %v", name, err)
+		}
+		if !served {
+			return domain.NIPCheckFail, fmt.Sprintf("event not returned for a matching %s filter", name)
+		}
+	}
+
+	for _, name := range sortedKeys(notMatching) {
+		served, err := c.serves(ctx, relay, notMatching[name], ev.ID)
+		if err != nil {
+			return domain.NIPCheckFail, fmt.Sprintf("%s filter: %v", name, err)
+		}
+		if served {
+			return domain.NIPCheckFail, fmt.Sprintf("event returned for a non-matching %s filter", name)
+		}
+	}
+
+	return domain.NIPCheckPass, ""
+}
+
+// probeDeletion publishes an event and a deletion request for it, the event must no longer be served.
+func (c conformanceCheck) probeDeletion(ctx context.Context, run *CheckRun, relay *RelayConn) (string, string) {
+	target, err := c.publish(ctx, run, relay, deletionIdentifier, "", nil)
+	if err != nil {
+		return domain.NIPCheckUnknown, fmt.Sprintf("event to delete refused: %v", err)
+	}
+
+	deletion := nostr.Event{
+		PubKey:    target.PubKey,
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindDeletion,
+		Tags: nostr.Tags{
+			{"e", target.ID},
+			{"a", fmt.Sprintf("%d:%s:%s", target.Kind, target.PubKey, deletionIdentifier)},
+			{"k", strconv.Itoa(target.Kind)},
+		},
+	}
+	if err := deletion.Sign(run.rc.privateKey); err != nil {
+		return domain.NIPCheckUnknown, fmt.Sprintf("failed to sign the deletion request: %v", err)
+	}
+
+	if err := relay.Publish(ctx, deletion); err != nil {
+		return domain.NIPCheckFail, fmt.Sprintf("deletion request refused: %v", err)
+	}
+
+	served, err := c.serves(ctx, relay, nostr.Filter{IDs: []string{target.ID}}, target.ID)
+	if err != nil {
+		return domain.NIPCheckUnknown, err.Error()
+	}
+	if served {
+		return domain.NIPCheckFail, "deleted event still served"
+	}
+
+	return domain.NIPCheckPass, ""
+}
+
+// probeExpiration publishes an event that already expired, the relay must either refuse it or not serve it.
+func (c conformanceCheck) probeExpiration(ctx context.Context, run *CheckRun, relay *RelayConn) (string, string) {
+	expiration := strconv.FormatInt(int64(nostr.Now())-60, 10)
+
+	ev, err := c.publish(ctx, run, relay, expirationIdentifier, "", nostr.Tags{{"expiration", expiration}})
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "expir") {
+			return domain.NIPCheckPass, ""
+		}
+		return domain.NIPCheckUnknown, fmt.Sprintf("expired event refused for another reason: %v", err)
+	}
+
+	served, err := c.serves(ctx, relay, nostr.Filter{IDs: []string{ev.ID}}, ev.ID)
+	if err != nil {
+		return domain.NIPCheckUnknown, err.Error()
+	}
+	if served {
+		return domain.NIPCheckFail, "expired event accepted and served"
+	}
+
+	return domain.NIPCheckPass, ""
+}
+
+// probeCount counts the events of the monitor, at least the write check probe is stored.
+// It's not tested when the write check couldn't store its probe, there would be nothing to count.
+func (c conformanceCheck) probeCount(ctx context.Context, run *CheckRun, relay *RelayConn) (string, string) {
+	if run.Probe == nil {
+		return domain.NIPCheckUnknown, "not tested, the write check stored no probe event to count"
+	}
+
+	count, err := relay.Count(ctx, nostr.Filter{Authors: []string{run.Probe.PubKey}, Kinds: []int{probeKind}})
+	if err != nil {
+		return domain.NIPCheckFail, err.Error()
+	}
+	if count < 1 {
+		return domain.NIPCheckFail, fmt.Sprintf("COUNT returned %d, the probe event is stored", count)
+	}
+
+	return domain.NIPCheckPass, ""
+}
+
+// probeSearch publishes an event containing a random term, a search for that term must return it
+// and a search for another term must not, as relays ignoring the search field return every event matching the rest.
+func (c conformanceCheck) probeSearch(ctx context.Context, run *CheckRun, relay *RelayConn) (string, string) {
+	term := searchTerm()
+
+	ev, err := c.publish(ctx, run, relay, searchIdentifier, "nostrich watch search probe "+term, nil)
+	if err != nil {
+		return domain.NIPCheckUnknown, fmt.Sprintf("event to search refused: %v", err)
+	}
+
+	filter := nostr.Filter{Authors: []string{ev.PubKey}, Kinds: []int{ev.Kind}, Search: term}
+
+	served, err := c.serves(ctx, relay, filter, ev.ID)
+	if err != nil {
+		return domain.NIPCheckFail, fmt.Sprintf("search refused: %v", err)
+	}
+	if !served {
+		return domain.NIPCheckFail, "event not returned for a search matching its content"
+	}
+
+	filter.Search = searchTerm()
+
+	served, err = c.serves(ctx, relay, filter, ev.ID)
+	if err != nil {
+		return domain.NIPCheckFail, fmt.Sprintf("search refused: %v", err)
+	}
+	if served {
+		return domain.NIPCheckFail, "search term ignored, an event not containing it was returned"
+	}
+
+	return domain.NIPCheckPass, ""
+}
+
+// searchTerm returns a random word no event contains but the ones of the search probe.
+func searchTerm() string {
+	return "nostrichwatch" + nostr.GeneratePrivateKey()[:16]
+}
+
+// publish signs and publishes a throwaway addressable event with the given "d" tag and content.
+func (c conformanceCheck) publish(
+	ctx context.Context,
+	run *CheckRun,
+	relay *RelayConn,
+	identifier string,
+	content string,
+	tags nostr.Tags,
+) (nostr.Event, error) {
+	pub, err := nostr.GetPublicKey(run.rc.privateKey)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("failed to derive the monitor's public key: %w", err)
+	}
+
+	ev := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      probeKind,
+		Tags:      append(nostr.Tags{{"d", identifier}}, tags...),
+		Content:   content,
+	}
+	if err := ev.Sign(run.rc.privateKey); err != nil {
+		return ev, fmt.Errorf("failed to sign the probe event: %w", err)
+	}
+
+	return ev, relay.Publish(ctx, ev)
+}
+
+// serves tells whether the relay returns the event with the given id for the filter.
+func (c conformanceCheck) serves(ctx context.Context, relay *RelayConn, filter nostr.Filter, id string) (bool, error) {
+	events, err := relay.Query(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(events, func(ev nostr.Event) bool { return ev.ID == id }), nil
+}
+
+// sortedKeys returns the names of the filters in a stable order, so failures are reported consistently.
+func sortedKeys(filters map[string]nostr.Filter) []string {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,175 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+)
+
+// newConformanceCheckRun returns the state of a check run on the mock relay claiming the given NIPs,
+// with an open connection and the probe event of the write check already stored.
+func newConformanceCheckRun(t *testing.T, mr *mockRelay, nips ...int) *CheckRun {
+	t.Helper()
+
+	run := newAuthCheckRun(t, mr)
+	run.Relay = mr.connect(t)
+	run.SupportedNIPs = nips
+
+	if !mr.rejectWrites {
+		probe := nostr.Event{CreatedAt: nostr.Now(), Kind: probeKind, Tags: nostr.Tags{{"d", probeIdentifier}}}
+		require.NoError(t, probe.Sign(run.rc.privateKey))
+		require.NoError(t, run.Relay.Publish(context.Background(), probe))
+		run.Probe = &probe
+	}
+
+	return run
+}
+
+// nipResults returns the result of every NIP probed during the run.
+func nipResults(run *CheckRun) map[int]string {
+	results := map[int]string{}
+	for _, check := range run.NIPChecks {
+		results[check.NIP] = check.Result
+	}
+
+	return results
+}
+
+func TestNewConformanceCheck(t *testing.T) {
+	c, err := NewConformanceCheck(2*time.Second, []int{50, 1, 1})
+	require.NoError(t, err)
+	require.Equal(t, CheckConformance, c.Name())
+	require.Equal(t, 4*time.Second, c.Timeout())
+	require.Empty(t, c.Tags(&CheckRun{}))
+
+	_, err = NewConformanceCheck(2*time.Second, []int{11})
+	require.Error(t, err)
+}
+
+func TestConformanceCheckConformingRelay(t *testing.T) {
+	mr := newMockRelay(t)
+	mr.deletions = true
+	mr.expiration = true
+	mr.count = true
+	mr.search = true
+
+	run := newConformanceCheckRun(t, mr, ConformanceNIPs...)
+
+	c, err := NewConformanceCheck(2*time.Second, ConformanceNIPs)
+	require.NoError(t, err)
+	require.NoError(t, c.Run(context.Background(), run))
+
+	require.Equal(t, map[int]string{
+		1:  domain.NIPCheckPass,
+		9:  domain.NIPCheckPass,
+		40: domain.NIPCheckPass,
+		45: domain.NIPCheckPass,
+		50: domain.NIPCheckPass,
+	}, nipResults(run))
+
+	for _, check := range run.NIPChecks {
+		require.Nil(t, check.Detail)
+		require.Equal(t, run.RelayURL, check.RelayURL)
+	}
+}
+
+func TestConformanceCheckNonConformingRelay(t *testing.T) {
+	mr := newMockRelay(t)
+	run := newConformanceCheckRun(t, mr, ConformanceNIPs...)
+
+	c, err := NewConformanceCheck(time.Second, ConformanceNIPs)
+	require.NoError(t, err)
+	require.NoError(t, c.Run(context.Background(), run))
+
+	require.Equal(t, map[int]string{
+		1:  domain.NIPCheckPass,
+		9:  domain.NIPCheckFail,
+		40: domain.NIPCheckFail,
+		45: domain.NIPCheckFail,
+		50: domain.NIPCheckFail,
+	}, nipResults(run))
+}
+
+func TestConformanceCheckOnlyProbesClaimedNIPs(t *testing.T) {
+	mr := newMockRelay(t)
+	run := newConformanceCheckRun(t, mr, 1, 11)
+
+	c, err := NewConformanceCheck(time.Second, ConformanceNIPs)
+	require.NoError(t, err)
+	require.NoError(t, c.Run(context.Background(), run))
+
+	require.Equal(t, map[int]string{1: domain.NIPCheckPass}, nipResults(run))
+}
+
+func TestConformanceCheckRestrictedWrites(t *testing.T) {
+	mr := newMockRelay(t)
+	mr.rejectWrites = true
+
+	run := newConformanceCheckRun(t, mr, ConformanceNIPs...)
+
+	c, err := NewConformanceCheck(time.Second, ConformanceNIPs)
+	require.NoError(t, err)
+	require.NoError(t, c.Run(context.Background(), run))
+
+	// Nothing can be verified without writing first.
+	require.Equal(t, map[int]string{
+		1:  domain.NIPCheckUnknown,
+		9:  domain.NIPCheckUnknown,
+		40: domain.NIPCheckUnknown,
+		45: domain.NIPCheckUnknown,
+		50: domain.NIPCheckUnknown,
+	}, nipResults(run))
+
+	for _, check := range run.NIPChecks {
+		require.NotNil(t, check.Detail)
+	}
+}
+
+func TestConformanceCheckWithoutWriteProbe(t *testing.T) {
+	mr := newMockRelay(t)
+	mr.count = true
+	mr.search = true
+
+	run := newConformanceCheckRun(t, mr, 45, 50)
+	// The write check failed or didn't run, the relay still accepts the events of the other probes.
+	run.Probe = nil
+
+	c, err := NewConformanceCheck(time.Second, ConformanceNIPs)
+	require.NoError(t, err)
+	require.NoError(t, c.Run(context.Background(), run))
+
+	require.Equal(t, map[int]string{
+		45: domain.NIPCheckUnknown,
+		50: domain.NIPCheckPass,
+	}, nipResults(run))
+}
+
+func TestConformanceCheckSearchMissingMatches(t *testing.T) {
+	mr := newMockRelay(t)
+	mr.search = true
+
+	run := newConformanceCheckRun(t, mr, 50)
+	// A relay that never returns anything for a search would pass the negative case alone.
+	mr.searchNothing = true
+
+	c, err := NewConformanceCheck(time.Second, ConformanceNIPs)
+	require.NoError(t, err)
+	require.NoError(t, c.Run(context.Background(), run))
+
+	require.Equal(t, map[int]string{50: domain.NIPCheckFail}, nipResults(run))
+	require.Contains(t, *run.NIPChecks[0].Detail, "matching its content")
+}
+
+func TestConformanceCheckWithoutConnection(t *testing.T) {
+	run := newCheckRun(t, "wss://relay.example.com")
+
+	c, err := NewConformanceCheck(time.Second, ConformanceNIPs)
+	require.NoError(t, err)
+	require.Error(t, c.Run(context.Background(), run))
+	require.Empty(t, run.NIPChecks)
+}
@@ -0,0 +1,231 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/coder/websocket"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// RelayConn is a raw websocket connection to a checked relay, answering one request at a time.
+// Unlike go-nostr relays, it's dialed with the HTTP client of the checker, so relays on Tor, I2P and Lokinet
+// are reached through its proxy, and it keeps track of the last AUTH challenge the relay sent.
+type RelayConn struct {
+	conn      *websocket.Conn
+	challenge string
+	// subscriptions is how many subscriptions were opened, used to number them.
+	subscriptions int
+
+	// messages are read in the background, a read whose context is done would close the connection.
+	// The channel is closed along with the connection, readErr tells why.
+	messages  chan []byte
+	readErr   error
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// dialRelay opens a connection to the relay with the given HTTP client.
+func dialRelay(ctx context.Context, client *http.Client, relayURL string) (*RelayConn, error) {
+	conn, _, err := websocket.Dial(ctx, relayURL, &websocket.DialOptions{HTTPClient: client})
+	if err != nil {
+		return nil, err
+	}
+
+	// Same limit as go-nostr, the default one is too low for large events.
+	conn.SetReadLimit(2 << 24)
+
+	c := &RelayConn{
+		conn:     conn,
+		messages: make(chan []byte),
+		closed:   make(chan struct{}),
+	}
+	go c.read()
+
+	return c, nil
+}
+
+// Close closes the connection without waiting for the relay to acknowledge it.
+func (c *RelayConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	return c.conn.CloseNow()
+}
+
+// read passes the messages of the relay to await until the connection is closed.
+func (c *RelayConn) read() {
+	defer close(c.messages)
+
+	for {
+		_, msg, err := c.conn.Read(context.Background())
+		if err != nil {
+			c.readErr = err
+			return
+		}
+
+		select {
+		case c.messages <- msg:
+		case <-c.closed:
+			c.readErr = net.ErrClosed
+			return
+		}
+	}
+}
+
+// Publish sends the event and waits for the relay to accept it.
+// A refusal is returned as an error carrying the reason given by the relay.
+func (c *RelayConn) Publish(ctx context.Context, ev nostr.Event) error {
+	ok, err := c.submit(ctx, ev.ID, &nostr.EventEnvelope{Event: ev})
+	if err != nil {
+		return err
+	}
+
+	if !ok.OK {
+		return fmt.Errorf("msg: %s", ok.Reason)
+	}
+
+	return nil
+}
+
+// Query returns the stored events matching the filters, up to the EOSE.
+// Unlike go-nostr's QuerySync it fails when the relay closes the subscription, and it keeps the events
+// the relay returns whether or not they match the filters, as long as they are correctly signed.
+func (c *RelayConn) Query(ctx context.Context, filters ...nostr.Filter) ([]nostr.Event, error) {
+	id := c.nextSubscriptionID()
+
+	if err := c.send(ctx, &nostr.ReqEnvelope{SubscriptionID: id, Filters: filters}); err != nil {
+		return nil, err
+	}
+
+	var events []nostr.Event
+	env, err := c.await(ctx, func(env nostr.Envelope) bool {
+		switch env := env.(type) {
+		case *nostr.EventEnvelope:
+			if env.SubscriptionID != nil && *env.SubscriptionID == id {
+				if valid, _ := env.Event.CheckSignature(); valid {
+					events = append(events, env.Event)
+				}
+			}
+		case *nostr.EOSEEnvelope:
+			return string(*env) == id
+		case *nostr.ClosedEnvelope:
+			return env.SubscriptionID == id
+		}
+		return false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("no answer to REQ: %w", err)
+	}
+
+	if closed, ok := env.(*nostr.ClosedEnvelope); ok {
+		return nil, fmt.Errorf("subscription closed by relay: %s", closed.Reason)
+	}
+
+	closeSub := nostr.CloseEnvelope(id)
+	if err := c.send(ctx, &closeSub); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Count returns how many events match the filter, according to the relay (NIP-45).
+func (c *RelayConn) Count(ctx context.Context, filter nostr.Filter) (int64, error) {
+	id := c.nextSubscriptionID()
+
+	if err := c.send(ctx, &nostr.CountEnvelope{SubscriptionID: id, Filter: filter}); err != nil {
+		return 0, err
+	}
+
+	env, err := c.await(ctx, func(env nostr.Envelope) bool {
+		switch env := env.(type) {
+		case *nostr.CountEnvelope:
+			return env.SubscriptionID == id
+		case *nostr.ClosedEnvelope:
+			return env.SubscriptionID == id
+		}
+		return false
+	})
+	if err != nil {
+		return 0, fmt.Errorf("no answer to COUNT: %w", err)
+	}
+
+	if closed, ok := env.(*nostr.ClosedEnvelope); ok {
+		return 0, fmt.Errorf("count refused by relay: %s", closed.Reason)
+	}
+
+	count := env.(*nostr.CountEnvelope).Count
+	if count == nil {
+		return 0, nil
+	}
+
+	return *count, nil
+}
+
+// nextSubscriptionID returns the id of a new subscription, unique on the connection.
+func (c *RelayConn) nextSubscriptionID() string {
+	c.subscriptions++
+	return fmt.Sprintf("nostrich-watch-%d", c.subscriptions)
+}
+
+// send writes an envelope to the relay.
+func (c *RelayConn) send(ctx context.Context, env nostr.Envelope) error {
+	b, err := env.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return c.conn.Write(ctx, websocket.MessageText, b)
+}
+
+// await reads messages from the relay until one matches, or the context is done.
+func (c *RelayConn) await(ctx context.Context, match func(nostr.Envelope) bool) (nostr.Envelope, error) {
+	for {
+		var msg []byte
+
+		select {
+		case m, ok := <-c.messages:
+			if !ok {
+				return nil, fmt.Errorf("connection closed: %w", c.readErr)
+			}
+			msg = m
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		env := nostr.ParseMessage(string(msg))
+		if env == nil {
+			continue
+		}
+
+		if auth, ok := env.(*nostr.AuthEnvelope); ok && auth.Challenge != nil {
+			c.challenge = *auth.Challenge
+		}
+
+		if match(env) {
+			return env, nil
+		}
+	}
+}
+
+// submit sends an EVENT or AUTH envelope and waits for the OK of the given event.
+func (c *RelayConn) submit(ctx context.Context, id string, env nostr.Envelope) (*nostr.OKEnvelope, error) {
+	if err := c.send(ctx, env); err != nil {
+		return nil, err
+	}
+
+	answer, err := c.await(ctx, func(env nostr.Envelope) bool {
+		ok, isOK := env.(*nostr.OKEnvelope)
+		return isOK && ok.EventID == id
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return answer.(*nostr.OKEnvelope), nil
+}
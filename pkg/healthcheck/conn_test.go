@@ -0,0 +1,40 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayConnSurvivesTimeouts(t *testing.T) {
+	mr := newMockRelay(t)
+	relay := mr.connect(t)
+
+	// COUNT is ignored by the mock relay, the request times out.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := relay.Count(ctx, nostr.Filter{Kinds: []int{probeKind}})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The connection is still usable afterwards.
+	ev := nostr.Event{CreatedAt: nostr.Now(), Kind: probeKind, Tags: nostr.Tags{{"d", probeIdentifier}}}
+	require.NoError(t, ev.Sign(nostr.GeneratePrivateKey()))
+	require.NoError(t, relay.Publish(context.Background(), ev))
+
+	events, err := relay.Query(context.Background(), nostr.Filter{IDs: []string{ev.ID}})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
+
+func TestRelayConnClosed(t *testing.T) {
+	mr := newMockRelay(t)
+	relay := mr.connect(t)
+	require.NoError(t, relay.Close())
+
+	_, err := relay.Query(context.Background(), nostr.Filter{Kinds: []int{probeKind}})
+	require.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/oschwald/geoip2-golang"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/geohash"
+)
+
+const (
+	// CheckDNS is the name of the DNS resolution check.
+	CheckDNS = "dns"
+	// geohashPrecision is the length of the geohash of relays, about 1.2km x 0.6km.
+	// GeoIP databases are not more accurate than the city the address is in anyway.
+	geohashPrecision = 6
+)
+
+// Location is where an IP address is located.
+type Location struct {
+	Country   string // ISO 3166-1 alpha-2 code
+	Latitude  float64
+	Longitude float64
+}
+
+// Locator maps IP addresses to their location.
+type Locator interface {
+	Locate(ip net.IP) (Location, error)
+}
+
+// GeoIPLocator locates IP addresses with an offline MaxMind GeoIP2 or GeoLite2 City database.
+type GeoIPLocator struct {
+	reader *geoip2.Reader
+}
+
+// OpenGeoIPLocator opens the GeoIP database file at the given path.
+func OpenGeoIPLocator(path string) (*GeoIPLocator, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database %s: %w", path, err)
+	}
+
+	return &GeoIPLocator{reader: reader}, nil
+}
+
+// Locate returns the location of the given IP address.
+func (l *GeoIPLocator) Locate(ip net.IP) (Location, error) {
+	city, err := l.reader.City(ip)
+	if err != nil {
+		return Location{}, err
+	}
+
+	if city.Country.IsoCode == "" && city.Location.Latitude == 0 && city.Location.Longitude == 0 {
+		return Location{}, fmt.Errorf("%s not found in the GeoIP database", ip)
+	}
+
+	return Location{
+		Country:   city.Country.IsoCode,
+		Latitude:  city.Location.Latitude,
+		Longitude: city.Location.Longitude,
+	}, nil
+}
+
+// Close closes the GeoIP database.
+func (l *GeoIPLocator) Close() error {
+	return l.reader.Close()
+}
+
+// dnsCheck resolves the relay host and, given a locator, geolocates the address it resolves to.
+type dnsCheck struct {
+	timeout  time.Duration
+	locator  Locator
+	resolver *net.Resolver
+}
+
+// NewDNSCheck returns the check resolving the A and AAAA records of the relay host.
+// A nil locator skips the geolocation of the resolved address.
+func NewDNSCheck(timeout time.Duration, locator Locator) Check {
+	return dnsCheck{timeout: timeout, locator: locator, resolver: net.DefaultResolver}
+}
+
+func (c dnsCheck) Name() string           { return CheckDNS }
+func (c dnsCheck) Timeout() time.Duration { return c.timeout }
+func (c dnsCheck) ClearnetOnly() bool     { return true }
+
+func (c dnsCheck) Run(ctx context.Context, run *CheckRun) error {
+	u, err := url.Parse(run.RelayURL)
+	if err != nil {
+		return err
+	}
+
+	result := &domain.DNSCheck{
+		RelayURL:  run.RelayURL,
+		CreatedAt: &run.Result.CreatedAt,
+	}
+	run.DNS = result
+
+	start := time.Now()
+
+	addrs, err := c.resolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		result.Error = nullString(err.Error())
+		return fmt.Errorf("failed to resolve %s: %w", u.Hostname(), err)
+	}
+
+	// Calculate RTT.
+	rttMs := int(time.Since(start).Milliseconds())
+	result.RTTDNS = &rttMs
+	result.Success = true
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			result.IPv4 = true
+		} else {
+			result.IPv6 = true
+		}
+
+		ips = append(ips, addr.IP)
+		result.IPs = append(result.IPs, addr.IP.String())
+	}
+
+	run.rc.logger.Info(
+		fmt.Sprintf("✅ Resolved %s to %v (RTT: %dms)", u.Hostname(), []string(result.IPs), rttMs),
+	)
+
+	if c.locator == nil {
+		return nil
+	}
+
+	location, err := c.locator.Locate(preferredIP(ips))
+	if err != nil {
+		return fmt.Errorf("failed to geolocate %s: %w", u.Hostname(), err)
+	}
+
+	result.Country = nullString(location.Country)
+	result.Geohash = nullString(geohash.Encode(location.Latitude, location.Longitude, geohashPrecision))
+
+	return nil
+}
+
+// Tags adds a "g" tag for every prefix of the geohash of the relay,
+// so clients can look up relays by area at whatever precision they need.
+func (c dnsCheck) Tags(run *CheckRun) nostr.Tags {
+	tags := nostr.Tags{}
+	if run.DNS == nil || run.DNS.Geohash == nil {
+		return tags
+	}
+
+	hash := *run.DNS.Geohash
+	for i := len(hash); i > 0; i-- {
+		tags = append(tags, nostr.Tag{"g", hash[:i]})
+	}
+
+	return tags
+}
+
+// preferredIP returns the first IPv4 address, or the first address when there is none.
+func preferredIP(ips []net.IP) net.IP {
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip
+		}
+	}
+
+	return ips[0]
+}
@@ -0,0 +1,92 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLocator places every address at the same spot, or fails when err is set.
+type fakeLocator struct {
+	location Location
+	err      error
+	located  net.IP
+}
+
+func (l *fakeLocator) Locate(ip net.IP) (Location, error) {
+	l.located = ip
+	return l.location, l.err
+}
+
+func TestDNSCheckResolvesAndLocates(t *testing.T) {
+	locator := &fakeLocator{location: Location{Country: "DK", Latitude: 57.64911, Longitude: 10.40744}}
+	c := NewDNSCheck(5*time.Second, locator)
+	run := newCheckRun(t, "ws://127.0.0.1:7777")
+
+	err := c.Run(context.Background(), run)
+	require.NoError(t, err)
+
+	require.NotNil(t, run.DNS)
+	require.True(t, run.DNS.Success)
+	require.NotNil(t, run.DNS.RTTDNS)
+	require.True(t, run.DNS.IPv4)
+	require.False(t, run.DNS.IPv6)
+	require.EqualValues(t, []string{"127.0.0.1"}, run.DNS.IPs)
+	require.Equal(t, "127.0.0.1", locator.located.String())
+	require.Equal(t, "DK", *run.DNS.Country)
+	require.Equal(t, "u4pruy", *run.DNS.Geohash)
+
+	require.EqualValues(t, nostr.Tags{
+		{"g", "u4pruy"},
+		{"g", "u4pru"},
+		{"g", "u4pr"},
+		{"g", "u4p"},
+		{"g", "u4"},
+		{"g", "u"},
+	}, c.Tags(run))
+}
+
+func TestDNSCheckIPv6(t *testing.T) {
+	c := NewDNSCheck(5*time.Second, nil)
+	run := newCheckRun(t, "ws://[::1]:7777")
+
+	err := c.Run(context.Background(), run)
+	require.NoError(t, err)
+
+	require.False(t, run.DNS.IPv4)
+	require.True(t, run.DNS.IPv6)
+
+	// Nothing is located without a locator.
+	require.Nil(t, run.DNS.Geohash)
+	require.Empty(t, c.Tags(run))
+}
+
+func TestDNSCheckLocatorFailure(t *testing.T) {
+	c := NewDNSCheck(5*time.Second, &fakeLocator{err: errors.New("not found")})
+	run := newCheckRun(t, "ws://127.0.0.1:7777")
+
+	err := c.Run(context.Background(), run)
+	require.Error(t, err)
+
+	// The resolution itself still counts.
+	require.True(t, run.DNS.Success)
+	require.Nil(t, run.DNS.Geohash)
+}
+
+func TestDNSCheckResolutionFailure(t *testing.T) {
+	c := NewDNSCheck(5*time.Second, nil)
+	// The .invalid TLD is guaranteed to never resolve.
+	run := newCheckRun(t, "wss://relay.invalid")
+
+	err := c.Run(context.Background(), run)
+	require.Error(t, err)
+
+	require.False(t, run.DNS.Success)
+	require.NotNil(t, run.DNS.Error)
+	require.Nil(t, run.DNS.RTTDNS)
+}
@@ -2,9 +2,15 @@ package healthcheck
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -13,9 +19,22 @@ import (
 	"github.com/nbd-wtf/go-nostr/nip11"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/repository/postgres"
 )
 
+const (
+	// probeKind is the kind of the throwaway event used to measure write and read round trips.
+	// It is addressable (NIP-78 application data), so every probe replaces the previous one
+	// instead of piling up on the checked relays.
+	probeKind = 30078
+	// probeIdentifier is the "d" tag of the probe event.
+	probeIdentifier = "nostrich-watch-probe"
+	// missedChecks is how many checks in a row can fail to publish before the last 30166 event about a relay expires.
+	missedChecks = 2
+)
+
 // HealthCheck represents a health check result.
 type HealthCheck struct {
 	RelayURL         string
@@ -30,14 +49,31 @@ type HealthCheck struct {
 	CreatedAt        time.Time
 }
 
+// Publisher publishes the events of the monitor to its relay, see publisher.Publisher.
+type Publisher interface {
+	Publish(ctx context.Context, ev nostr.Event) error
+}
+
+// Dispatcher schedules the delivery of an event stored in the outbox, see task.NewRelayPublishTask.
+type Dispatcher interface {
+	Dispatch(ctx context.Context, eventID string) error
+}
+
 // RelayChecker handles health checking for relays.
 type RelayChecker struct {
-	db           *sqlx.DB
-	timeout      time.Duration
-	privateKey   string
-	hc           *HealthCheck
-	logger       *slog.Logger
-	monitorRelay string
+	db         *sqlx.DB
+	timeout    time.Duration
+	privateKey string
+	hc         *HealthCheck
+	logger     *slog.Logger
+	publisher  Publisher
+	dispatcher Dispatcher
+	policy     *ChangePolicy
+	frequency  time.Duration
+	registry   *Registry
+	proxy      *url.URL
+	// httpClient connects to the relays and fetches their NIP-11 documents, see newHTTPClient.
+	httpClient *http.Client
 }
 
 // Option is a functional option type that allows us to configure the Client.
@@ -53,6 +89,13 @@ func NewRelayChecker(options ...Option) *RelayChecker {
 		opt(rc)
 	}
 
+	// Run the default checks unless a registry was given.
+	if rc.registry == nil {
+		rc.registry = DefaultRegistry(rc.timeout)
+	}
+
+	rc.httpClient = newHTTPClient(rc.proxy)
+
 	return rc
 }
 
@@ -84,48 +127,253 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
-// WithMonitorRelay is a functional option to set monitor's private relay URL.
-func WithMonitorRelay(monitorRelay string) Option {
+// WithPublisher is a functional option to set how the monitor's events reach its relay.
+// The publisher is shared, the relay checker doesn't close it.
+func WithPublisher(publisher Publisher) Option {
+	return func(rc *RelayChecker) {
+		rc.publisher = publisher
+	}
+}
+
+// WithDispatcher is a functional option to set how the delivery of the 30166 events stored in the outbox is scheduled.
+// Without a dispatcher, stored events wait for the next sweep of the outbox.
+func WithDispatcher(dispatcher Dispatcher) Option {
+	return func(rc *RelayChecker) {
+		rc.dispatcher = dispatcher
+	}
+}
+
+// WithChangePolicy is a functional option to skip publishing 30166 events that report nothing new.
+// Without a policy, an event is published after every successful check.
+func WithChangePolicy(policy ChangePolicy) Option {
 	return func(rc *RelayChecker) {
-		rc.monitorRelay = monitorRelay
+		rc.policy = &policy
 	}
 }
 
-// CheckRelay performs a health check on a single relay.
+// WithFrequency is a functional option to set how often the relay is checked,
+// the 30166 events expire (NIP-40) once a few checks in a row failed to replace them.
+// Without a frequency, the events don't expire.
+func WithFrequency(frequency time.Duration) Option {
+	return func(rc *RelayChecker) {
+		rc.frequency = frequency
+	}
+}
+
+// WithRegistry is a functional option to set the checks performed on every relay.
+func WithRegistry(registry *Registry) Option {
+	return func(rc *RelayChecker) {
+		rc.registry = registry
+	}
+}
+
+// WithProxy is a functional option to set the SOCKS5 proxy relays on Tor, I2P and Lokinet are checked through.
+// Without a proxy, those relays are not checked at all.
+func WithProxy(proxy *url.URL) Option {
+	return func(rc *RelayChecker) {
+		rc.proxy = proxy
+	}
+}
+
+// CheckRelay performs a health check on a single relay, running every check of the registry in order.
+// Every attempt is persisted, even when the relay cannot be reached,
+// so that offline relays stop showing their last successful check as current.
+// The 30166 event about a reachable relay is stored in the outbox along with the check, not published here.
 func (rc *RelayChecker) CheckRelay(ctx context.Context, relayURL string) error {
+	canonicalURL, err := relayurl.Normalize(relayURL)
+	if err != nil {
+		return fmt.Errorf("refusing to check %s: %w", relayURL, err)
+	}
+	relayURL = canonicalURL
+
+	network := relayurl.Network(relayURL)
+	if network != relayurl.NetworkClearnet {
+		if rc.proxy == nil {
+			// Not storing anything, an unreachable network doesn't make the relay offline.
+			rc.logger.Warn(
+				fmt.Sprintf("⚠️ skipping %s, no proxy configured to reach %s relays", relayURL, network),
+			)
+			return nil
+		}
+	}
+
 	rc.hc = &HealthCheck{
 		RelayURL:  relayURL,
 		CreatedAt: time.Now(),
 	}
 
-	// Test WebSocket connection and get relay instance.
-	_, err := rc.testConnection(ctx, rc.timeout)
+	run := &CheckRun{
+		RelayURL: relayURL,
+		Network:  network,
+		Result:   rc.hc,
+		rc:       rc,
+	}
+	defer func() {
+		if run.Relay != nil {
+			_ = run.Relay.Close()
+		}
+	}()
+
+	relayRepo := postgres.NewRelayRepository(rc.db)
+
+	for _, check := range rc.registry.Checks() {
+		if !runsOn(check, network) {
+			continue
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, check.Timeout())
+		err := check.Run(checkCtx, run)
+		cancel()
+
+		if errors.Is(err, ErrRelayUnreachable) {
+			// An unreachable relay is a valid check result, store it and stop here.
+			return rc.saveHealthCheck(ctx, relayRepo, run)
+		}
+
+		// A failed check is a partial result, the rest of the checks are still stored and published.
+		if err != nil {
+			rc.logger.Error(
+				fmt.Sprintf("❌ %s check failed for %s: %v", check.Name(), relayURL, err),
+			)
+		}
+	}
+
+	ev, err := rc.signRelayEvent(run)
 	if err != nil {
 		return err
 	}
 
-	// Test NIP-11 document (optional).
-	info, err := rc.testNIP11(ctx, rc.timeout)
+	b, err := json.Marshal(ev)
 	if err != nil {
-		rc.logger.Error(
-			fmt.Sprintf("❌ failed to get relay info for %s: %v", relayURL, err),
-		)
+		return fmt.Errorf("failed to encode the 30166 event about %s: %w", relayURL, err)
+	}
+
+	// Looked up out of the transaction, where a failed query would roll the check back.
+	redundant := rc.redundant(ctx, relayRepo, ev)
+
+	// The check and the event reporting on it are stored together, so retrying a failed delivery
+	// never checks the relay again, and a failed check never leaves an event behind.
+	if err := relayRepo.WithTx(ctx, func(tx repository.RelayRepository) error {
+		if run.Info != nil && rc.hc.NIP11Success {
+			if err := tx.Update(ctx, toRelay(relayURL, run)); err != nil {
+				rc.logger.Error(
+					fmt.Sprintf("❌ failed to update relay info for %s: %v", relayURL, err),
+				)
+				return err
+			}
+		}
+
+		if err := rc.saveHealthCheck(ctx, tx, run); err != nil {
+			return err
+		}
+
+		if redundant {
+			return nil
+		}
+
+		if err := tx.SaveOutboxEvent(ctx, domain.OutboxEvent{
+			EventID:   ev.ID,
+			Kind:      ev.Kind,
+			RelayURL:  &relayURL,
+			Event:     b,
+			CreatedAt: &rc.hc.CreatedAt,
+		}); err != nil {
+			rc.logger.Error(
+				fmt.Sprintf("❌ failed to store the 30166 event about %s in the outbox: %v", relayURL, err),
+			)
+			return err
+		}
+
+		return nil
+	}); err != nil {
 		return err
 	}
 
-	// If NIP-11 was successful, update relay metadata.
-	supportedNIPsSlice, err := convertAnyToInt(info.SupportedNIPs)
+	// The history is a nice to have, failing to keep it doesn't fail the check.
+	// It is kept out of the transaction, where a failed statement would roll the check back.
+	if run.Info != nil && rc.hc.NIP11Success {
+		if err := rc.saveDocument(ctx, relayRepo, relayURL, run.Info); err != nil {
+			rc.logger.Error(
+				fmt.Sprintf("❌ failed to save the NIP-11 document of %s: %v", relayURL, err),
+			)
+		}
+	}
+
+	if redundant {
+		suppressedEvents.Inc()
+		rc.logger.Info(fmt.Sprintf("💤 nothing changed on %s, the 30166 event is not published", relayURL))
+		return nil
+	}
+
+	// The event is safe in the outbox, if it can't be scheduled now the next sweep will.
+	if rc.dispatcher != nil {
+		if err := rc.dispatcher.Dispatch(ctx, ev.ID); err != nil {
+			rc.logger.Warn(
+				fmt.Sprintf("⚠️ failed to schedule the publish of 30166 event about %s: %v", relayURL, err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// redundant tells whether the event reports nothing new since the previous event about the relay,
+// delivered or still pending.
+// The event is published whenever the previous one can't be found, or was retracted since.
+func (rc *RelayChecker) redundant(ctx context.Context, relayRepo repository.RelayRepository, ev nostr.Event) bool {
+	if rc.policy == nil {
+		return false
+	}
+
+	latest, err := relayRepo.FindLatestOutboxEvent(ctx, rc.hc.RelayURL, ev.Kind)
 	if err != nil {
-		return err
+		if !errors.Is(err, sql.ErrNoRows) {
+			rc.logger.Warn(
+				fmt.Sprintf("⚠️ failed to get the previous 30166 event about %s: %v", rc.hc.RelayURL, err),
+			)
+		}
+		return false
+	}
+
+	var prev nostr.Event
+	if err := json.Unmarshal(latest.Event, &prev); err != nil {
+		rc.logger.Warn(
+			fmt.Sprintf("⚠️ failed to decode the previous 30166 event about %s: %v", rc.hc.RelayURL, err),
+		)
+		return false
+	}
+
+	if !rc.policy.Redundant(prev, ev) {
+		return false
+	}
+
+	// The previous event no longer stands for the relay once retracted, see RetractRelay.
+	retraction, err := relayRepo.FindLatestOutboxEvent(ctx, rc.hc.RelayURL, nostr.KindDeletion)
+	if err == nil {
+		return retraction.CreatedAt.Before(*latest.CreatedAt)
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		rc.logger.Warn(
+			fmt.Sprintf("⚠️ failed to get the retractions of the events about %s: %v", rc.hc.RelayURL, err),
+		)
+		return false
 	}
 
+	return true
+}
+
+// toRelay returns the metadata of the relay advertised by its NIP-11 document.
+func toRelay(relayURL string, run *CheckRun) domain.Relay {
+	info := run.Info
+
 	// Convert []int to pq.Int64Array
 	var supportedNIPs pq.Int64Array
-	for _, nip := range supportedNIPsSlice {
+	for _, nip := range run.SupportedNIPs {
 		supportedNIPs = append(supportedNIPs, int64(nip))
 	}
 
-	relayInfo := domain.Relay{
+	return domain.Relay{
 		URL:            relayURL,
 		Name:           &info.Name,
 		Description:    &info.Description,
@@ -140,17 +388,100 @@ func (rc *RelayChecker) CheckRelay(ctx context.Context, relayURL string) error {
 		Tags:           pq.StringArray(info.Tags),
 		LanguageTags:   pq.StringArray(info.LanguageTags),
 		RelayCountries: pq.StringArray(info.RelayCountries),
+		Limitation:     toRelayLimitation(info.Limitation),
+		Fees:           toRelayFees(info.Fees),
+		Retention:      toRelayRetention(info.Retention),
+		PaymentsURL:    nullString(info.PaymentsURL),
 	}
+}
 
-	relayRepo := postgres.NewRelayRepository(rc.db)
+// signRelayEvent returns the 30166 event reporting the results of the run, signed with the monitor's key.
+func (rc *RelayChecker) signRelayEvent(run *CheckRun) (nostr.Event, error) {
+	pub, err := nostr.GetPublicKey(rc.privateKey)
+	if err != nil {
+		rc.logger.Error(
+			fmt.Sprintf("❌ failed to derive the monitor's public key: %v", err),
+		)
+		return nostr.Event{}, err
+	}
 
-	if err := relayRepo.Update(ctx, relayInfo); err != nil {
+	ev := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      30166,
+		Tags: nostr.Tags{
+			{"d", run.RelayURL},
+			{"n", run.Network},
+		},
+		Content: "",
+	}
+
+	// Every check contributes its own tags.
+	for _, check := range rc.registry.Checks() {
+		ev.Tags = append(ev.Tags, check.Tags(run)...)
+	}
+
+	if expiration, ok := rc.expiration(ev.CreatedAt); ok {
+		ev.Tags = append(ev.Tags, nostr.Tag{"expiration", strconv.FormatInt(int64(expiration), 10)})
+	}
+
+	if err := ev.Sign(rc.privateKey); err != nil {
 		rc.logger.Error(
-			fmt.Sprintf("❌ failed to update relay info for %s: %v", relayURL, err),
+			fmt.Sprintf("❌ failed to sign the event using the monitor's private key: %v", err),
 		)
+		return nostr.Event{}, err
+	}
+
+	return ev, nil
+}
+
+// expiration returns when a 30166 event created at createdAt stops describing the relay:
+// the next event replaces it by then, unless several checks in a row failed to publish one.
+// Unchanged events are only replaced once they reach the max age of the change policy.
+func (rc *RelayChecker) expiration(createdAt nostr.Timestamp) (nostr.Timestamp, bool) {
+	if rc.frequency <= 0 {
+		return 0, false
+	}
+
+	validity := (missedChecks + 1) * rc.frequency
+	if rc.policy != nil && rc.policy.MaxAge > 0 {
+		validity += rc.policy.MaxAge
+	}
+
+	return createdAt + nostr.Timestamp(validity.Seconds()), true
+}
+
+// saveDocument keeps the NIP-11 document of the relay as a new version of its history, if it changed.
+func (rc *RelayChecker) saveDocument(
+	ctx context.Context,
+	relayRepo repository.RelayRepository,
+	relayURL string,
+	info *nip11.RelayInformationDocument,
+) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to encode the document: %w", err)
+	}
+
+	created, err := relayRepo.SaveDocument(ctx, domain.NewRelayDocument(relayURL, b, rc.hc.CreatedAt))
+	if err != nil {
 		return err
 	}
 
+	if created {
+		rc.logger.Info(fmt.Sprintf("📝 NIP-11 document of %s changed, stored a new version", relayURL))
+	}
+
+	return nil
+}
+
+// saveHealthCheck persists the current health check result, successful or not,
+// along with the certificate inspection, authentication, NIP conformance and DNS resolution results, if any.
+func (rc *RelayChecker) saveHealthCheck(
+	ctx context.Context,
+	relayRepo repository.RelayRepository,
+	run *CheckRun,
+) error {
 	hc := domain.HealthCheck{
 		RelayURL:         rc.hc.RelayURL,
 		CreatedAt:        &rc.hc.CreatedAt,
@@ -164,74 +495,65 @@ func (rc *RelayChecker) CheckRelay(ctx context.Context, relayURL string) error {
 		RTTNIP11:         rc.hc.RTTNIP11,
 	}
 
-	if err := relayRepo.SaveHealthCheck(ctx, hc); err != nil {
-		rc.logger.Error(
-			fmt.Sprintf("❌ failed to update health checks for %s: %v", relayURL, err),
-		)
-		return err
+	// NIP-11 is not attempted when the connection fails, store it as unknown.
+	if !rc.hc.NIP11Success && rc.hc.NIP11Error == "" {
+		hc.Nip11Success = nil
 	}
 
-	pub, err := nostr.GetPublicKey(rc.privateKey)
-	if err != nil {
+	if err := relayRepo.SaveHealthCheck(ctx, hc); err != nil {
 		rc.logger.Error(
-			fmt.Sprintf("❌ failed to derive the monitor's public key: %v", err),
+			fmt.Sprintf("❌ failed to update health checks for %s: %v", rc.hc.RelayURL, err),
 		)
 		return err
 	}
 
-	ev := nostr.Event{
-		PubKey:    pub,
-		CreatedAt: nostr.Now(),
-		Kind:      30166,
-		Tags: nostr.Tags{
-			{"d", relayURL},
-			{"n", "clearnet"},
-			{"rtt-open", strconv.Itoa(nullInt(rc.hc.RTTOpen))},
-		},
-		Content: "",
-	}
-
-	// Add Supported NIPs to the Tags field.
-	// Convert pq.Int64Array to []int
-	supportedNIPsInt := make([]int, len(supportedNIPs))
-	for i, nip := range supportedNIPs {
-		supportedNIPsInt[i] = int(nip)
+	if run.SSL != nil {
+		if err := relayRepo.SaveSSLCheck(ctx, *run.SSL); err != nil {
+			rc.logger.Error(
+				fmt.Sprintf("❌ failed to save ssl check for %s: %v", rc.hc.RelayURL, err),
+			)
+			return err
+		}
 	}
-	ev.Tags = addSupportedNIPs(ev.Tags, supportedNIPsInt)
 
-	// Add payment and auth requirements, if any.
-	ev.Tags = addLimitations(ev.Tags, info.Limitation)
-
-	// Add "Topics" From NIP-11 "Informational Document" nip11.tags[].
-	ev.Tags = addTopics(ev.Tags, []string(info.Tags))
-
-	// Add Supported languages by the relay of interest.
-	ev.Tags = addLanguages(ev.Tags, []string(info.LanguageTags))
-
-	if err := ev.Sign(rc.privateKey); err != nil {
-		rc.logger.Error(
-			fmt.Sprintf("❌ failed to sign the event using the monitor's private key: %v", err),
-		)
-		return err
+	if run.Auth != nil {
+		if err := relayRepo.SaveAuthCheck(ctx, *run.Auth); err != nil {
+			rc.logger.Error(
+				fmt.Sprintf("❌ failed to save auth check for %s: %v", rc.hc.RelayURL, err),
+			)
+			return err
+		}
 	}
 
-	relay, err := nostr.RelayConnect(ctx, rc.monitorRelay)
-	if err != nil {
+	if err := relayRepo.SaveNIPChecks(ctx, run.NIPChecks); err != nil {
 		rc.logger.Error(
-			fmt.Sprintf("❌ failed to connect to the monitor's relay: %v", err),
+			fmt.Sprintf("❌ failed to save nip checks for %s: %v", rc.hc.RelayURL, err),
 		)
 		return err
 	}
 
-	if err := relay.Publish(ctx, ev); err != nil {
-		rc.logger.Error(
-			fmt.Sprintf(
-				"❌ failed to publish 30166 event about %s to the monitor's relay: %v",
-				relayURL,
-				err,
-			),
-		)
-		return err
+	if run.DNS != nil {
+		if err := relayRepo.SaveDNSCheck(ctx, *run.DNS); err != nil {
+			rc.logger.Error(
+				fmt.Sprintf("❌ failed to save dns check for %s: %v", rc.hc.RelayURL, err),
+			)
+			return err
+		}
+
+		// Only a successful geolocation replaces the last known location.
+		if run.DNS.Geohash != nil {
+			if err := relayRepo.UpdateObservedLocation(
+				ctx,
+				rc.hc.RelayURL,
+				safeDeref(run.DNS.Country),
+				*run.DNS.Geohash,
+			); err != nil {
+				rc.logger.Error(
+					fmt.Sprintf("❌ failed to update observed location of %s: %v", rc.hc.RelayURL, err),
+				)
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -241,15 +563,14 @@ func (rc *RelayChecker) CheckRelay(ctx context.Context, relayURL string) error {
 func (rc *RelayChecker) testConnection(
 	ctx context.Context,
 	timeout time.Duration,
-) (*nostr.Relay, error) {
+) (*RelayConn, error) {
 	start := time.Now()
 
 	// Create context with timeout.
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Connect to relay using go-nostr library.
-	relay, err := nostr.RelayConnect(ctx, rc.hc.RelayURL)
+	relay, err := dialRelay(ctx, rc.httpClient, rc.hc.RelayURL)
 	if err != nil {
 		rc.logger.Error(fmt.Sprintf("❌ failed to connect to %s: %v", rc.hc.RelayURL, err))
 		rc.hc.WebSocketSuccess = false
@@ -267,7 +588,105 @@ func (rc *RelayChecker) testConnection(
 	return relay, nil
 }
 
-// testNIP11 tests fetching the NIP-11 information document using go-nostr library.
+// testWrite publishes a signed throwaway event with the monitor's key and measures
+// how long the relay takes to answer with an OK.
+func (rc *RelayChecker) testWrite(
+	ctx context.Context,
+	relay *RelayConn,
+	timeout time.Duration,
+) (nostr.Event, error) {
+	pub, err := nostr.GetPublicKey(rc.privateKey)
+	if err != nil {
+		rc.logger.Error(
+			fmt.Sprintf("❌ failed to derive the monitor's public key: %v", err),
+		)
+		return nostr.Event{}, err
+	}
+
+	ev := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      probeKind,
+		Tags:      nostr.Tags{{"d", probeIdentifier}},
+		Content:   "",
+	}
+
+	if err := ev.Sign(rc.privateKey); err != nil {
+		rc.logger.Error(
+			fmt.Sprintf("❌ failed to sign the write probe using the monitor's private key: %v", err),
+		)
+		return nostr.Event{}, err
+	}
+
+	// Create context with timeout.
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	if err := relay.Publish(ctx, ev); err != nil {
+		rc.logger.Error(fmt.Sprintf("❌ failed to write to %s: %v", rc.hc.RelayURL, err))
+		return ev, err
+	}
+
+	// Calculate RTT.
+	rttMs := int(time.Since(start).Milliseconds())
+	rc.hc.RTTWrite = &rttMs
+
+	rc.logger.Info(fmt.Sprintf("✅ Wrote to relay %s (RTT: %dms)", rc.hc.RelayURL, rttMs))
+
+	return ev, nil
+}
+
+// probeFilter returns the filter matching the monitor's probe event, stored by the relay or not.
+func (rc *RelayChecker) probeFilter() (nostr.Filter, error) {
+	pub, err := nostr.GetPublicKey(rc.privateKey)
+	if err != nil {
+		rc.logger.Error(
+			fmt.Sprintf("❌ failed to derive the monitor's public key: %v", err),
+		)
+		return nostr.Filter{}, err
+	}
+
+	return nostr.Filter{
+		Kinds:   []int{probeKind},
+		Authors: []string{pub},
+		Tags:    nostr.TagMap{"d": []string{probeIdentifier}},
+		Limit:   1,
+	}, nil
+}
+
+// testRead sends a REQ for the given filter and measures how long the relay
+// takes to answer with an EOSE.
+func (rc *RelayChecker) testRead(
+	ctx context.Context,
+	relay *RelayConn,
+	timeout time.Duration,
+	filter nostr.Filter,
+) error {
+	// Create context with timeout.
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	if _, err := relay.Query(ctx, filter); err != nil {
+		rc.logger.Error(fmt.Sprintf("❌ failed to read from %s: %v", rc.hc.RelayURL, err))
+		return err
+	}
+
+	// Calculate RTT.
+	rttMs := int(time.Since(start).Milliseconds())
+	rc.hc.RTTRead = &rttMs
+
+	rc.logger.Info(
+		fmt.Sprintf("✅ Read from relay %s (RTT: %dms)", rc.hc.RelayURL, rttMs),
+	)
+
+	return nil
+}
+
+// testNIP11 tests fetching the NIP-11 information document.
 func (rc *RelayChecker) testNIP11(
 	ctx context.Context,
 	timeout time.Duration,
@@ -278,9 +697,8 @@ func (rc *RelayChecker) testNIP11(
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Use go-nostr's NIP-11 package to fetch relay information.
 	rc.logger.Info(fmt.Sprintf("getting NIP 11 response from relay %s", rc.hc.RelayURL))
-	info, err := nip11.Fetch(ctx, rc.hc.RelayURL)
+	info, err := fetchNIP11(ctx, rc.httpClient, rc.hc.RelayURL)
 	if err != nil {
 		rc.logger.Error(
 			fmt.Sprintf("❌ failed to get NIP 11 response from relay %s, %s", info.URL, err),
@@ -300,7 +718,35 @@ func (rc *RelayChecker) testNIP11(
 	return info, nil
 }
 
-func (rc *RelayChecker) Publish10166Event(ctx context.Context, frequency, timeout string) error {
+// fetchNIP11 fetches the NIP-11 document of the relay with the given client, the way nip11.Fetch does
+// with the default one. The document always has its URL set, even when the fetch fails.
+func fetchNIP11(ctx context.Context, client *http.Client, relayURL string) (nip11.RelayInformationDocument, error) {
+	u := nostr.NormalizeURL(relayURL)
+	info := nip11.RelayInformationDocument{URL: u}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http"+strings.TrimPrefix(u, "ws"), nil)
+	if err != nil {
+		return info, fmt.Errorf("invalid url %s: %w", relayURL, err)
+	}
+	req.Header.Set("Accept", "application/nostr+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return info, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return info, fmt.Errorf("invalid json: %w", err)
+	}
+
+	return info, nil
+}
+
+// Publish10166Event publishes the monitor announcement, listing the checks of the registry and their timeouts.
+func (rc *RelayChecker) Publish10166Event(ctx context.Context, frequency string) error {
 	pub, err := nostr.GetPublicKey(rc.privateKey)
 	if err != nil {
 		rc.logger.Error(
@@ -317,17 +763,12 @@ func (rc *RelayChecker) Publish10166Event(ctx context.Context, frequency, timeou
 		Tags: nostr.Tags{
 			// Frequency of monitoring (example: every 3600 seconds/1 hour).
 			{"frequency", frequency},
-
-			// Checks performed.
-			{"c", "ws"},
-			{"c", "nip11"},
-
-			// Timeout configurations.
-			{"timeout", timeout, "open"},
-			{"timeout", timeout, "nip11"},
 		},
 	}
 
+	// Checks performed and their timeout configurations.
+	ev.Tags = append(ev.Tags, rc.registry.announcementTags()...)
+
 	// Since it's a replaceable event, it will automatically
 	// replace any previous 10166 from this pubkey
 	if err = ev.Sign(rc.privateKey); err != nil {
@@ -337,23 +778,86 @@ func (rc *RelayChecker) Publish10166Event(ctx context.Context, frequency, timeou
 		return err
 	}
 
-	relay, err := nostr.RelayConnect(ctx, rc.monitorRelay)
+	if err := rc.publish(ctx, ev); err != nil {
+		rc.logger.Error(
+			fmt.Sprintf(
+				"❌ failed to publish 10166 event monitor annnoucement to the monitor's relay: %v",
+				err,
+			),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// RetractRelay asks the relays the monitor publishes to to delete its 30166 events about a relay
+// it no longer monitors (NIP-09), so they stop being served as current.
+// The deletion request is stored in the outbox and delivered like the 30166 events.
+func (rc *RelayChecker) RetractRelay(ctx context.Context, relayURL string) error {
+	pub, err := nostr.GetPublicKey(rc.privateKey)
 	if err != nil {
 		rc.logger.Error(
-			fmt.Sprintf("❌ failed to connect to the monitor's relay: %v", err),
+			fmt.Sprintf("❌ failed to derive the monitor's public key: %v", err),
 		)
 		return err
 	}
 
-	if err := relay.Publish(ctx, ev); err != nil {
+	// Deleting the address deletes every version of the event up to the deletion request,
+	// a later 30166 event about the relay is served again if it's monitored once more.
+	ev := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindDeletion,
+		Tags: nostr.Tags{
+			{"a", fmt.Sprintf("30166:%s:%s", pub, relayURL)},
+			{"k", "30166"},
+		},
+		Content: "relay no longer monitored",
+	}
+
+	if err := ev.Sign(rc.privateKey); err != nil {
 		rc.logger.Error(
-			fmt.Sprintf(
-				"❌ failed to publish 10166 event monitor annnoucement to the monitor's relay: %v",
-				err,
-			),
+			fmt.Sprintf("❌ failed to sign the event using the monitor's private key: %v", err),
 		)
 		return err
 	}
 
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to encode the deletion request about %s: %w", relayURL, err)
+	}
+
+	relayRepo := postgres.NewRelayRepository(rc.db)
+
+	if err := relayRepo.SaveOutboxEvent(ctx, domain.OutboxEvent{
+		EventID:  ev.ID,
+		Kind:     ev.Kind,
+		RelayURL: &relayURL,
+		Event:    b,
+	}); err != nil {
+		rc.logger.Error(
+			fmt.Sprintf("❌ failed to store the deletion request about %s in the outbox: %v", relayURL, err),
+		)
+		return err
+	}
+
+	if rc.dispatcher != nil {
+		if err := rc.dispatcher.Dispatch(ctx, ev.ID); err != nil {
+			rc.logger.Warn(
+				fmt.Sprintf("⚠️ failed to schedule the publish of the deletion request about %s: %v", relayURL, err),
+			)
+		}
+	}
+
 	return nil
 }
+
+// publish sends an event to the monitor's relay through the shared publisher.
+func (rc *RelayChecker) publish(ctx context.Context, ev nostr.Event) error {
+	if rc.publisher == nil {
+		return errors.New("no publisher configured for the monitor's relay")
+	}
+
+	return rc.publisher.Publish(ctx, ev)
+}
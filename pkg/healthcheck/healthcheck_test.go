@@ -2,6 +2,9 @@ package healthcheck
 
 import (
 	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -13,7 +16,11 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip11"
+	"github.com/nbd-wtf/go-nostr/nip40"
 	"github.com/stretchr/testify/require"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
 )
 
 func TestNewRelayChecker(t *testing.T) {
@@ -321,3 +328,410 @@ func TestAddLanguageTags(t *testing.T) {
 		})
 	}
 }
+
+func TestTestWriteAndReadWithMockRelay(t *testing.T) {
+	mr := newMockRelay(t)
+	relay := mr.connect(t)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	checker := NewRelayChecker(
+		WithTimeout(5*time.Second),
+		WithPrivateKey(nostr.GeneratePrivateKey()),
+		WithLogger(logger),
+	)
+	checker.hc = &HealthCheck{
+		RelayURL:  mr.URL(),
+		CreatedAt: time.Now(),
+	}
+
+	ctx := context.Background()
+
+	probe, err := checker.testWrite(ctx, relay, 5*time.Second)
+	require.NoError(t, err)
+	require.NotNil(t, checker.hc.RTTWrite)
+	require.Equal(t, probeKind, probe.Kind)
+
+	stored := mr.stored()
+	require.Len(t, stored, 1)
+	require.Equal(t, probe.ID, stored[0].ID)
+
+	filter, err := checker.probeFilter()
+	require.NoError(t, err)
+	require.True(t, filter.Matches(&probe))
+
+	err = checker.testRead(ctx, relay, 5*time.Second, filter)
+	require.NoError(t, err)
+	require.NotNil(t, checker.hc.RTTRead)
+}
+
+func TestTestWriteRejectedByRelay(t *testing.T) {
+	mr := newMockRelay(t)
+	mr.rejectWrites = true
+	relay := mr.connect(t)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	checker := NewRelayChecker(
+		WithTimeout(5*time.Second),
+		WithPrivateKey(nostr.GeneratePrivateKey()),
+		WithLogger(logger),
+	)
+	checker.hc = &HealthCheck{
+		RelayURL:  mr.URL(),
+		CreatedAt: time.Now(),
+	}
+
+	_, err := checker.testWrite(context.Background(), relay, 5*time.Second)
+	require.Error(t, err)
+	require.Nil(t, checker.hc.RTTWrite)
+	require.Empty(t, mr.stored())
+
+	// The read round trip doesn't depend on the write.
+	run := &CheckRun{RelayURL: mr.URL(), Relay: relay, rc: checker}
+	require.NoError(t, NewReadCheck(5*time.Second).Run(context.Background(), run))
+	require.NotNil(t, checker.hc.RTTRead)
+}
+
+func TestAddRTT(t *testing.T) {
+	rtt := 42
+
+	tags := nostr.Tags{{"d", "wss://some.relay/"}}
+	tags = addRTT(tags, "rtt-read", &rtt)
+	tags = addRTT(tags, "rtt-write", nil)
+
+	require.EqualValues(t, nostr.Tags{
+		{"d", "wss://some.relay/"},
+		{"rtt-read", "42"},
+	}, tags)
+}
+
+func TestAddLimitations(t *testing.T) {
+	limitation := toRelayLimitation(&nip11.RelayLimitationDocument{
+		PaymentRequired:  true,
+		MinPowDifficulty: 20,
+	})
+
+	require.EqualValues(t, nostr.Tags{
+		{"R", "payment"},
+		{"R", "!auth"},
+		{"R", "!writes"},
+		{"R", "pow"},
+	}, addLimitations(nostr.Tags{}, limitation, true))
+
+	// The auth requirement is left to the auth check when it ran.
+	require.EqualValues(t, nostr.Tags{
+		{"R", "payment"},
+		{"R", "!writes"},
+		{"R", "pow"},
+	}, addLimitations(nostr.Tags{}, limitation, false))
+
+	require.Empty(t, addLimitations(nostr.Tags{}, nil, true))
+}
+
+func TestToRelayFeesAndRetention(t *testing.T) {
+	var doc nip11.RelayInformationDocument
+	err := json.Unmarshal([]byte(`{
+		"fees": {
+			"admission": [{"amount": 21000, "unit": "msats"}],
+			"publication": [{"kinds": [4], "amount": 100, "unit": "msats"}]
+		},
+		"retention": [{"kinds": [[40, 49]], "time": 3600}, {"count": 1000}]
+	}`), &doc)
+	require.NoError(t, err)
+
+	fees := toRelayFees(doc.Fees)
+	require.Equal(t, []domain.AdmissionFee{{Amount: 21000, Unit: "msats"}}, fees.Admission)
+	require.Equal(t, []domain.PublicationFee{{Kinds: []int{4}, Amount: 100, Unit: "msats"}}, fees.Publication)
+	require.Empty(t, fees.Subscription)
+
+	require.Equal(t, domain.RelayRetention{
+		{Kinds: [][]int{{40, 49}}, Time: 3600},
+		{Count: 1000},
+	}, toRelayRetention(doc.Retention))
+
+	// Undeclared or empty fees are not stored.
+	require.Nil(t, toRelayFees(nil))
+	require.Nil(t, toRelayFees(&nip11.RelayFeesDocument{}))
+}
+
+func TestCheckRelayRecordsFailedConnection(t *testing.T) {
+	// Start and immediately close a server to get an address nobody listens on.
+	server := httptest.NewServer(http.NotFoundHandler())
+	wsURL := strings.Replace(server.URL, "http://", "ws://", 1)
+	server.Close()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock db: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectExec("INSERT INTO health_checks").
+		WithArgs(
+			wsURL,
+			sqlmock.AnyArg(), // created_at
+			false,            // websocket_success
+			sqlmock.AnyArg(), // websocket_error
+			nil,              // nip11_success
+			nil,              // nip11_error
+			nil,              // rtt_open
+			nil,              // rtt_read
+			nil,              // rtt_write
+			nil,              // rtt_nip11
+		).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO dns_checks").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	checker := NewRelayChecker(
+		WithDB(sqlx.NewDb(db, "postgres")),
+		WithTimeout(2*time.Second),
+		WithPrivateKey(nostr.GeneratePrivateKey()),
+		WithLogger(logger),
+	)
+
+	err = checker.CheckRelay(context.Background(), wsURL)
+	require.NoError(t, err)
+	require.False(t, checker.hc.WebSocketSuccess)
+	require.NotEmpty(t, checker.hc.WebSocketError)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// fakeDispatcher records the events whose delivery was scheduled.
+type fakeDispatcher struct {
+	eventIDs []string
+}
+
+func (d *fakeDispatcher) Dispatch(_ context.Context, eventID string) error {
+	d.eventIDs = append(d.eventIDs, eventID)
+	return nil
+}
+
+func TestCheckRelayStoresEventInOutbox(t *testing.T) {
+	mr := newMockRelay(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var stored []byte
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO health_checks").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WithArgs(
+			sqlmock.AnyArg(), // event_id
+			30166,
+			mr.URL(),
+			outboxEventArg{event: &stored},
+			sqlmock.AnyArg(), // created_at
+		).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	dispatcher := &fakeDispatcher{}
+
+	checker := NewRelayChecker(
+		WithDB(sqlx.NewDb(db, "postgres")),
+		WithPrivateKey(nostr.GeneratePrivateKey()),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stdout, nil))),
+		WithRegistry(NewRegistry(NewWebSocketCheck(2*time.Second))),
+		WithDispatcher(dispatcher),
+	)
+
+	require.NoError(t, checker.CheckRelay(context.Background(), mr.URL()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// The signed event is stored as is, and its delivery scheduled once committed.
+	var ev nostr.Event
+	require.NoError(t, json.Unmarshal(stored, &ev))
+	require.Equal(t, []string{ev.ID}, dispatcher.eventIDs)
+	require.Equal(t, mr.URL(), ev.Tags.GetD())
+
+	ok, err := ev.CheckSignature()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Nothing is published while checking.
+	require.Empty(t, mr.stored())
+}
+
+func TestCheckRelayRollsBackWhenTheEventCannotBeStored(t *testing.T) {
+	mr := newMockRelay(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO health_checks").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	dispatcher := &fakeDispatcher{}
+
+	checker := NewRelayChecker(
+		WithDB(sqlx.NewDb(db, "postgres")),
+		WithPrivateKey(nostr.GeneratePrivateKey()),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stdout, nil))),
+		WithRegistry(NewRegistry(NewWebSocketCheck(2*time.Second))),
+		WithDispatcher(dispatcher),
+	)
+
+	require.Error(t, checker.CheckRelay(context.Background(), mr.URL()))
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Empty(t, dispatcher.eventIDs)
+}
+
+// outboxEventArg matches the encoded event stored in the outbox, keeping it for later inspection.
+type outboxEventArg struct {
+	event *[]byte
+}
+
+func (a outboxEventArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+
+	*a.event = []byte(s)
+
+	return json.Valid(*a.event)
+}
+
+func TestCheckRelaySkipsUnchangedEvents(t *testing.T) {
+	mr := newMockRelay(t)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	privateKey := nostr.GeneratePrivateKey()
+
+	// The previous event reports the same state, with a round trip within the same bucket.
+	prev := nostr.Event{
+		CreatedAt: nostr.Timestamp(time.Now().Add(-5 * time.Minute).Unix()),
+		Kind:      30166,
+		Tags:      nostr.Tags{{"d", mr.URL()}, {"n", "clearnet"}, {"rtt-open", "1"}},
+	}
+	require.NoError(t, prev.Sign(privateKey))
+
+	b, err := json.Marshal(prev)
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT (.+) FROM outbox_events").
+		WithArgs(mr.URL(), 30166).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"event_id", "kind", "relay_url", "event", "status", "created_at"}).
+				AddRow(prev.ID, prev.Kind, mr.URL(), b, domain.OutboxStatusPublished, prev.CreatedAt.Time()),
+		)
+	// The events about the relay were never retracted.
+	mock.ExpectQuery("SELECT (.+) FROM outbox_events").
+		WithArgs(mr.URL(), nostr.KindDeletion).
+		WillReturnRows(sqlmock.NewRows([]string{"event_id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO health_checks").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	dispatcher := &fakeDispatcher{}
+
+	checker := NewRelayChecker(
+		WithDB(sqlx.NewDb(db, "postgres")),
+		WithPrivateKey(privateKey),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stdout, nil))),
+		WithRegistry(NewRegistry(NewWebSocketCheck(2*time.Second))),
+		WithDispatcher(dispatcher),
+		WithChangePolicy(DefaultChangePolicy()),
+	)
+
+	// The check is stored, the event isn't.
+	require.NoError(t, checker.CheckRelay(context.Background(), mr.URL()))
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Empty(t, dispatcher.eventIDs)
+}
+
+func TestRelayEventExpiration(t *testing.T) {
+	run := &CheckRun{RelayURL: "wss://relay.example.com", Network: "clearnet", Result: &HealthCheck{}}
+
+	checker := NewRelayChecker(
+		WithPrivateKey(nostr.GeneratePrivateKey()),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stdout, nil))),
+		WithRegistry(NewRegistry()),
+	)
+
+	// Without a frequency, the events never expire.
+	ev, err := checker.signRelayEvent(run)
+	require.NoError(t, err)
+	require.Nil(t, ev.Tags.Find("expiration"))
+
+	// Two checks can fail to replace the event before it expires.
+	WithFrequency(15 * time.Minute)(checker)
+
+	ev, err = checker.signRelayEvent(run)
+	require.NoError(t, err)
+	require.Equal(t, ev.CreatedAt+45*60, nip40.GetExpiration(ev.Tags))
+
+	// Unchanged events are replaced less often.
+	WithChangePolicy(ChangePolicy{MaxAge: time.Hour})(checker)
+
+	ev, err = checker.signRelayEvent(run)
+	require.NoError(t, err)
+	require.Equal(t, ev.CreatedAt+45*60+3600, nip40.GetExpiration(ev.Tags))
+}
+
+func TestRetractRelay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var stored []byte
+
+	mock.ExpectExec("INSERT INTO outbox_events").
+		WithArgs(
+			sqlmock.AnyArg(), // event_id
+			nostr.KindDeletion,
+			"wss://relay.example.com",
+			outboxEventArg{event: &stored},
+			nil, // created_at
+		).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	privateKey := nostr.GeneratePrivateKey()
+	pub, err := nostr.GetPublicKey(privateKey)
+	require.NoError(t, err)
+
+	dispatcher := &fakeDispatcher{}
+
+	checker := NewRelayChecker(
+		WithDB(sqlx.NewDb(db, "postgres")),
+		WithPrivateKey(privateKey),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stdout, nil))),
+		WithDispatcher(dispatcher),
+	)
+
+	require.NoError(t, checker.RetractRelay(context.Background(), "wss://relay.example.com"))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var ev nostr.Event
+	require.NoError(t, json.Unmarshal(stored, &ev))
+	require.Equal(t, []string{ev.ID}, dispatcher.eventIDs)
+	require.Equal(t, "30166:"+pub+":wss://relay.example.com", ev.Tags.GetFirst([]string{"a", ""}).Value())
+	require.Equal(t, "30166", ev.Tags.GetFirst([]string{"k", ""}).Value())
+}
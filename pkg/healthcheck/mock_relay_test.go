@@ -0,0 +1,226 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coder/websocket"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// mockRelay is a minimal in-memory Nostr relay used to exercise the probes
+// that need a real websocket round trip (EVENT -> OK, REQ -> EOSE).
+type mockRelay struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	events []nostr.Event
+
+	// rejectWrites makes the relay answer every EVENT with a negative OK.
+	rejectWrites bool
+	// challenge is sent in an AUTH message as soon as a client connects, if set.
+	challenge string
+	// requireAuth refuses reads and writes until the client authenticates with the challenge.
+	requireAuth bool
+	// deletions makes the relay drop the events referenced by the NIP-09 deletion requests of their author.
+	deletions bool
+	// expiration makes the relay refuse events whose NIP-40 expiration already passed.
+	expiration bool
+	// count makes the relay answer NIP-45 COUNT requests, they are ignored otherwise.
+	count bool
+	// search makes the relay honor NIP-50 search terms, they are ignored otherwise.
+	search bool
+	// searchNothing makes the relay return no event at all for searches, whatever their terms.
+	searchNothing bool
+}
+
+// newMockRelay starts a mock relay and registers its shutdown with the test cleanup.
+func newMockRelay(t *testing.T) *mockRelay {
+	t.Helper()
+
+	mr := &mockRelay{}
+	mr.server = httptest.NewServer(http.HandlerFunc(mr.handle))
+	t.Cleanup(mr.server.Close)
+
+	return mr
+}
+
+// URL returns the websocket URL of the mock relay.
+func (mr *mockRelay) URL() string {
+	return strings.Replace(mr.server.URL, "http://", "ws://", 1)
+}
+
+func (mr *mockRelay) handle(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	ctx := r.Context()
+
+	// authenticated tells whether the client answered the challenge, for this connection only.
+	authenticated := false
+
+	if mr.challenge != "" {
+		b, err := (&nostr.AuthEnvelope{Challenge: &mr.challenge}).MarshalJSON()
+		if err != nil {
+			return
+		}
+
+		if err := conn.Write(ctx, websocket.MessageText, b); err != nil {
+			return
+		}
+	}
+
+	for {
+		_, msg, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+
+		for _, reply := range mr.process(string(msg), &authenticated) {
+			b, err := reply.MarshalJSON()
+			if err != nil {
+				return
+			}
+
+			if err := conn.Write(ctx, websocket.MessageText, b); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// process returns the envelopes the relay sends back for a single client message.
+func (mr *mockRelay) process(msg string, authenticated *bool) []nostr.Envelope {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	gated := mr.requireAuth && !*authenticated
+
+	switch env := nostr.ParseMessage(msg).(type) {
+	case *nostr.AuthEnvelope:
+		ok := env.Event.Kind == nostr.KindClientAuthentication &&
+			env.Event.Tags.FindWithValue("challenge", mr.challenge) != nil
+		*authenticated = ok
+
+		return []nostr.Envelope{&nostr.OKEnvelope{EventID: env.Event.ID, OK: ok}}
+	case *nostr.EventEnvelope:
+		if gated {
+			return []nostr.Envelope{
+				&nostr.OKEnvelope{EventID: env.ID, OK: false, Reason: "auth-required: authenticate first"},
+			}
+		}
+
+		if mr.rejectWrites {
+			return []nostr.Envelope{
+				&nostr.OKEnvelope{EventID: env.ID, OK: false, Reason: "blocked: writes are restricted"},
+			}
+		}
+
+		if mr.expiration {
+			if exp := env.Tags.Find("expiration"); exp != nil && expired(exp[1]) {
+				return []nostr.Envelope{
+					&nostr.OKEnvelope{EventID: env.ID, OK: false, Reason: "invalid: event is expired"},
+				}
+			}
+		}
+
+		if mr.deletions && env.Kind == nostr.KindDeletion {
+			mr.events = slices.DeleteFunc(mr.events, func(ev nostr.Event) bool {
+				return ev.PubKey == env.PubKey && env.Tags.FindWithValue("e", ev.ID) != nil
+			})
+		}
+
+		mr.events = append(mr.events, env.Event)
+
+		return []nostr.Envelope{&nostr.OKEnvelope{EventID: env.ID, OK: true}}
+	case *nostr.CountEnvelope:
+		if !mr.count {
+			return nil
+		}
+
+		var count int64
+		for _, ev := range mr.events {
+			if mr.matches(env.Filter, &ev) {
+				count++
+			}
+		}
+
+		return []nostr.Envelope{&nostr.CountEnvelope{SubscriptionID: env.SubscriptionID, Count: &count}}
+	case *nostr.ReqEnvelope:
+		if gated {
+			return []nostr.Envelope{
+				&nostr.ClosedEnvelope{SubscriptionID: env.SubscriptionID, Reason: "auth-required: authenticate first"},
+			}
+		}
+
+		var replies []nostr.Envelope
+		for _, ev := range mr.events {
+			if slices.ContainsFunc(env.Filters, func(f nostr.Filter) bool { return mr.matches(f, &ev) }) {
+				replies = append(replies, &nostr.EventEnvelope{
+					SubscriptionID: &env.SubscriptionID,
+					Event:          ev,
+				})
+			}
+		}
+
+		eose := nostr.EOSEEnvelope(env.SubscriptionID)
+
+		return append(replies, &eose)
+	default:
+		return nil
+	}
+}
+
+// matches tells whether the event matches the filter, search terms only count when the relay honors them.
+func (mr *mockRelay) matches(filter nostr.Filter, ev *nostr.Event) bool {
+	if mr.searchNothing && filter.Search != "" {
+		return false
+	}
+
+	if mr.search && filter.Search != "" && !strings.Contains(ev.Content, filter.Search) {
+		return false
+	}
+
+	return filter.Matches(ev)
+}
+
+// expired tells whether a NIP-40 expiration timestamp already passed.
+func expired(expiration string) bool {
+	ts, err := strconv.ParseInt(expiration, 10, 64)
+
+	return err == nil && ts < int64(nostr.Now())
+}
+
+// stored returns a copy of the events accepted by the relay.
+func (mr *mockRelay) stored() []nostr.Event {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return append([]nostr.Event(nil), mr.events...)
+}
+
+// connect opens a connection to the mock relay.
+func (mr *mockRelay) connect(t *testing.T) *RelayConn {
+	t.Helper()
+
+	relay, err := dialRelay(context.Background(), http.DefaultClient, mr.URL())
+	if err != nil {
+		t.Fatalf("failed to connect to mock relay: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = relay.Close()
+	})
+
+	return relay
+}
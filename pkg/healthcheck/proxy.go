@@ -0,0 +1,48 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
+)
+
+// ParseProxyURL parses the URL of a SOCKS5 proxy, like socks5h://127.0.0.1:9050.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url %q: %w", raw, err)
+	}
+
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("invalid proxy url %q: expected a socks5 or socks5h scheme", raw)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url %q: missing host", raw)
+	}
+
+	return u, nil
+}
+
+// newHTTPClient returns the client a RelayChecker connects to relays and fetches their NIP-11 documents with.
+// It has its own transport, so routing overlay relays to the proxy doesn't touch the rest of the process.
+func newHTTPClient(proxy *url.URL) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyForRequest(proxy)
+
+	return &http.Client{Transport: transport}
+}
+
+// proxyForRequest returns a proxy function sending the requests to overlay hosts through proxy, if any.
+// Every other request keeps the default behavior of honoring the proxy environment variables.
+func proxyForRequest(proxy *url.URL) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if proxy != nil && relayurl.HostNetwork(req.URL.Hostname()) != relayurl.NetworkClearnet {
+			return proxy, nil
+		}
+
+		return http.ProxyFromEnvironment(req)
+	}
+}
@@ -0,0 +1,195 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+)
+
+// startSOCKS5 starts a SOCKS5 proxy refusing every connection,
+// sending the destinations it was asked for to the returned channel.
+func startSOCKS5(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+
+	destinations := make(chan string, 10)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer func() {
+					_ = conn.Close()
+				}()
+
+				// Greeting: version, number of methods, methods. No authentication is accepted.
+				header := make([]byte, 2)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte{5, 0})
+
+				// Request: version, command, reserved, address type, domain name, port.
+				request := make([]byte, 5)
+				if _, err := io.ReadFull(conn, request); err != nil || request[3] != 3 {
+					return
+				}
+				domain := make([]byte, request[4]+2)
+				if _, err := io.ReadFull(conn, domain); err != nil {
+					return
+				}
+
+				port := binary.BigEndian.Uint16(domain[len(domain)-2:])
+				destinations <- net.JoinHostPort(string(domain[:len(domain)-2]), strconv.Itoa(int(port)))
+
+				// Connection refused.
+				_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+			}()
+		}
+	}()
+
+	return ln.Addr().String(), destinations
+}
+
+func TestParseProxyURL(t *testing.T) {
+	u, err := ParseProxyURL("socks5h://127.0.0.1:9050")
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1:9050", u.Host)
+
+	for _, raw := range []string{"http://127.0.0.1:8080", "127.0.0.1:9050", "socks5://"} {
+		_, err := ParseProxyURL(raw)
+		require.Error(t, err, raw)
+	}
+}
+
+func TestProxyForRequest(t *testing.T) {
+	proxy, err := ParseProxyURL("socks5h://127.0.0.1:9050")
+	require.NoError(t, err)
+
+	proxyFor := proxyForRequest(proxy)
+
+	for _, host := range []string{"relay.onion", "relay.i2p", "relay.loki"} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+host, nil)
+		require.NoError(t, err)
+
+		got, err := proxyFor(req)
+		require.NoError(t, err)
+		require.Equal(t, proxy, got, host)
+	}
+
+	// Clearnet hosts are reached directly, as is everything without a proxy.
+	req, err := http.NewRequest(http.MethodGet, "http://relay.example.com", nil)
+	require.NoError(t, err)
+	got, err := proxyFor(req)
+	require.NoError(t, err)
+	require.NotEqual(t, proxy, got)
+
+	req, err = http.NewRequest(http.MethodGet, "http://relay.onion", nil)
+	require.NoError(t, err)
+	got, err = proxyForRequest(nil)(req)
+	require.NoError(t, err)
+	require.NotEqual(t, proxy, got)
+}
+
+func TestNewHTTPClientLeavesDefaultTransportAlone(t *testing.T) {
+	proxy, err := ParseProxyURL("socks5h://127.0.0.1:9050")
+	require.NoError(t, err)
+
+	client := newHTTPClient(proxy)
+	require.NotSame(t, http.DefaultTransport, client.Transport)
+
+	req, err := http.NewRequest(http.MethodGet, "http://relay.onion", nil)
+	require.NoError(t, err)
+
+	got, err := client.Transport.(*http.Transport).Proxy(req)
+	require.NoError(t, err)
+	require.Equal(t, proxy, got)
+
+	// The rest of the process keeps reaching overlay hosts without the proxy.
+	if proxyFunc := http.DefaultTransport.(*http.Transport).Proxy; proxyFunc != nil {
+		got, err = proxyFunc(req)
+		require.NoError(t, err)
+		require.NotEqual(t, proxy, got)
+	}
+}
+
+func TestCheckRelaySkipsOverlayRelaysWithoutProxy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	checker := NewRelayChecker(
+		WithDB(sqlx.NewDb(db, "postgres")),
+		WithTimeout(time.Second),
+		WithPrivateKey(nostr.GeneratePrivateKey()),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stdout, nil))),
+	)
+
+	// Nothing is stored, the relay isn't offline because we can't reach its network.
+	err = checker.CheckRelay(context.Background(), "ws://relay.onion")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckRelayReachesOverlayRelaysThroughProxy(t *testing.T) {
+	addr, destinations := startSOCKS5(t)
+
+	proxy, err := ParseProxyURL("socks5h://" + addr)
+	require.NoError(t, err)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	// Only the failed connection is stored, the dns check is not run on Tor relays.
+	mock.ExpectExec("INSERT INTO health_checks").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	checker := NewRelayChecker(
+		WithDB(sqlx.NewDb(db, "postgres")),
+		WithTimeout(2*time.Second),
+		WithPrivateKey(nostr.GeneratePrivateKey()),
+		WithLogger(slog.New(slog.NewTextHandler(os.Stdout, nil))),
+		WithProxy(proxy),
+	)
+
+	err = checker.CheckRelay(context.Background(), "ws://relay.onion")
+	require.NoError(t, err)
+	require.False(t, checker.hc.WebSocketSuccess)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	select {
+	case destination := <-destinations:
+		require.Equal(t, "relay.onion:80", destination)
+	default:
+		t.Fatal("the relay was not reached through the proxy")
+	}
+}
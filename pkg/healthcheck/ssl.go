@@ -0,0 +1,206 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+)
+
+const (
+	// CheckSSL is the name of the TLS certificate check.
+	CheckSSL = "ssl"
+	// DefaultSSLExpiryWarningDays is how close to its expiry a certificate is reported by default.
+	DefaultSSLExpiryWarningDays = 14
+)
+
+// sslExpiryAlerts counts the certificates found within the warning window of their expiry, or already expired.
+var sslExpiryAlerts = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "ssl_certificate_expiry_alerts_total",
+		Help: "Total number of relay certificates found close to their expiry or expired",
+	},
+)
+
+// sslCheck inspects the certificate presented by a wss:// relay.
+// It runs before the websocket check, so a broken certificate is recorded even when it makes the connection fail.
+type sslCheck struct {
+	timeout     time.Duration
+	warningDays int
+	// roots are the trusted root certificates, nil means the system ones.
+	roots *x509.CertPool
+}
+
+// NewSSLCheck returns the check inspecting the TLS certificate of wss:// relays.
+// Certificates expiring within warningDays are reported.
+func NewSSLCheck(timeout time.Duration, warningDays int) Check {
+	return sslCheck{timeout: timeout, warningDays: warningDays}
+}
+
+func (c sslCheck) Name() string           { return CheckSSL }
+func (c sslCheck) Timeout() time.Duration { return c.timeout }
+
+// ClearnetOnly is true as the certificate is fetched with a direct connection to the relay.
+// Addresses of overlay networks authenticate the relay on their own anyway.
+func (c sslCheck) ClearnetOnly() bool { return true }
+
+func (c sslCheck) Run(ctx context.Context, run *CheckRun) error {
+	u, err := url.Parse(run.RelayURL)
+	if err != nil {
+		return err
+	}
+
+	// Plain ws:// relays have no certificate to inspect.
+	if u.Scheme != "wss" {
+		return nil
+	}
+
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	result := &domain.SSLCheck{
+		RelayURL:  run.RelayURL,
+		CreatedAt: &run.Result.CreatedAt,
+	}
+	run.SSL = result
+
+	// Verification is done by hand below, so invalid certificates can still be inspected.
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: true, // #nosec G402
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		result.Error = nullString(err.Error())
+		return fmt.Errorf("tls handshake failed: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		err := errors.New("no certificate presented")
+		result.Error = nullString(err.Error())
+		return err
+	}
+
+	result.Success = true
+
+	leaf := certs[0]
+	now := time.Now()
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	// The chain is verified within the validity period of the leaf,
+	// so an expired certificate is not reported as misissued.
+	_, chainErr := leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		CurrentTime:   leaf.NotBefore,
+	})
+
+	chainValid := chainErr == nil
+	current := !now.Before(leaf.NotBefore) && !now.After(leaf.NotAfter)
+	valid := chainValid && current
+	daysToExpiry := int(math.Floor(leaf.NotAfter.Sub(now).Hours() / 24))
+
+	result.ChainValid = &chainValid
+	result.Valid = &valid
+	result.Issuer = nullString(certificateName(leaf.Issuer))
+	result.Subject = nullString(certificateName(leaf.Subject))
+	result.NotBefore = &leaf.NotBefore
+	result.ExpiresAt = &leaf.NotAfter
+	result.DaysToExpiry = &daysToExpiry
+
+	expiringSoon := daysToExpiry <= c.warningDays
+	result.ExpiringSoon = &expiringSoon
+
+	if expiringSoon {
+		sslExpiryAlerts.Inc()
+		run.rc.logger.Warn(
+			fmt.Sprintf(
+				"⚠️ certificate of %s expires in %d days (%s)",
+				run.RelayURL,
+				daysToExpiry,
+				leaf.NotAfter.Format(time.RFC3339),
+			),
+		)
+	}
+
+	switch {
+	case !chainValid:
+		result.Error = nullString(chainErr.Error())
+		return fmt.Errorf("invalid certificate chain: %w", chainErr)
+	case !current:
+		err := fmt.Errorf("certificate not valid between %s and %s", leaf.NotBefore, leaf.NotAfter)
+		result.Error = nullString(err.Error())
+		return err
+	}
+
+	run.rc.logger.Info(
+		fmt.Sprintf("✅ Valid certificate for %s (expires in %d days)", run.RelayURL, daysToExpiry),
+	)
+
+	return nil
+}
+
+func (c sslCheck) Tags(run *CheckRun) nostr.Tags {
+	tags := nostr.Tags{}
+	if run.SSL == nil || !run.SSL.Success {
+		return tags
+	}
+
+	if run.SSL.Valid != nil && *run.SSL.Valid {
+		tags = append(tags, nostr.Tag{"ssl", "valid"})
+	} else {
+		tags = append(tags, nostr.Tag{"ssl", "!valid"})
+	}
+
+	if run.SSL.ExpiresAt != nil {
+		tags = append(tags, nostr.Tag{"ssl-expires", strconv.FormatInt(run.SSL.ExpiresAt.Unix(), 10)})
+	}
+
+	if run.SSL.Issuer != nil {
+		tags = append(tags, nostr.Tag{"ssl-issuer", *run.SSL.Issuer})
+	}
+
+	return tags
+}
+
+// certificateName returns the common name of a certificate subject or issuer,
+// falling back to its organization and then to its full name.
+func certificateName(name pkix.Name) string {
+	if name.CommonName != "" {
+		return name.CommonName
+	}
+
+	if len(name.Organization) > 0 {
+		return name.Organization[0]
+	}
+
+	return name.String()
+}
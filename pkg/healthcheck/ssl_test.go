@@ -0,0 +1,106 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSLCheckTrustedCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	c := sslCheck{timeout: 5 * time.Second, warningDays: DefaultSSLExpiryWarningDays, roots: roots}
+	run := newCheckRun(t, strings.Replace(server.URL, "https://", "wss://", 1))
+
+	err := c.Run(context.Background(), run)
+	require.NoError(t, err)
+
+	require.NotNil(t, run.SSL)
+	require.True(t, run.SSL.Success)
+	require.True(t, *run.SSL.Valid)
+	require.True(t, *run.SSL.ChainValid)
+	require.Nil(t, run.SSL.Error)
+	require.Equal(t, "Acme Co", *run.SSL.Issuer)
+	require.Equal(t, server.Certificate().NotAfter, *run.SSL.ExpiresAt)
+	require.Positive(t, *run.SSL.DaysToExpiry)
+	require.False(t, *run.SSL.ExpiringSoon)
+
+	tags := c.Tags(run)
+	require.Contains(t, tags, nostr.Tag{"ssl", "valid"})
+	require.Contains(t, tags, nostr.Tag{"ssl-issuer", "Acme Co"})
+}
+
+func TestSSLCheckConfiguredWarningWindow(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	// The test certificate expires decades from now, only a window that wide reports it.
+	c := sslCheck{timeout: 5 * time.Second, warningDays: 100 * 365, roots: roots}
+	run := newCheckRun(t, strings.Replace(server.URL, "https://", "wss://", 1))
+
+	require.NoError(t, c.Run(context.Background(), run))
+	require.True(t, *run.SSL.ExpiringSoon)
+}
+
+func TestSSLCheckUntrustedCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	// The self-signed test certificate is not trusted by the system roots.
+	c := NewSSLCheck(5*time.Second, DefaultSSLExpiryWarningDays)
+	run := newCheckRun(t, strings.Replace(server.URL, "https://", "wss://", 1))
+
+	err := c.Run(context.Background(), run)
+	require.Error(t, err)
+
+	require.NotNil(t, run.SSL)
+	require.True(t, run.SSL.Success)
+	require.False(t, *run.SSL.Valid)
+	require.False(t, *run.SSL.ChainValid)
+	require.NotNil(t, run.SSL.Error)
+	require.NotNil(t, run.SSL.ExpiresAt)
+
+	require.Contains(t, c.Tags(run), nostr.Tag{"ssl", "!valid"})
+}
+
+func TestSSLCheckHandshakeFailure(t *testing.T) {
+	// A plain HTTP server can't complete a TLS handshake.
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	c := NewSSLCheck(5*time.Second, DefaultSSLExpiryWarningDays)
+	run := newCheckRun(t, strings.Replace(server.URL, "http://", "wss://", 1))
+
+	err := c.Run(context.Background(), run)
+	require.Error(t, err)
+
+	require.NotNil(t, run.SSL)
+	require.False(t, run.SSL.Success)
+	require.NotNil(t, run.SSL.Error)
+	require.Nil(t, run.SSL.Valid)
+	require.Empty(t, c.Tags(run))
+}
+
+func TestSSLCheckSkipsPlainWebSocket(t *testing.T) {
+	c := NewSSLCheck(5*time.Second, DefaultSSLExpiryWarningDays)
+	run := newCheckRun(t, "ws://relay.example.com")
+
+	err := c.Run(context.Background(), run)
+	require.NoError(t, err)
+	require.Nil(t, run.SSL)
+	require.Empty(t, c.Tags(run))
+}
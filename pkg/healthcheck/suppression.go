@@ -0,0 +1,139 @@
+package healthcheck
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultPublishMaxAge is how long a 30166 event about a relay that didn't change goes without being published
+// again by default.
+const DefaultPublishMaxAge = time.Hour
+
+// Names of the rules of ParseTagRules.
+const (
+	ruleExact  = "exact"
+	ruleIgnore = "ignore"
+	ruleBucket = "bucket"
+)
+
+// suppressedEvents counts the 30166 events not published because nothing changed since the previous one.
+var suppressedEvents = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "suppressed_events_total",
+		Help: "Total number of 30166 events not published because the relay didn't change since the previous one",
+	},
+)
+
+// TagRule tells which changes of a tag of the 30166 event are worth publishing the event again.
+// The zero value treats any change as meaningful.
+type TagRule struct {
+	// Ignore makes changes of the tag never worth publishing on their own.
+	Ignore bool
+	// Bucket groups the numeric values of the tag in ranges this wide,
+	// a value moving within its range is not a change.
+	Bucket int
+}
+
+// ChangePolicy decides whether a 30166 event is redundant with the previous one about the same relay.
+type ChangePolicy struct {
+	// MaxAge is how long an unchanged event goes without being published again, to keep it fresh.
+	// Zero publishes every event.
+	MaxAge time.Duration
+	// Rules by tag name, tags without a rule are compared as they are.
+	Rules map[string]TagRule
+}
+
+// DefaultChangePolicy returns the policy used unless configured otherwise:
+// round trip times only matter when they move across 100ms ranges.
+func DefaultChangePolicy() ChangePolicy {
+	return ChangePolicy{
+		MaxAge: DefaultPublishMaxAge,
+		Rules: map[string]TagRule{
+			"rtt-open":  {Bucket: 100},
+			"rtt-read":  {Bucket: 100},
+			"rtt-write": {Bucket: 100},
+		},
+	}
+}
+
+// ParseTagRules parses rules given as "<tag>=<rule>", where the rule is exact, ignore or bucket:<width>.
+func ParseTagRules(rules []string) (map[string]TagRule, error) {
+	parsed := map[string]TagRule{}
+
+	for _, r := range rules {
+		name, rule, ok := strings.Cut(r, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid tag rule %q, expected <tag>=<rule>", r)
+		}
+
+		switch kind, arg, _ := strings.Cut(rule, ":"); kind {
+		case ruleExact:
+			parsed[name] = TagRule{}
+		case ruleIgnore:
+			parsed[name] = TagRule{Ignore: true}
+		case ruleBucket:
+			width, err := strconv.Atoi(arg)
+			if err != nil || width <= 0 {
+				return nil, fmt.Errorf("invalid bucket width in tag rule %q", r)
+			}
+			parsed[name] = TagRule{Bucket: width}
+		default:
+			return nil, fmt.Errorf("unknown tag rule %q, expected exact, ignore or bucket:<width>", r)
+		}
+	}
+
+	return parsed, nil
+}
+
+// Redundant tells whether next reports nothing new since prev, and prev is recent enough to stand for it.
+func (p ChangePolicy) Redundant(prev, next nostr.Event) bool {
+	if p.MaxAge <= 0 || prev.Kind != next.Kind {
+		return false
+	}
+
+	if next.CreatedAt.Time().Sub(prev.CreatedAt.Time()) >= p.MaxAge {
+		return false
+	}
+
+	return slices.Equal(p.fingerprint(prev.Tags), p.fingerprint(next.Tags))
+}
+
+// fingerprint returns the tags as the rules see them, in a stable order.
+func (p ChangePolicy) fingerprint(tags nostr.Tags) []string {
+	keys := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if len(tag) == 0 {
+			continue
+		}
+
+		// Every event expires at another time, whether it's worth publishing depends on the rest.
+		if tag[0] == "expiration" {
+			continue
+		}
+
+		rule := p.Rules[tag[0]]
+		if rule.Ignore {
+			continue
+		}
+
+		if rule.Bucket > 0 && len(tag) > 1 {
+			if v, err := strconv.Atoi(tag[1]); err == nil {
+				tag = append(nostr.Tag{tag[0], strconv.Itoa(v / rule.Bucket * rule.Bucket)}, tag[2:]...)
+			}
+		}
+
+		keys = append(keys, strings.Join(tag, "\x00"))
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,97 @@
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+)
+
+func relayEvent(createdAt time.Time, tags ...nostr.Tag) nostr.Event {
+	return nostr.Event{
+		CreatedAt: nostr.Timestamp(createdAt.Unix()),
+		Kind:      30166,
+		Tags:      append(nostr.Tags{{"d", "wss://relay.example.com"}, {"n", "clearnet"}}, tags...),
+	}
+}
+
+func TestChangePolicyRedundant(t *testing.T) {
+	now := time.Now()
+	prev := relayEvent(now.Add(-10*time.Minute), nostr.Tag{"rtt-open", "120"}, nostr.Tag{"N", "1"}, nostr.Tag{"N", "11"})
+
+	policy := DefaultChangePolicy()
+
+	tests := []struct {
+		name      string
+		next      nostr.Event
+		redundant bool
+	}{
+		{
+			name:      "same tags in another order",
+			next:      relayEvent(now, nostr.Tag{"N", "11"}, nostr.Tag{"N", "1"}, nostr.Tag{"rtt-open", "120"}),
+			redundant: true,
+		},
+		{
+			name:      "round trip within its bucket",
+			next:      relayEvent(now, nostr.Tag{"rtt-open", "180"}, nostr.Tag{"N", "1"}, nostr.Tag{"N", "11"}),
+			redundant: true,
+		},
+		{
+			name: "only the expiration changed",
+			next: relayEvent(
+				now,
+				nostr.Tag{"rtt-open", "120"}, nostr.Tag{"N", "1"}, nostr.Tag{"N", "11"},
+				nostr.Tag{"expiration", "1700000000"},
+			),
+			redundant: true,
+		},
+		{
+			name: "round trip across buckets",
+			next: relayEvent(now, nostr.Tag{"rtt-open", "230"}, nostr.Tag{"N", "1"}, nostr.Tag{"N", "11"}),
+		},
+		{
+			name: "new supported NIP",
+			next: relayEvent(now, nostr.Tag{"rtt-open", "120"}, nostr.Tag{"N", "1"}, nostr.Tag{"N", "11"}, nostr.Tag{"N", "42"}),
+		},
+		{
+			name: "read check no longer passing",
+			next: relayEvent(now, nostr.Tag{"rtt-open", "120"}, nostr.Tag{"N", "1"}),
+		},
+		{
+			name: "previous event too old",
+			next: relayEvent(
+				now.Add(policy.MaxAge),
+				nostr.Tag{"rtt-open", "120"}, nostr.Tag{"N", "1"}, nostr.Tag{"N", "11"},
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.redundant, policy.Redundant(prev, tt.next))
+		})
+	}
+}
+
+func TestChangePolicyRules(t *testing.T) {
+	now := time.Now()
+	prev := relayEvent(now.Add(-time.Minute), nostr.Tag{"rtt-open", "120"}, nostr.Tag{"t", "paid"})
+	next := relayEvent(now, nostr.Tag{"rtt-open", "480"}, nostr.Tag{"t", "free"})
+
+	rules, err := ParseTagRules([]string{"rtt-open=bucket:500", "t=ignore"})
+	require.NoError(t, err)
+	require.True(t, ChangePolicy{MaxAge: time.Hour, Rules: rules}.Redundant(prev, next))
+
+	rules, err = ParseTagRules([]string{"rtt-open=exact", "t=ignore"})
+	require.NoError(t, err)
+	require.False(t, ChangePolicy{MaxAge: time.Hour, Rules: rules}.Redundant(prev, next))
+
+	// Without a max age, every event is published.
+	require.False(t, ChangePolicy{}.Redundant(prev, prev))
+
+	for _, invalid := range []string{"rtt-open", "=ignore", "rtt-open=bucket", "rtt-open=bucket:0", "t=sometimes"} {
+		_, err := ParseTagRules([]string{invalid})
+		require.Error(t, err, invalid)
+	}
+}
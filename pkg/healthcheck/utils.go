@@ -7,6 +7,8 @@ import (
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip11"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
 )
 
 // convertAnyToInt utility function to convert an slice of any to an slice of integers.
@@ -40,6 +42,14 @@ func nullBool(b bool) *bool {
 	return &b
 }
 
+func safeDeref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
 func nullInt(i *int) int {
 	if i == nil {
 		return 0
@@ -48,6 +58,15 @@ func nullInt(i *int) int {
 	return *i
 }
 
+// addRTT helper function to add a round trip time tag to the 30166 event, if it was measured.
+func addRTT(tags nostr.Tags, name string, rtt *int) nostr.Tags {
+	if rtt == nil {
+		return tags
+	}
+
+	return append(tags, nostr.Tag{name, strconv.Itoa(*rtt)})
+}
+
 // addSupportedNIPs helper function to add the supported NIPs from the NIP11 response to the 30166 events as tags.
 func addSupportedNIPs(tags nostr.Tags, supportedNIPs []int) nostr.Tags {
 	for _, n := range supportedNIPs {
@@ -57,23 +76,88 @@ func addSupportedNIPs(tags nostr.Tags, supportedNIPs []int) nostr.Tags {
 	return tags
 }
 
-// addLimitations helper function to add the 30116 event if the relays requires payment or authentication.
-func addLimitations(tags nostr.Tags, limitations *nip11.RelayLimitationDocument) nostr.Tags {
-	if limitations != nil {
-		if limitations.PaymentRequired {
-			tags = append(tags, nostr.Tag{"R", "payment"})
-		} else {
-			tags = append(tags, nostr.Tag{"R", "!payment"})
-		}
+// addLimitations helper function to add the requirements of the relay to the 30166 event:
+// payment, restricted writes and proof of work, plus authentication when withAuth is set.
+func addLimitations(tags nostr.Tags, limitation *domain.RelayLimitation, withAuth bool) nostr.Tags {
+	if limitation == nil {
+		return tags
+	}
+
+	tags = addRequirement(tags, "payment", limitation.PaymentRequired)
+	if withAuth {
+		tags = addRequirement(tags, "auth", limitation.AuthRequired)
+	}
+	tags = addRequirement(tags, "writes", limitation.RestrictedWrites)
+
+	return addRequirement(tags, "pow", limitation.PowRequired())
+}
+
+// addRequirement adds an "R" tag with the requirement, negated with "!" when it's not required.
+func addRequirement(tags nostr.Tags, requirement string, required bool) nostr.Tags {
+	if !required {
+		requirement = "!" + requirement
+	}
+
+	return append(tags, nostr.Tag{"R", requirement})
+}
 
-		if limitations.AuthRequired {
-			tags = append(tags, nostr.Tag{"R", "auth"})
-		} else {
-			tags = append(tags, nostr.Tag{"R", "!auth"})
+// toRelayLimitation converts the limitation of a NIP-11 document to the one stored with the relay.
+func toRelayLimitation(l *nip11.RelayLimitationDocument) *domain.RelayLimitation {
+	if l == nil {
+		return nil
+	}
+
+	return &domain.RelayLimitation{
+		MaxMessageLength:    l.MaxMessageLength,
+		MaxSubscriptions:    l.MaxSubscriptions,
+		MaxLimit:            l.MaxLimit,
+		DefaultLimit:        l.DefaultLimit,
+		MaxSubidLength:      l.MaxSubidLength,
+		MaxEventTags:        l.MaxEventTags,
+		MaxContentLength:    l.MaxContentLength,
+		MinPowDifficulty:    l.MinPowDifficulty,
+		CreatedAtLowerLimit: l.CreatedAtLowerLimit,
+		CreatedAtUpperLimit: l.CreatedAtUpperLimit,
+		AuthRequired:        l.AuthRequired,
+		PaymentRequired:     l.PaymentRequired,
+		RestrictedWrites:    l.RestrictedWrites,
+	}
+}
+
+// toRelayFees converts the fees of a NIP-11 document to the ones stored with the relay.
+func toRelayFees(f *nip11.RelayFeesDocument) *domain.RelayFees {
+	if f == nil {
+		return nil
+	}
+
+	fees := &domain.RelayFees{}
+	for _, fee := range f.Admission {
+		fees.Admission = append(fees.Admission, domain.AdmissionFee(fee))
+	}
+	for _, fee := range f.Subscription {
+		fees.Subscription = append(fees.Subscription, domain.SubscriptionFee(fee))
+	}
+	for _, fee := range f.Publication {
+		fees.Publication = append(fees.Publication, domain.PublicationFee(fee))
+	}
+
+	if fees.Empty() {
+		return nil
+	}
+
+	return fees
+}
+
+// toRelayRetention converts the retention of a NIP-11 document to the one stored with the relay.
+func toRelayRetention(retention []*nip11.RelayRetentionDocument) domain.RelayRetention {
+	var rules domain.RelayRetention
+	for _, r := range retention {
+		if r != nil {
+			rules = append(rules, domain.RetentionRule{Time: r.Time, Count: r.Count, Kinds: r.Kinds})
 		}
 	}
 
-	return tags
+	return rules
 }
 
 // addTopics helper function to add the topics of the relay from the NIP11 response to the 30166 event.
@@ -0,0 +1,130 @@
+package presentation
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// APIResponse is the envelope of every successful JSON API response
+type APIResponse struct {
+	Data any      `json:"data"`
+	Meta *APIMeta `json:"meta,omitempty"`
+}
+
+// APIMeta represents the pagination metadata of list responses
+type APIMeta struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Count      int    `json:"count"`
+	NextOffset *int   `json:"next_offset,omitempty"` // nil when there are no more results
+	NextCursor string `json:"next_cursor,omitempty"` // only set by the endpoints supporting keyset pagination
+}
+
+// APIErrorResponse is the envelope of every failed JSON API response
+type APIErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIError represents an error returned by the JSON API
+type APIError struct {
+	Code    string `json:"code"` // "invalid_parameter", "not_found", "internal_error"
+	Message string `json:"message"`
+}
+
+// RelayResponse represents a relay in the JSON API
+type RelayResponse struct {
+	URL         string `json:"url"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	PubKey      string `json:"pubkey,omitempty"`
+	Contact     string `json:"contact,omitempty"`
+
+	Software       string   `json:"software,omitempty"`
+	Version        string   `json:"version,omitempty"`
+	SupportedNIPs  []int    `json:"supported_nips"`
+	Countries      []string `json:"countries"`
+	LanguageTags   []string `json:"language_tags"`
+	Tags           []string `json:"tags"`
+	Classification string   `json:"classification"`
+
+	Icon           string `json:"icon,omitempty"`
+	Banner         string `json:"banner,omitempty"`
+	PrivacyPolicy  string `json:"privacy_policy,omitempty"`
+	TermsOfService string `json:"terms_of_service,omitempty"`
+	PostingPolicy  string `json:"posting_policy,omitempty"`
+
+	Status      string               `json:"status"`
+	IsOnline    bool                 `json:"is_online"`
+	LatestCheck *HealthCheckResponse `json:"latest_check"`
+	Stats       *RelayStatsResponse  `json:"stats,omitempty"`
+	Auth        *AuthCheckResponse   `json:"auth,omitempty"` // only on a single relay, when it was checked
+}
+
+// HealthCheckResponse represents a single health check in the JSON API
+type HealthCheckResponse struct {
+	CheckedAt        *time.Time `json:"checked_at"`
+	WebsocketSuccess bool       `json:"websocket_success"`
+	WebsocketError   *string    `json:"websocket_error,omitempty"`
+	NIP11Success     *bool      `json:"nip11_success"`
+	NIP11Error       *string    `json:"nip11_error,omitempty"`
+	RTTOpen          *int       `json:"rtt_open"`
+	RTTRead          *int       `json:"rtt_read"`
+	RTTWrite         *int       `json:"rtt_write"`
+	RTTNIP11         *int       `json:"rtt_nip11"`
+}
+
+// AuthCheckResponse represents the latest NIP-42 authentication check of a relay in the JSON API
+type AuthCheckResponse struct {
+	CheckedAt         *time.Time `json:"checked_at"`
+	Success           bool       `json:"success"`
+	Error             *string    `json:"error,omitempty"`
+	ChallengeReceived bool       `json:"challenge_received"`
+	Authenticated     bool       `json:"authenticated"`
+	ReadAuthRequired  bool       `json:"read_auth_required"`
+	WriteAuthRequired bool       `json:"write_auth_required"`
+	NIP11AuthRequired *bool      `json:"nip11_auth_required"` // null when the NIP-11 document declares no limitation
+	NIP11Mismatch     *bool      `json:"nip11_mismatch"`      // whether the observed behavior contradicts NIP-11
+}
+
+// RelayStatsResponse represents the aggregated health of a relay in the JSON API
+type RelayStatsResponse struct {
+	Window        string  `json:"window"`
+	TotalChecks   int     `json:"total_checks"`
+	FailedChecks  int     `json:"failed_checks"`
+	UptimePercent float64 `json:"uptime_percent"`
+	AvgRTTOpen    *int    `json:"avg_rtt_open"`
+	AvgRTTRead    *int    `json:"avg_rtt_read"`
+	AvgRTTWrite   *int    `json:"avg_rtt_write"`
+	AvgRTTNIP11   *int    `json:"avg_rtt_nip11"`
+}
+
+// RelayDocumentResponse represents a version of the NIP-11 document of a relay in the JSON API
+type RelayDocumentResponse struct {
+	Version     int                      `json:"version"`
+	ContentHash string                   `json:"content_hash"`
+	SeenAt      *time.Time               `json:"seen_at"` // when this version was first seen
+	Document    json.RawMessage          `json:"document"`
+	Changes     []DocumentChangeResponse `json:"changes"` // since the previous version, empty for the first one
+}
+
+// DocumentChangeResponse represents a field that changed between two versions of a NIP-11 document
+type DocumentChangeResponse struct {
+	Path string          `json:"path"`
+	Old  json.RawMessage `json:"old,omitempty"` // missing when the field was added
+	New  json.RawMessage `json:"new,omitempty"` // missing when the field was removed
+}
+
+// NIPAdoptionResponse represents the relays claiming support for a NIP in the JSON API
+type NIPAdoptionResponse struct {
+	NIP          int                     `json:"nip"`
+	Relays       int                     `json:"relays"`
+	SharePercent float64                 `json:"share_percent"`
+	Trend        []NIPTrendPointResponse `json:"trend"` // oldest first, empty without NIP-11 document history
+}
+
+// NIPTrendPointResponse represents the support of a NIP at a point in time in the JSON API
+type NIPTrendPointResponse struct {
+	Date         time.Time `json:"date"`
+	Relays       int       `json:"relays"`
+	SharePercent float64   `json:"share_percent"`
+}
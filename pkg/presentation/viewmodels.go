@@ -1,11 +1,16 @@
 package presentation
 
+import (
+	"net/url"
+)
+
 // RelayTableViewModel represents relay data optimized for dashboard table display
 type RelayTableViewModel struct {
 	URL              string
 	Name             string
 	IsOnline         bool
 	UptimePercent    float64
+	HasUptime        bool   // false when the relay has no checks in the stats window
 	Classification   string // "Public", "Paid", "WoT", "Private"
 	RTTOpen          *int   // WebSocket connection time (ms)
 	RTTNIP11         *int   // NIP-11 fetch time (ms)
@@ -15,7 +20,6 @@ type RelayTableViewModel struct {
 }
 
 // RelayDetailViewModel represents comprehensive relay data for detail pages
-// Note: This is defined but not used yet - reserved for future detail page implementation
 type RelayDetailViewModel struct {
 	// Basic Info (from domain.Relay)
 	URL         string
@@ -32,7 +36,8 @@ type RelayDetailViewModel struct {
 	CurrentRTTWrite *int
 	CurrentRTTNIP11 *int
 
-	// Aggregated Health Data
+	// Aggregated Health Data (over StatsWindow: "24h", "7d" or "30d")
+	StatsWindow   string
 	UptimePercent float64
 	AvgRTTOpen    *int
 	AvgRTTRead    *int
@@ -49,6 +54,9 @@ type RelayDetailViewModel struct {
 	LanguageTags  []string
 	Tags          []string
 
+	// Conformance of the claimed NIPs (from the latest nip_checks), keyed by NIP, NIPs never probed are missing
+	NIPChecks map[int]NIPCheckViewModel
+
 	// Policy URLs (from domain.Relay)
 	PrivacyPolicy  string
 	TermsOfService string
@@ -58,4 +66,253 @@ type RelayDetailViewModel struct {
 
 	// Classification
 	Classification string // derived from tags/countries
+
+	// TLS certificate (from the latest ssl_check), nil for relays never inspected
+	SSL *SSLViewModel
+
+	// NIP-42 authentication (from the latest auth_check), nil for relays never probed
+	Auth *AuthViewModel
+
+	// Limitations, fees and retention (from NIP-11), nil or empty when not declared
+	Limitation  *LimitationViewModel
+	Fees        []string // e.g. "Admission: 21000 msats"
+	Retention   []string // e.g. "Kinds 1, 30000-39999: 1000 events"
+	PaymentsURL string
+
+	// NIP-11 document history (from relay_documents), most recent version first
+	Documents []DocumentVersionViewModel
+
+	// Observed Location (from the GeoIP lookup of the relay address), empty when never located
+	ObservedCountry  string
+	ObservedGeohash  string
+	MapURL           string // embeddable map centered on the observed location
+	LocationMismatch bool   // observed country missing from the self-reported Countries
+}
+
+// NIPCheckViewModel represents the latest conformance probe of a NIP claimed by a relay
+type NIPCheckViewModel struct {
+	Result    string // "pass", "fail" or "unknown"
+	Detail    string // why the probe didn't pass, if it didn't
+	CheckTime string
+}
+
+// SSLViewModel represents the certificate presented by a wss:// relay on its latest check
+type SSLViewModel struct {
+	CheckTime    string
+	Success      bool // false when the TLS handshake failed
+	Valid        bool
+	ChainValid   bool
+	Issuer       string
+	Subject      string
+	ExpiresAt    string // formatted expiry date
+	DaysToExpiry int
+	ExpiringSoon bool // within the warning window of its expiry, or expired
+	Error        string
+}
+
+// AuthViewModel represents how a relay handled NIP-42 authentication on its latest check
+type AuthViewModel struct {
+	CheckTime         string
+	Success           bool // false when the probe couldn't complete
+	ChallengeReceived bool
+	Authenticated     bool
+	ReadAuthRequired  bool
+	WriteAuthRequired bool
+	NIP11Declared     bool // whether the NIP-11 document declares auth_required at all
+	NIP11AuthRequired bool
+	NIP11Mismatch     bool // observed behavior contradicts the NIP-11 document
+	Error             string
+}
+
+// LimitationViewModel represents the limits a relay declares in its NIP-11 document
+type LimitationViewModel struct {
+	PaymentRequired  bool
+	AuthRequired     bool
+	RestrictedWrites bool
+	MinPowDifficulty int
+
+	// Zero when not declared
+	MaxMessageLength int
+	MaxContentLength int
+	MaxSubscriptions int
+	MaxLimit         int
+	MaxEventTags     int
+
+	// How far in the past or future event timestamps may be, e.g. "30 days", empty when not declared
+	OldestEvent string
+	NewestEvent string
+}
+
+// DocumentVersionViewModel represents a version of the NIP-11 document of a relay and what changed in it
+type DocumentVersionViewModel struct {
+	Version int
+	SeenAt  string // when this version was first seen
+	Hash    string // abbreviated content hash
+	Changes []DocumentChangeViewModel
+}
+
+// DocumentChangeViewModel represents a field that changed since the previous version of a NIP-11 document
+type DocumentChangeViewModel struct {
+	Path string // e.g. "limitation.payment_required"
+	Old  string // empty when the field was added
+	New  string // empty when the field was removed
+}
+
+// HealthHistoryViewModel represents a page of past health checks of a relay and its sparkline
+type HealthHistoryViewModel struct {
+	RelayURL string
+	Window   string // "24h", "7d" or "30d"
+
+	// Pagination of the checks list
+	Page    int
+	HasPrev bool
+	HasNext bool
+
+	Checks    []HealthCheckRowViewModel
+	Sparkline SparklineViewModel
+}
+
+// HealthCheckRowViewModel represents a single past health check
+type HealthCheckRowViewModel struct {
+	CheckTime string
+	IsOnline  bool
+	RTTOpen   *int
+	RTTNIP11  *int
+	Error     string // websocket or NIP-11 error, if any
+}
+
+// SparklineViewModel represents a server-rendered SVG of RTT-open and online state over time
+type SparklineViewModel struct {
+	Width  int
+	Height int
+
+	// RTT-open polylines, broken on offline checks ("x1,y1 x2,y2 ...")
+	Segments []string
+	MaxRTT   int
+
+	// Online/offline strip drawn under the RTT line
+	StatusY      int
+	StatusHeight int
+	Status       []SparklineStatus
+}
+
+// SparklineStatus represents the state of a relay during a slice of the sparkline
+type SparklineStatus struct {
+	X        string
+	Width    string
+	IsOnline bool
+}
+
+// SoftwareInventoryViewModel represents the relay implementations found across the monitored relays
+type SoftwareInventoryViewModel struct {
+	Window          string // "24h", "7d" or "30d"
+	Relays          int    // relays reporting their software
+	Implementations []SoftwareImplementationViewModel
+}
+
+// SoftwareImplementationViewModel represents the relays running an implementation, whatever version they run
+type SoftwareImplementationViewModel struct {
+	Name           string // canonical name, e.g. "strfry"
+	Relays         int
+	SharePercent   float64 // of all the relays reporting their software
+	LatestVersion  string  // empty when no version can be compared
+	OutdatedRelays int
+	UptimePercent  float64
+	HasUptime      bool // false when none of the relays has checks in the window
+	AvgRTTOpen     *int
+	Versions       []SoftwareVersionViewModel
+}
+
+// SoftwareVersionViewModel represents the relays running a version of an implementation
+type SoftwareVersionViewModel struct {
+	Version       string // "Unknown" when not reported
+	Relays        int
+	SharePercent  float64 // of the relays running the implementation
+	Outdated      bool
+	UptimePercent float64
+	HasUptime     bool
+	AvgRTTOpen    *int
+}
+
+// NIPMatrixViewModel represents the adoption of every NIP across relays
+type NIPMatrixViewModel struct {
+	Relays int // relays with a NIP-11 document
+	NIPs   []NIPAdoptionViewModel
+
+	// Relays supporting the selected NIP, nil when none is selected
+	Selected *NIPRelaysViewModel
+}
+
+// NIPAdoptionViewModel represents the relays claiming support for a NIP and its trend
+type NIPAdoptionViewModel struct {
+	NIP          int
+	Relays       int
+	SharePercent float64
+
+	// Share of relays over time, empty without NIP-11 document history
+	TrendPoints string  // SVG polyline, "x1,y1 x2,y2 ..."
+	TrendChange float64 // percentage points gained since the oldest point
+}
+
+// NIPRelaysViewModel represents the active relays claiming support for a NIP, most available first
+type NIPRelaysViewModel struct {
+	NIP    int
+	Window string // "24h", "7d" or "30d"
+	Relays []RelayTableViewModel
+}
+
+// RelayFiltersViewModel represents the search, filters and sort applied to the dashboard table
+type RelayFiltersViewModel struct {
+	Search         string
+	Online         string // "", "true" or "false"
+	NIP            string
+	Classification string
+	Country        string
+	Language       string
+	Network        string
+	Requirement    string // NIP-66 requirement, e.g. "payment" or "!auth"
+	Software       string
+	Sort           string // "", "name", "rtt" or "last_check"
+	Desc           bool
+	Limit          int
+
+	// Facets offered in the filter dropdowns
+	NIPOptions      []FacetOption
+	CountryOptions  []FacetOption
+	LanguageOptions []FacetOption
+	SoftwareOptions []FacetOption
+}
+
+// FacetOption represents a value offered in a filter dropdown
+type FacetOption struct {
+	Value string
+	Label string // value and number of relays, e.g. "US (12)"
+}
+
+// Query returns the filters encoded as query string parameters, so they can be shared through URLs.
+func (f RelayFiltersViewModel) Query() url.Values {
+	q := url.Values{}
+
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+
+	set("q", f.Search)
+	set("online", f.Online)
+	set("nip", f.NIP)
+	set("classification", f.Classification)
+	set("country", f.Country)
+	set("language", f.Language)
+	set("network", f.Network)
+	set("requirement", f.Requirement)
+	set("software", f.Software)
+	set("sort", f.Sort)
+
+	if f.Desc {
+		q.Set("order", "desc")
+	}
+
+	return q
 }
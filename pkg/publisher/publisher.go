@@ -0,0 +1,336 @@
+// Package publisher keeps connections to the relays the monitor publishes its events to,
+// shared by every task of a worker instead of dialing the relays for each event.
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository/postgres"
+)
+
+// Results of a publish attempt, used as the "result" label of the metrics.
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+// Metric variables.
+var (
+	publishedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "published_events_total",
+			Help: "Total number of events published to each publish target",
+		},
+		[]string{"kind", "relay", "result"},
+	)
+
+	publishDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "publish_duration_seconds",
+			Help:    "Time taken to publish an event to each publish target, retries included",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"kind", "relay"},
+	)
+)
+
+// ErrClosed is returned when publishing through a closed Publisher.
+var ErrClosed = errors.New("publisher closed")
+
+// Result is the outcome of publishing an event to one of the publish targets.
+type Result struct {
+	RelayURL string
+	Attempts int
+	// Err is the last error, nil when the relay accepted the event.
+	Err error
+}
+
+// PublishError is returned when no publish target accepted an event, so callers can tell
+// a failed publish apart from a failed health check.
+type PublishError struct {
+	EventID string
+	Kind    int
+	Results []Result
+}
+
+func (e *PublishError) Error() string {
+	if len(e.Results) == 0 {
+		return fmt.Sprintf("failed to publish kind %d event %s: no publish targets", e.Kind, e.EventID)
+	}
+
+	failures := make([]string, len(e.Results))
+	for i, r := range e.Results {
+		failures[i] = fmt.Sprintf("%s: %v", r.RelayURL, r.Err)
+	}
+
+	return fmt.Sprintf("failed to publish kind %d event %s: %s", e.Kind, e.EventID, strings.Join(failures, "; "))
+}
+
+func (e *PublishError) Unwrap() []error {
+	errs := make([]error, len(e.Results))
+	for i, r := range e.Results {
+		errs[i] = r.Err
+	}
+
+	return errs
+}
+
+// Publisher publishes events to every publish target concurrently, over a connection to each of them
+// reopened whenever it drops. Transient failures are retried with an exponential backoff.
+type Publisher struct {
+	relayURLs []string
+	timeout   time.Duration
+	retries   int
+	backoff   time.Duration
+	logger    *slog.Logger
+	relayRepo repository.RelayRepository
+	pool      *nostr.SimplePool
+}
+
+// Option is a functional option type that allows us to configure the Publisher.
+type Option func(*Publisher)
+
+// NewPublisher returns a Publisher to the given relays, duplicates are ignored.
+// No connection is opened until the first event is published.
+func NewPublisher(relayURLs []string, options ...Option) *Publisher {
+	p := &Publisher{
+		timeout: 10 * time.Second,
+		retries: 3,
+		backoff: time.Second,
+		logger:  slog.Default(),
+		pool:    nostr.NewSimplePool(context.Background()),
+	}
+
+	seen := map[string]bool{}
+	for _, url := range relayURLs {
+		if url != "" && !seen[url] {
+			seen[url] = true
+			p.relayURLs = append(p.relayURLs, url)
+		}
+	}
+
+	// Apply all the functional options to configure the Publisher.
+	for _, opt := range options {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithTimeout is a functional option to set how long a single attempt to publish an event may take.
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Publisher) {
+		p.timeout = timeout
+	}
+}
+
+// WithRetries is a functional option to set how many times a transient failure is retried,
+// waiting twice as long as the previous time before each retry, starting at backoff.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(p *Publisher) {
+		p.retries = retries
+		p.backoff = backoff
+	}
+}
+
+// WithLogger is a functional option to set the logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Publisher) {
+		p.logger = logger
+	}
+}
+
+// WithDB is a functional option to record the outcome of every publish in the published_events table.
+func WithDB(db *sqlx.DB) Option {
+	return func(p *Publisher) {
+		p.relayRepo = postgres.NewRelayRepository(db)
+	}
+}
+
+// Publish sends a signed event to every publish target and waits for their OK.
+// It only fails, with a *PublishError, when no target accepted the event.
+func (p *Publisher) Publish(ctx context.Context, ev nostr.Event) error {
+	if len(p.relayURLs) == 0 {
+		return &PublishError{EventID: ev.ID, Kind: ev.Kind}
+	}
+
+	results := make([]Result, len(p.relayURLs))
+
+	var wg sync.WaitGroup
+	for i, url := range p.relayURLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.publishTo(ctx, url, ev)
+		}()
+	}
+	wg.Wait()
+
+	p.record(ctx, ev, results)
+
+	var failures []Result
+	for _, r := range results {
+		if r.Err != nil {
+			failures = append(failures, r)
+		}
+	}
+
+	if len(failures) == len(results) {
+		return &PublishError{EventID: ev.ID, Kind: ev.Kind, Results: failures}
+	}
+
+	for _, r := range failures {
+		p.logger.Warn(
+			fmt.Sprintf("⚠️ kind %d event %s not published to %s: %v", ev.Kind, ev.ID, r.RelayURL, r.Err),
+		)
+	}
+
+	return nil
+}
+
+// publishTo sends the event to a single relay, retrying transient failures.
+func (p *Publisher) publishTo(ctx context.Context, url string, ev nostr.Event) Result {
+	kind := strconv.Itoa(ev.Kind)
+	start := time.Now()
+
+	result := Result{RelayURL: url}
+	for {
+		result.Attempts++
+		result.Err = p.publishOnce(ctx, url, ev)
+
+		if result.Err == nil || !transient(result.Err) || result.Attempts > p.retries {
+			break
+		}
+
+		wait := p.backoff << (result.Attempts - 1)
+		p.logger.Warn(
+			fmt.Sprintf("⚠️ failed to publish to %s, retrying in %s: %v", url, wait, result.Err),
+		)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			result.Err = fmt.Errorf("%w, gave up retrying: %w", result.Err, ctx.Err())
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	publishDuration.WithLabelValues(kind, url).Observe(time.Since(start).Seconds())
+
+	if result.Err != nil {
+		publishedCounter.WithLabelValues(kind, url, resultFailure).Inc()
+	} else {
+		publishedCounter.WithLabelValues(kind, url, resultSuccess).Inc()
+	}
+
+	return result
+}
+
+// publishOnce sends the event to a relay and waits for its OK.
+// A dropped connection is reopened and the event sent again, once.
+func (p *Publisher) publishOnce(ctx context.Context, url string, ev nostr.Event) error {
+	if p.pool.Context.Err() != nil {
+		return ErrClosed
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	relay, err := p.pool.EnsureRelay(url)
+	if err != nil {
+		return err
+	}
+
+	err = relay.Publish(ctx, ev)
+	if relay.IsConnected() {
+		// An OK, or a refusal, over a healthy connection is final.
+		return err
+	}
+
+	// go-nostr reports no error when the connection drops before the OK, the event may not have arrived.
+	p.logger.Warn(fmt.Sprintf("⚠️ connection to %s dropped, reconnecting", url))
+
+	// EnsureRelay dials again as the pooled connection is no longer connected.
+	relay, err = p.pool.EnsureRelay(url)
+	if err != nil {
+		return err
+	}
+
+	return relay.Publish(ctx, ev)
+}
+
+// record stores the outcome of the publish on every target, failing to do so doesn't fail the publish.
+func (p *Publisher) record(ctx context.Context, ev nostr.Event, results []Result) {
+	if p.relayRepo == nil {
+		return
+	}
+
+	now := time.Now()
+	events := make([]domain.PublishedEvent, len(results))
+	for i, r := range results {
+		events[i] = domain.PublishedEvent{
+			EventID:   ev.ID,
+			Kind:      ev.Kind,
+			RelayURL:  r.RelayURL,
+			Status:    domain.PublishStatusPublished,
+			Attempts:  r.Attempts,
+			CreatedAt: &now,
+		}
+
+		if r.Err != nil {
+			msg := r.Err.Error()
+			events[i].Status = domain.PublishStatusFailed
+			events[i].Error = &msg
+		}
+	}
+
+	if err := p.relayRepo.SavePublishedEvents(ctx, events); err != nil {
+		p.logger.Error(fmt.Sprintf("❌ failed to record the publish of event %s: %v", ev.ID, err))
+	}
+}
+
+// transient tells whether publishing again may succeed. Relays refuse events with an OK message
+// prefixed by why (NIP-01), only rate limits and errors on their side are worth retrying.
+func transient(err error) bool {
+	if errors.Is(err, ErrClosed) {
+		return false
+	}
+
+	// go-nostr reports refusals as "msg: <reason>".
+	reason, refused := strings.CutPrefix(err.Error(), "msg: ")
+	if !refused {
+		// Connection failures and timeouts.
+		return true
+	}
+
+	return strings.HasPrefix(reason, "rate-limited:") || strings.HasPrefix(reason, "error:")
+}
+
+// Close closes the connections to the relays, events can no longer be published afterwards.
+func (p *Publisher) Close() error {
+	p.pool.Relays.Range(func(_ string, relay *nostr.Relay) bool {
+		_ = relay.Close()
+		return true
+	})
+
+	p.pool.Close("publisher closed")
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package publisher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coder/websocket"
+	"github.com/jmoiron/sqlx"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+)
+
+// fakeRelay accepts every event unless told to refuse them, counting the connections it was asked to open.
+type fakeRelay struct {
+	server *httptest.Server
+	// refusals are the reasons the next events are refused with, in order, the rest are accepted.
+	refusals []string
+	// refuse is the reason every event is refused with once refusals are exhausted, if set.
+	refuse string
+
+	mu          sync.Mutex
+	connections int
+	events      int
+	conns       []*websocket.Conn
+}
+
+func newFakeRelay(t *testing.T) *fakeRelay {
+	t.Helper()
+
+	fr := &fakeRelay{}
+	fr.server = httptest.NewServer(http.HandlerFunc(fr.handle))
+	t.Cleanup(fr.server.Close)
+
+	return fr
+}
+
+func (fr *fakeRelay) URL() string {
+	return strings.Replace(fr.server.URL, "http://", "ws://", 1)
+}
+
+func (fr *fakeRelay) handle(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	fr.mu.Lock()
+	fr.connections++
+	fr.conns = append(fr.conns, conn)
+	fr.mu.Unlock()
+
+	for {
+		_, msg, err := conn.Read(r.Context())
+		if err != nil {
+			return
+		}
+
+		env, ok := nostr.ParseMessage(string(msg)).(*nostr.EventEnvelope)
+		if !ok {
+			continue
+		}
+
+		reply := nostr.OKEnvelope{EventID: env.ID, OK: true}
+		if reason := fr.nextRefusal(); reason != "" {
+			reply.OK = false
+			reply.Reason = reason
+		}
+
+		b, err := reply.MarshalJSON()
+		if err != nil {
+			return
+		}
+		if err := conn.Write(r.Context(), websocket.MessageText, b); err != nil {
+			return
+		}
+	}
+}
+
+// nextRefusal returns why the next event is refused, empty when it is accepted.
+func (fr *fakeRelay) nextRefusal() string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	fr.events++
+
+	if len(fr.refusals) > 0 {
+		reason := fr.refusals[0]
+		fr.refusals = fr.refusals[1:]
+		return reason
+	}
+
+	return fr.refuse
+}
+
+// received returns the number of events sent to the relay, accepted or not.
+func (fr *fakeRelay) received() int {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	return fr.events
+}
+
+// drop closes every open connection, as a relay restarting would.
+func (fr *fakeRelay) drop() {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	for _, conn := range fr.conns {
+		_ = conn.CloseNow()
+	}
+	fr.conns = nil
+}
+
+func (fr *fakeRelay) connectionCount() int {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	return fr.connections
+}
+
+func signedEvent(t *testing.T) nostr.Event {
+	t.Helper()
+
+	ev := nostr.Event{CreatedAt: nostr.Now(), Kind: 30166, Tags: nostr.Tags{{"d", "wss://relay.example.com"}}}
+	require.NoError(t, ev.Sign(nostr.GeneratePrivateKey()))
+
+	return ev
+}
+
+func TestPublishReusesConnection(t *testing.T) {
+	fr := newFakeRelay(t)
+	p := NewPublisher([]string{fr.URL()}, WithTimeout(2*time.Second))
+	defer func() {
+		_ = p.Close()
+	}()
+
+	for range 3 {
+		require.NoError(t, p.Publish(context.Background(), signedEvent(t)))
+	}
+
+	require.Equal(t, 1, fr.connectionCount())
+}
+
+func TestPublishReconnects(t *testing.T) {
+	fr := newFakeRelay(t)
+	p := NewPublisher([]string{fr.URL()}, WithTimeout(2*time.Second), WithRetries(0, 0))
+	defer func() {
+		_ = p.Close()
+	}()
+
+	require.NoError(t, p.Publish(context.Background(), signedEvent(t)))
+
+	fr.drop()
+
+	require.Eventually(t, func() bool {
+		return p.Publish(context.Background(), signedEvent(t)) == nil
+	}, 5*time.Second, 100*time.Millisecond)
+	require.GreaterOrEqual(t, fr.connectionCount(), 2)
+}
+
+func TestPublishFansOut(t *testing.T) {
+	accepting := newFakeRelay(t)
+	refusing := newFakeRelay(t)
+	refusing.refuse = "blocked: not allowed"
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	ev := signedEvent(t)
+
+	mock.ExpectExec("INSERT INTO published_events").
+		WithArgs(
+			ev.ID, ev.Kind, accepting.URL(), domain.PublishStatusPublished, nil, 1, sqlmock.AnyArg(),
+			ev.ID, ev.Kind, refusing.URL(), domain.PublishStatusFailed, sqlmock.AnyArg(), 1, sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+
+	p := NewPublisher(
+		[]string{accepting.URL(), refusing.URL(), accepting.URL()},
+		WithTimeout(2*time.Second),
+		WithRetries(3, time.Millisecond),
+		WithDB(sqlx.NewDb(db, "postgres")),
+	)
+	defer func() {
+		_ = p.Close()
+	}()
+
+	// One target accepting the event is enough, the refusal is final and not retried.
+	require.NoError(t, p.Publish(context.Background(), ev))
+	require.Equal(t, 1, accepting.received())
+	require.Equal(t, 1, refusing.received())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishRetriesTransientFailures(t *testing.T) {
+	fr := newFakeRelay(t)
+	fr.refusals = []string{"rate-limited: slow down", "error: could not store"}
+
+	p := NewPublisher([]string{fr.URL()}, WithTimeout(2*time.Second), WithRetries(3, time.Millisecond))
+	defer func() {
+		_ = p.Close()
+	}()
+
+	require.NoError(t, p.Publish(context.Background(), signedEvent(t)))
+	require.Equal(t, 3, fr.received())
+}
+
+func TestPublishRefused(t *testing.T) {
+	fr := newFakeRelay(t)
+	fr.refuse = "rate-limited: slow down"
+
+	p := NewPublisher([]string{fr.URL()}, WithTimeout(2*time.Second), WithRetries(2, time.Millisecond))
+	defer func() {
+		_ = p.Close()
+	}()
+
+	ev := signedEvent(t)
+	err := p.Publish(context.Background(), ev)
+
+	var publishErr *PublishError
+	require.ErrorAs(t, err, &publishErr)
+	require.Equal(t, ev.ID, publishErr.EventID)
+	require.Equal(t, 30166, publishErr.Kind)
+	require.Len(t, publishErr.Results, 1)
+	require.Equal(t, fr.URL(), publishErr.Results[0].RelayURL)
+	require.Equal(t, 3, publishErr.Results[0].Attempts)
+	require.Contains(t, err.Error(), "rate-limited")
+}
+
+func TestPublishAfterClose(t *testing.T) {
+	fr := newFakeRelay(t)
+	p := NewPublisher([]string{fr.URL()})
+
+	require.NoError(t, p.Close())
+	require.ErrorIs(t, p.Publish(context.Background(), signedEvent(t)), ErrClosed)
+}
+
+func TestPublishWithoutTargets(t *testing.T) {
+	var publishErr *PublishError
+	require.ErrorAs(t, NewPublisher(nil).Publish(context.Background(), signedEvent(t)), &publishErr)
+}
@@ -0,0 +1,127 @@
+// Package relayurl canonicalizes relay URLs, so a relay is stored and looked up under a single key
+// no matter how it was written.
+package relayurl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalid is returned for URLs that can't be the address of a relay.
+var ErrInvalid = errors.New("invalid relay url")
+
+// defaultPorts are dropped from canonical URLs.
+var defaultPorts = map[string]string{
+	"ws":  "80",
+	"wss": "443",
+}
+
+// Normalize returns the canonical form of a relay URL:
+// lowercase scheme and host, no default port, no trailing slash, no fragment.
+// Only ws and wss URLs are accepted.
+func Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("%w: empty url", ErrInvalid)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	// url.Parse already lowercases the scheme.
+	if _, ok := defaultPorts[u.Scheme]; !ok {
+		return "", fmt.Errorf("%w: %q scheme, expected ws or wss", ErrInvalid, u.Scheme)
+	}
+
+	if u.User != nil {
+		return "", fmt.Errorf("%w: credentials are not allowed", ErrInvalid)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return "", fmt.Errorf("%w: missing host", ErrInvalid)
+	}
+
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 literal without port.
+		host = "[" + host + "]"
+	}
+
+	canonical := u.Scheme + "://" + host + strings.TrimRight(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		canonical += "?" + u.RawQuery
+	}
+
+	return canonical, nil
+}
+
+// Duplicates groups the given URLs by canonical form, keeping only the groups that need to be merged:
+// several URLs for the same relay, or a single one that isn't canonical.
+// URLs that can't be normalized are returned apart.
+func Duplicates(urls []string) (groups map[string][]string, invalid []string) {
+	groups = make(map[string][]string)
+
+	for _, u := range urls {
+		canonical, err := Normalize(u)
+		if err != nil {
+			invalid = append(invalid, u)
+			continue
+		}
+
+		groups[canonical] = append(groups[canonical], u)
+	}
+
+	for canonical, members := range groups {
+		if len(members) == 1 && members[0] == canonical {
+			delete(groups, canonical)
+		}
+	}
+
+	return groups, invalid
+}
+
+// Networks a relay can be reached on, as tagged in NIP-66 "n" tags.
+const (
+	NetworkClearnet = "clearnet"
+	NetworkTor      = "tor"
+	NetworkI2P      = "i2p"
+	NetworkLoki     = "loki"
+)
+
+// overlaySuffixes map the top-level domains of overlay networks to their network.
+var overlaySuffixes = map[string]string{
+	".onion": NetworkTor,
+	".i2p":   NetworkI2P,
+	".loki":  NetworkLoki,
+}
+
+// Network returns the network a relay URL is reachable on, from the top-level domain of its host.
+// URLs that can't be parsed are considered clearnet.
+func Network(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return NetworkClearnet
+	}
+
+	return HostNetwork(u.Hostname())
+}
+
+// HostNetwork returns the network a host name belongs to.
+func HostNetwork(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for suffix, network := range overlaySuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return network
+		}
+	}
+
+	return NetworkClearnet
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor can't be decoded or
+// was produced for a different sort than the one requested.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of the last relay of a page in a keyset paginated list.
+// It is handed to clients as an opaque string.
+type Cursor struct {
+	Sort string  `json:"s"`
+	Desc bool    `json:"d,omitempty"`
+	Key  *string `json:"k"` // value of the sort column, nil when the column is NULL
+	URL  string  `json:"u"` // tie breaker
+}
+
+// Encode returns the opaque representation of the cursor.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses an opaque cursor and checks it belongs to the given sort.
+func DecodeCursor(s string, sort string, desc bool) (Cursor, error) {
+	var c Cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if c.Sort != SortKey(sort) || c.Desc != desc {
+		return Cursor{}, fmt.Errorf("%w: cursor does not match the requested sort", ErrInvalidCursor)
+	}
+
+	return c, nil
+}
+
+// NextCursor returns the cursor of the page following the given relays,
+// or an empty string when the page was not full and there is nothing left.
+func NextCursor(relays []domain.Relay, opts *ListOption) string {
+	if opts == nil || opts.Limit == nil || len(relays) < *opts.Limit || len(relays) == 0 {
+		return ""
+	}
+
+	last := relays[len(relays)-1]
+
+	return Cursor{
+		Sort: SortKey(opts.Sort),
+		Desc: opts.Desc,
+		Key:  last.SortKey,
+		URL:  last.URL,
+	}.Encode()
+}
+
+// SortKey returns the effective sort of a list, the URL when none is given.
+func SortKey(sort string) string {
+	if sort == "" {
+		return SortByURL
+	}
+	return sort
+}
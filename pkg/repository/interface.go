@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
 )
@@ -10,12 +11,112 @@ type ListOption struct {
 	Limit  *int
 	Offset *int
 	URLs   []string
+
+	// Online keeps the relays whose latest check succeeded (true) or did not (false).
+	Online *bool
+	// NIP keeps the relays that claim support for the given NIP.
+	NIP *int
+	// Software keeps the relays whose software contains the given string, case insensitive.
+	Software string
+	// Country keeps the relays that declare the given country code.
+	Country string
+	// Search keeps the relays whose name, description or URL contain every word of the given text, case insensitive.
+	Search string
+	// Classification keeps the relays of the given class: "Public", "Paid", "WoT" or "Private".
+	Classification string
+	// Language keeps the relays that declare the given language tag.
+	Language string
+	// Network keeps the relays on the given network: "clearnet", "tor", "i2p" or "loki".
+	Network string
+	// Requirements keeps the relays declaring every given NIP-66 requirement in NIP-11:
+	// "auth", "payment", "writes" or "pow", or their absence when prefixed with "!". Unknown ones are ignored.
+	Requirements []string
+	// Statuses keeps the relays in any of the given lifecycle statuses. Empty means all of them.
+	Statuses []string
+
+	// Sort orders the relays by "url", "name", "rtt", "last_check", "random" or "relevance". Empty means by URL.
+	// The URL always breaks ties, so the order is stable across pages.
+	Sort string
+	// Desc reverses the sort order.
+	Desc bool
+	// Cursor continues the list after the relay it points to, see NextCursor.
+	// Prefer it over Offset, it doesn't skip or repeat relays when the table changes between pages.
+	Cursor string
+}
+
+// Sort keys accepted by ListOption.
+const (
+	SortByURL       = "url"
+	SortByName      = "name"
+	SortByRTT       = "rtt"
+	SortByLastCheck = "last_check"
+	// SortByRandom shuffles relays in an order that changes every day, but is stable within the day.
+	SortByRandom = "random"
+	// SortByRelevance puts the relays matching the search in their name first, then in their description,
+	// then in their URL. It requires a search.
+	SortByRelevance = "relevance"
+)
+
+// HealthCheckListOption narrows the health checks of a relay to a time range.
+type HealthCheckListOption struct {
+	Since  *time.Time
+	Until  *time.Time
+	Limit  *int
+	Offset *int
+}
+
+// DocumentListOption selects a page of the NIP-11 document versions of a relay.
+type DocumentListOption struct {
+	Limit  *int
+	Offset *int
 }
 
 type RelayRepository interface {
-	Create(ctx context.Context, relayInfo domain.Relay) error
+	// Create inserts a relay unless it's already stored, and tells whether it was inserted.
+	Create(ctx context.Context, relayInfo domain.Relay) (bool, error)
 	List(ctx context.Context, opts *ListOption) ([]domain.Relay, error)
 	FindByURL(ctx context.Context, url string) (domain.Relay, error)
 	Update(ctx context.Context, relayInfo domain.Relay) error
 	SaveHealthCheck(ctx context.Context, status domain.HealthCheck) error
+	ListHealthChecks(
+		ctx context.Context,
+		url string,
+		opts *HealthCheckListOption,
+	) ([]domain.HealthCheck, error)
+	SaveSSLCheck(ctx context.Context, check domain.SSLCheck) error
+	FindLatestSSLCheck(ctx context.Context, url string) (domain.SSLCheck, error)
+	SaveDNSCheck(ctx context.Context, check domain.DNSCheck) error
+	SaveAuthCheck(ctx context.Context, check domain.AuthCheck) error
+	FindLatestAuthCheck(ctx context.Context, url string) (domain.AuthCheck, error)
+	SaveNIPChecks(ctx context.Context, checks []domain.NIPCheck) error
+	ListLatestNIPChecks(ctx context.Context, url string) ([]domain.NIPCheck, error)
+	SavePublishedEvents(ctx context.Context, events []domain.PublishedEvent) error
+	SaveOutboxEvent(ctx context.Context, event domain.OutboxEvent) error
+	FindOutboxEvent(ctx context.Context, eventID string) (domain.OutboxEvent, error)
+	FindLatestOutboxEvent(ctx context.Context, relayURL string, kind int) (domain.OutboxEvent, error)
+	ListUnmonitoredRelaysWithEvents(ctx context.Context) ([]string, error)
+	ListPendingOutboxEvents(ctx context.Context, from, to time.Time) ([]domain.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, eventID string) error
+	MarkOutboxEventFailed(ctx context.Context, eventID, reason string) error
+	MarkOutboxEventExpired(ctx context.Context, eventID string) error
+	SaveDocument(ctx context.Context, document domain.RelayDocument) (bool, error)
+	ListDocuments(
+		ctx context.Context,
+		url string,
+		opts *DocumentListOption,
+	) ([]domain.RelayDocument, error)
+	UpdateObservedLocation(ctx context.Context, url, country, geohash string) error
+	ListFacets(ctx context.Context) (domain.RelayFacets, error)
+	GetStats(ctx context.Context, url string, since time.Time) (domain.RelayStats, error)
+	ListStats(ctx context.Context, urls []string, since time.Time) ([]domain.RelayStats, error)
+	ListSoftwareStats(ctx context.Context, since time.Time) ([]domain.SoftwareStats, error)
+	ListNIPSupport(ctx context.Context) ([]domain.NIPSupport, error)
+	ListNIPSupportHistory(ctx context.Context, since time.Time, step time.Duration) ([]domain.NIPSupport, error)
+	MergeRelays(ctx context.Context, into string, duplicates []string) error
+	SetStatus(ctx context.Context, url string, status string) error
+	RetireDeadRelays(ctx context.Context, deadSince time.Time) (int64, error)
+	ReviveRetiredRelays(ctx context.Context) (int64, error)
+	// WithTx runs fn with a repository whose queries all run in a single transaction,
+	// committed when fn returns nil and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(RelayRepository) error) error
 }
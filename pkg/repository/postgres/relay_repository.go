@@ -2,23 +2,72 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
 )
 
+// dbtx is what the repository runs its queries on, the pool of connections or a transaction.
+type dbtx interface {
+	sqlx.ExtContext
+	NamedExec(query string, arg any) (sql.Result, error)
+	NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error)
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
 type relayRepository struct {
-	db *sqlx.DB
+	db dbtx
 }
 
 func NewRelayRepository(db *sqlx.DB) repository.RelayRepository {
 	return &relayRepository{db: db}
 }
 
+// WithTx runs fn with a repository whose queries all run in a single transaction,
+// committed when fn returns nil and rolled back otherwise. Nested calls join the outer transaction.
+func (r *relayRepository) WithTx(ctx context.Context, fn func(repository.RelayRepository) error) error {
+	return r.inTx(ctx, func(tx *relayRepository) error {
+		return fn(tx)
+	})
+}
+
+// inTx is WithTx for the methods of the repository itself.
+func (r *relayRepository) inTx(ctx context.Context, fn func(tx *relayRepository) error) error {
+	db, ok := r.db.(*sqlx.DB)
+	if !ok {
+		// Already in a transaction.
+		return fn(r)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(&relayRepository{db: tx}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // List returns a list of relays of interest from the databased to be monitored.
 func (r *relayRepository) List(
 	ctx context.Context,
@@ -26,16 +75,17 @@ func (r *relayRepository) List(
 ) ([]domain.Relay, error) {
 	var relays []domain.Relay
 
-	tx, err := r.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer func() {
-		_ = tx.Rollback()
-	}()
+	sort := sortColumns[repository.SortByURL]
+	if opts != nil {
+		s, ok := sortColumns[repository.SortKey(opts.Sort)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported sort %q", opts.Sort)
+		}
+		sort = s
 
-	if _, err := tx.ExecContext(ctx, "SELECT setseed(EXTRACT(DOY FROM current_date) / 366.0)"); err != nil {
-		return nil, fmt.Errorf("failed to set seed: %w", err)
+		if repository.SortKey(opts.Sort) == repository.SortByRelevance && opts.Search == "" {
+			return nil, fmt.Errorf("sort %q requires a search", opts.Sort)
+		}
 	}
 
 	// Build the complete query with subquery inline
@@ -56,22 +106,32 @@ func (r *relayRepository) List(
 		"r.language_tags",
 		"r.tags",
 		"r.posting_policy",
+		"r.status",
+		"r.discovered_via",
 		"r.created_at",
 		"r.updated_at",
+		fmt.Sprintf("(%s)::TEXT AS sort_key", sort.expr),
 		`h.created_at AS "health_checks.created_at"`,
 		`h.websocket_success AS "health_checks.websocket_success"`,
 		`h.nip11_success AS "health_checks.nip11_success"`,
 		`h.rtt_open AS "health_checks.rtt_open"`,
+		`h.rtt_read AS "health_checks.rtt_read"`,
+		`h.rtt_write AS "health_checks.rtt_write"`,
 		`h.rtt_nip11 AS "health_checks.rtt_nip11"`,
 	).
 		From("relays AS r").
 		LeftJoin(`(
-			SELECT DISTINCT ON (relay_url) relay_url, created_at, websocket_success, nip11_success, rtt_open, rtt_nip11
+			SELECT DISTINCT ON (relay_url)
+				relay_url, created_at, websocket_success, nip11_success, rtt_open, rtt_read, rtt_write, rtt_nip11
 			FROM health_checks
 			ORDER BY relay_url, created_at DESC
 		) h ON r.url = h.relay_url`)
 
+	desc := false
+
 	if opts != nil {
+		desc = opts.Desc
+
 		if opts.Limit != nil {
 			query = query.Limit(uint64(*opts.Limit))
 		}
@@ -82,23 +142,30 @@ func (r *relayRepository) List(
 		if len(opts.URLs) > 0 {
 			query = query.Where(sq.Eq{"r.url": opts.URLs})
 		}
+
+		query = applyListFilters(query, opts)
+
+		if opts.Cursor != "" {
+			cursor, err := repository.DecodeCursor(opts.Cursor, opts.Sort, opts.Desc)
+			if err != nil {
+				return nil, err
+			}
+
+			query = query.Where(sort.after(cursor, desc))
+		}
 	}
 
-	query = query.OrderBy("RANDOM()")
+	query = query.OrderBy(sort.orderBy(desc)...)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build query: %w", err)
 	}
 
-	if err := tx.SelectContext(ctx, &relays, sql, args...); err != nil {
+	if err := r.db.SelectContext(ctx, &relays, sql, args...); err != nil {
 		return nil, fmt.Errorf("failed to get relays: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	// Post-process to set HealthCheck to nil when there's no actual health check data
 	for i := range relays {
 		if relays[i].HealthCheck != nil && relays[i].HealthCheck.CreatedAt == nil {
@@ -109,6 +176,196 @@ func (r *relayRepository) List(
 	return relays, nil
 }
 
+// sortColumn describes how relays are ordered by a sort key.
+// The URL always breaks ties, in ascending order, to make the order total.
+type sortColumn struct {
+	// expr is the SQL expression relays are ordered by.
+	expr string
+	// cast turns the textual cursor key back into the type of expr.
+	cast string
+	// nullable tells whether expr can be NULL, NULLs always go last.
+	nullable bool
+}
+
+// sortColumns maps the sort keys of repository.ListOption to their SQL expressions.
+var sortColumns = map[string]sortColumn{
+	repository.SortByURL:       {expr: "r.url", cast: "TEXT"},
+	repository.SortByName:      {expr: "LOWER(r.name)", cast: "TEXT", nullable: true},
+	repository.SortByRTT:       {expr: "h.rtt_open", cast: "INTEGER", nullable: true},
+	repository.SortByLastCheck: {expr: "h.created_at", cast: "TIMESTAMPTZ", nullable: true},
+	// Hashing the URL with the date shuffles relays once a day, while keeping a
+	// total order that keyset pagination can rely on.
+	repository.SortByRandom: {expr: "MD5(r.url || current_date::TEXT)", cast: "TEXT"},
+	// The rank is negated so the most relevant relays come first in ascending order.
+	repository.SortByRelevance: {expr: "-ts_rank(r.search_vector, search_query)", cast: "REAL"},
+}
+
+// orderBy returns the ORDER BY clauses of the sort.
+func (s sortColumn) orderBy(desc bool) []string {
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+
+	if s.expr == "r.url" {
+		return []string{"r.url " + direction}
+	}
+
+	return []string{
+		fmt.Sprintf("%s %s NULLS LAST", s.expr, direction),
+		"r.url ASC",
+	}
+}
+
+// after returns the keyset condition selecting the relays that come after the cursor.
+func (s sortColumn) after(c repository.Cursor, desc bool) sq.Sqlizer {
+	cmp := ">"
+	if desc {
+		cmp = "<"
+	}
+
+	if s.expr == "r.url" {
+		return sq.Expr(fmt.Sprintf("r.url %s ?", cmp), c.URL)
+	}
+
+	// The cursor sits among the NULLs, which go last: only NULLs with a greater URL follow.
+	if c.Key == nil {
+		return sq.And{
+			sq.Expr(fmt.Sprintf("%s IS NULL", s.expr)),
+			sq.Expr("r.url > ?", c.URL),
+		}
+	}
+
+	key := fmt.Sprintf("?::%s", s.cast)
+
+	after := sq.Or{
+		sq.Expr(fmt.Sprintf("%s %s %s", s.expr, cmp, key), *c.Key),
+		sq.Expr(fmt.Sprintf("%s = %s AND r.url > ?", s.expr, key), *c.Key, c.URL),
+	}
+
+	if s.nullable {
+		after = append(after, sq.Expr(fmt.Sprintf("%s IS NULL", s.expr)))
+	}
+
+	return after
+}
+
+// applyListFilters narrows the relays query down with the optional filters.
+func applyListFilters(query sq.SelectBuilder, opts *repository.ListOption) sq.SelectBuilder {
+	if len(opts.Statuses) > 0 {
+		query = query.Where(sq.Eq{"r.status": opts.Statuses})
+	}
+
+	if opts.Online != nil {
+		if *opts.Online {
+			query = query.Where(sq.Eq{"h.websocket_success": true})
+		} else {
+			// Relays never checked are not online either.
+			query = query.Where(sq.Or{
+				sq.Eq{"h.websocket_success": false},
+				sq.Eq{"h.websocket_success": nil},
+			})
+		}
+	}
+
+	if opts.NIP != nil {
+		query = query.Where("r.supported_nips @> ARRAY[?]::INTEGER[]", *opts.NIP)
+	}
+
+	if opts.Software != "" {
+		query = query.Where(sq.ILike{"r.software": "%" + opts.Software + "%"})
+	}
+
+	if opts.Country != "" {
+		// Country codes are compared case insensitively, as NIP-11 documents spell them both ways.
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM UNNEST(r.relay_countries) AS country WHERE UPPER(country) = ?)",
+			strings.ToUpper(opts.Country),
+		)
+	}
+
+	if opts.Language != "" {
+		query = query.Where("r.language_tags @> ARRAY[?]::TEXT[]", opts.Language)
+	}
+
+	if opts.Network != "" {
+		query = query.Where(networkCondition(opts.Network))
+	}
+
+	for _, requirement := range opts.Requirements {
+		if cond, ok := requirementCondition(requirement); ok {
+			query = query.Where(cond)
+		}
+	}
+
+	if opts.Search != "" {
+		// The search query is joined once, so the relevance sort can rank relays against it too.
+		query = query.
+			JoinClause("CROSS JOIN plainto_tsquery('simple', ?) AS search_query", opts.Search).
+			Where("r.search_vector @@ search_query")
+	}
+
+	// Classification is derived from the tags, following the same precedence as the dashboard.
+	switch opts.Classification {
+	case "Paid":
+		query = query.Where("r.tags @> ARRAY['paid']::TEXT[]")
+	case "WoT":
+		query = query.Where("r.tags @> ARRAY['wot']::TEXT[] AND NOT r.tags @> ARRAY['paid']::TEXT[]")
+	case "Private":
+		query = query.Where("r.tags @> ARRAY['private']::TEXT[] AND NOT r.tags && ARRAY['paid', 'wot']::TEXT[]")
+	case "Public":
+		query = query.Where("NOT COALESCE(r.tags && ARRAY['paid', 'wot', 'private']::TEXT[], false)")
+	}
+
+	return query
+}
+
+// requirementConditions are the SQL conditions matching the relays declaring each NIP-66 requirement in NIP-11.
+var requirementConditions = map[string]string{
+	"auth":    "COALESCE((r.limitation->>'auth_required')::BOOLEAN, false)",
+	"payment": "COALESCE((r.limitation->>'payment_required')::BOOLEAN, false)",
+	"writes":  "COALESCE((r.limitation->>'restricted_writes')::BOOLEAN, false)",
+	"pow":     "COALESCE((r.limitation->>'min_pow_difficulty')::INTEGER, 0) > 0",
+}
+
+// requirementCondition returns the condition matching a requirement, or its absence when prefixed with "!".
+func requirementCondition(requirement string) (sq.Sqlizer, bool) {
+	negated := strings.HasPrefix(requirement, "!")
+
+	cond, ok := requirementConditions[strings.TrimPrefix(requirement, "!")]
+	if !ok {
+		return nil, false
+	}
+
+	if negated {
+		return sq.Expr("NOT " + cond), true
+	}
+
+	return sq.Expr(cond), true
+}
+
+// networkHosts match the host of canonical relay URLs on overlay networks, by top-level domain.
+var networkHosts = map[string]string{
+	relayurl.NetworkTor:  `^wss?://[^/?]+\.onion(:[0-9]+)?([/?]|$)`,
+	relayurl.NetworkI2P:  `^wss?://[^/?]+\.i2p(:[0-9]+)?([/?]|$)`,
+	relayurl.NetworkLoki: `^wss?://[^/?]+\.loki(:[0-9]+)?([/?]|$)`,
+}
+
+// networkCondition keeps the relays on the given network, telling them apart by the host of their URL.
+func networkCondition(network string) sq.Sqlizer {
+	if pattern, ok := networkHosts[network]; ok {
+		return sq.Expr("r.url ~ ?", pattern)
+	}
+
+	// Clearnet is whatever is on none of the overlay networks.
+	var conds sq.And
+	for _, overlay := range []string{relayurl.NetworkTor, relayurl.NetworkI2P, relayurl.NetworkLoki} {
+		conds = append(conds, sq.Expr("r.url !~ ?", networkHosts[overlay]))
+	}
+
+	return conds
+}
+
 func (r *relayRepository) FindByURL(ctx context.Context, url string) (domain.Relay, error) {
 	relay := domain.Relay{}
 
@@ -131,12 +388,22 @@ func (r *relayRepository) FindByURL(ctx context.Context, url string) (domain.Rel
 		"r.language_tags",
 		"r.tags",
 		"r.posting_policy",
+		"r.limitation",
+		"r.fees",
+		"r.retention",
+		"r.payments_url",
+		"r.observed_country",
+		"r.observed_geohash",
+		"r.status",
+		"r.discovered_via",
 		"r.created_at",
 		"r.updated_at",
 		`h.created_at AS "health_checks.created_at"`,
 		`h.websocket_success AS "health_checks.websocket_success"`,
 		`h.nip11_success AS "health_checks.nip11_success"`,
 		`h.rtt_open AS "health_checks.rtt_open"`,
+		`h.rtt_read AS "health_checks.rtt_read"`,
+		`h.rtt_write AS "health_checks.rtt_write"`,
 		`h.rtt_nip11 AS "health_checks.rtt_nip11"`,
 	).From("relays AS r").
 		LeftJoin("health_checks AS h ON r.url = h.relay_url").
@@ -176,6 +443,13 @@ func (r *relayRepository) Update(ctx context.Context, relayInfo domain.Relay) er
             privacy_policy = :privacy_policy,
             terms_of_service = :terms_of_service,
             posting_policy = :posting_policy,
+            relay_countries = :relay_countries,
+            language_tags = :language_tags,
+            tags = :tags,
+            limitation = :limitation,
+            fees = :fees,
+            retention = :retention,
+            payments_url = :payments_url,
             updated_at = CURRENT_TIMESTAMP
         WHERE url = :url`
 
@@ -186,7 +460,15 @@ func (r *relayRepository) Update(ctx context.Context, relayInfo domain.Relay) er
 	return nil
 }
 
-func (r *relayRepository) Create(ctx context.Context, relayInfo domain.Relay) error {
+// Create inserts a relay under its canonical URL, relays already stored are left untouched.
+// It tells whether the relay was inserted.
+func (r *relayRepository) Create(ctx context.Context, relayInfo domain.Relay) (bool, error) {
+	url, err := relayurl.Normalize(relayInfo.URL)
+	if err != nil {
+		return false, err
+	}
+	relayInfo.URL = url
+
 	query := `
 		INSERT INTO relays (
 			url,
@@ -202,6 +484,7 @@ func (r *relayRepository) Create(ctx context.Context, relayInfo domain.Relay) er
 			privacy_policy,
 			terms_of_service,
 			posting_policy,
+			discovered_via,
 			updated_at
 		)
 		VALUES (
@@ -218,15 +501,22 @@ func (r *relayRepository) Create(ctx context.Context, relayInfo domain.Relay) er
 			:privacy_policy,
 			:terms_of_service,
 			:posting_policy,
+			:discovered_via,
 			:updated_at
 		)
 		ON CONFLICT (url) DO NOTHING`
 
-	if _, err := r.db.NamedExecContext(ctx, query, relayInfo); err != nil {
-		return fmt.Errorf("failed to insert relay info: %w", err)
+	res, err := r.db.NamedExecContext(ctx, query, relayInfo)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert relay info: %w", err)
 	}
 
-	return nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check inserted relay %s: %w", url, err)
+	}
+
+	return n > 0, nil
 }
 
 func (r *relayRepository) SaveHealthCheck(ctx context.Context, status domain.HealthCheck) error {
@@ -263,3 +553,968 @@ func (r *relayRepository) SaveHealthCheck(ctx context.Context, status domain.Hea
 
 	return nil
 }
+
+// ListHealthChecks returns the health checks of the given relay, most recent first.
+func (r *relayRepository) ListHealthChecks(
+	ctx context.Context,
+	url string,
+	opts *repository.HealthCheckListOption,
+) ([]domain.HealthCheck, error) {
+	var checks []domain.HealthCheck
+
+	query := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select(
+		"relay_url",
+		"created_at",
+		"websocket_success",
+		"websocket_error",
+		"nip11_success",
+		"nip11_error",
+		"rtt_open",
+		"rtt_read",
+		"rtt_write",
+		"rtt_nip11",
+	).
+		From("health_checks").
+		Where(sq.Eq{"relay_url": url}).
+		OrderBy("created_at DESC")
+
+	if opts != nil {
+		if opts.Since != nil {
+			query = query.Where(sq.GtOrEq{"created_at": *opts.Since})
+		}
+		if opts.Until != nil {
+			query = query.Where(sq.Lt{"created_at": *opts.Until})
+		}
+		if opts.Limit != nil {
+			query = query.Limit(uint64(*opts.Limit))
+		}
+		if opts.Offset != nil {
+			query = query.Offset(uint64(*opts.Offset))
+		}
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if err := r.db.SelectContext(ctx, &checks, sql, args...); err != nil {
+		return nil, fmt.Errorf("failed to get health checks: %w", err)
+	}
+
+	return checks, nil
+}
+
+// SaveSSLCheck stores the result of a TLS certificate check.
+func (r *relayRepository) SaveSSLCheck(ctx context.Context, check domain.SSLCheck) error {
+	query := `
+		INSERT INTO ssl_checks (
+			relay_url,
+			created_at,
+			success,
+			error,
+			valid,
+			chain_valid,
+			issuer,
+			subject,
+			not_before,
+			expires_at,
+			days_to_expiry,
+			expiring_soon
+		)
+		VALUES (
+			:relay_url,
+			:created_at,
+			:success,
+			:error,
+			:valid,
+			:chain_valid,
+			:issuer,
+			:subject,
+			:not_before,
+			:expires_at,
+			:days_to_expiry,
+			:expiring_soon
+		)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, check); err != nil {
+		return fmt.Errorf("failed to save ssl check: %w", err)
+	}
+
+	return nil
+}
+
+// FindLatestSSLCheck returns the most recent TLS certificate check of the given relay.
+// It returns sql.ErrNoRows when the relay has never been checked.
+func (r *relayRepository) FindLatestSSLCheck(ctx context.Context, url string) (domain.SSLCheck, error) {
+	var check domain.SSLCheck
+
+	if err := r.db.GetContext(ctx, &check, `
+		SELECT
+			relay_url,
+			created_at,
+			success,
+			error,
+			valid,
+			chain_valid,
+			issuer,
+			subject,
+			not_before,
+			expires_at,
+			days_to_expiry,
+			expiring_soon
+		FROM ssl_checks
+		WHERE relay_url = $1
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		url,
+	); err != nil {
+		return domain.SSLCheck{}, fmt.Errorf("failed to get ssl check of %s: %w", url, err)
+	}
+
+	return check, nil
+}
+
+// SaveAuthCheck stores the result of a NIP-42 authentication check.
+func (r *relayRepository) SaveAuthCheck(ctx context.Context, check domain.AuthCheck) error {
+	query := `
+		INSERT INTO auth_checks (
+			relay_url,
+			created_at,
+			success,
+			error,
+			challenge_received,
+			authenticated,
+			read_auth_required,
+			write_auth_required,
+			nip11_auth_required,
+			nip11_mismatch
+		)
+		VALUES (
+			:relay_url,
+			:created_at,
+			:success,
+			:error,
+			:challenge_received,
+			:authenticated,
+			:read_auth_required,
+			:write_auth_required,
+			:nip11_auth_required,
+			:nip11_mismatch
+		)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, check); err != nil {
+		return fmt.Errorf("failed to save auth check: %w", err)
+	}
+
+	return nil
+}
+
+// FindLatestAuthCheck returns the most recent NIP-42 authentication check of the given relay.
+// It returns sql.ErrNoRows when the relay has never been checked.
+func (r *relayRepository) FindLatestAuthCheck(ctx context.Context, url string) (domain.AuthCheck, error) {
+	var check domain.AuthCheck
+
+	if err := r.db.GetContext(ctx, &check, `
+		SELECT
+			relay_url,
+			created_at,
+			success,
+			error,
+			challenge_received,
+			authenticated,
+			read_auth_required,
+			write_auth_required,
+			nip11_auth_required,
+			nip11_mismatch
+		FROM auth_checks
+		WHERE relay_url = $1
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		url,
+	); err != nil {
+		return domain.AuthCheck{}, fmt.Errorf("failed to get auth check of %s: %w", url, err)
+	}
+
+	return check, nil
+}
+
+// SaveNIPChecks stores the results of the NIP conformance probes of a check run.
+func (r *relayRepository) SaveNIPChecks(ctx context.Context, checks []domain.NIPCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO nip_checks (
+			relay_url,
+			created_at,
+			nip,
+			result,
+			detail
+		)
+		VALUES (
+			:relay_url,
+			:created_at,
+			:nip,
+			:result,
+			:detail
+		)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, checks); err != nil {
+		return fmt.Errorf("failed to save nip checks: %w", err)
+	}
+
+	return nil
+}
+
+// ListLatestNIPChecks returns the latest conformance probe result of every NIP probed on the given relay, ordered by NIP.
+func (r *relayRepository) ListLatestNIPChecks(ctx context.Context, url string) ([]domain.NIPCheck, error) {
+	var checks []domain.NIPCheck
+
+	if err := r.db.SelectContext(ctx, &checks, `
+		SELECT DISTINCT ON (nip) relay_url, created_at, nip, result, detail
+		FROM nip_checks
+		WHERE relay_url = $1
+		ORDER BY nip, created_at DESC`,
+		url,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get nip checks of %s: %w", url, err)
+	}
+
+	return checks, nil
+}
+
+// SavePublishedEvents stores the outcome of sending an event to each of the publish targets.
+func (r *relayRepository) SavePublishedEvents(ctx context.Context, events []domain.PublishedEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO published_events (
+			event_id,
+			kind,
+			relay_url,
+			status,
+			error,
+			attempts,
+			created_at
+		)
+		VALUES (
+			:event_id,
+			:kind,
+			:relay_url,
+			:status,
+			:error,
+			:attempts,
+			:created_at
+		)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, events); err != nil {
+		return fmt.Errorf("failed to save published events: %w", err)
+	}
+
+	return nil
+}
+
+// SaveOutboxEvent stores a signed event waiting to be delivered, an event already in the outbox is left as it is.
+func (r *relayRepository) SaveOutboxEvent(ctx context.Context, event domain.OutboxEvent) error {
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO outbox_events (event_id, kind, relay_url, event, created_at)
+		VALUES ($1, $2, $3, $4, COALESCE($5, CURRENT_TIMESTAMP))
+		ON CONFLICT (event_id) DO NOTHING`,
+		event.EventID,
+		event.Kind,
+		event.RelayURL,
+		string(event.Event),
+		event.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to save outbox event %s: %w", event.EventID, err)
+	}
+
+	return nil
+}
+
+// FindOutboxEvent returns the event of the outbox with the given id.
+// It wraps sql.ErrNoRows when there's no such event.
+func (r *relayRepository) FindOutboxEvent(ctx context.Context, eventID string) (domain.OutboxEvent, error) {
+	var event domain.OutboxEvent
+
+	if err := r.db.GetContext(ctx, &event, `
+		SELECT event_id, kind, relay_url, event, status, attempts, last_error, created_at, published_at
+		FROM outbox_events
+		WHERE event_id = $1`,
+		eventID,
+	); err != nil {
+		return domain.OutboxEvent{}, fmt.Errorf("failed to get outbox event %s: %w", eventID, err)
+	}
+
+	return event, nil
+}
+
+// FindLatestOutboxEvent returns the most recent event of the given kind about a relay, delivered or still pending.
+// Events given up on, failed or expired, never reached relays and are skipped.
+// It wraps sql.ErrNoRows when there's no such event.
+func (r *relayRepository) FindLatestOutboxEvent(
+	ctx context.Context,
+	relayURL string,
+	kind int,
+) (domain.OutboxEvent, error) {
+	var event domain.OutboxEvent
+
+	if err := r.db.GetContext(ctx, &event, `
+		SELECT event_id, kind, relay_url, event, status, attempts, last_error, created_at, published_at
+		FROM outbox_events
+		WHERE relay_url = $1 AND kind = $2 AND status IN ('pending', 'published')
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`,
+		relayURL, kind,
+	); err != nil {
+		return domain.OutboxEvent{}, fmt.Errorf("failed to get latest outbox event about %s: %w", relayURL, err)
+	}
+
+	return event, nil
+}
+
+// ListUnmonitoredRelaysWithEvents returns the relays the monitor stored 30166 events about but no longer monitors,
+// because they were removed, disabled or merged into another relay, and whose events weren't retracted since.
+func (r *relayRepository) ListUnmonitoredRelaysWithEvents(ctx context.Context) ([]string, error) {
+	var urls []string
+
+	if err := r.db.SelectContext(ctx, &urls, `
+		SELECT o.relay_url
+		FROM outbox_events o
+		LEFT JOIN relays r ON r.url = o.relay_url
+		WHERE o.kind = 30166 AND (r.url IS NULL OR r.status IN ($1, $2))
+		GROUP BY o.relay_url
+		HAVING MAX(o.created_at) > COALESCE(
+			(SELECT MAX(d.created_at) FROM outbox_events d WHERE d.kind = 5 AND d.relay_url = o.relay_url),
+			'-infinity'
+		)
+		ORDER BY o.relay_url`,
+		domain.RelayStatusDisabled, domain.RelayStatusRemoved,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get unmonitored relays: %w", err)
+	}
+
+	return urls, nil
+}
+
+// ListPendingOutboxEvents returns the events not delivered yet that were stored between from and to, oldest first.
+// Events given up on are left out.
+func (r *relayRepository) ListPendingOutboxEvents(
+	ctx context.Context,
+	from, to time.Time,
+) ([]domain.OutboxEvent, error) {
+	var events []domain.OutboxEvent
+
+	if err := r.db.SelectContext(ctx, &events, `
+		SELECT event_id, kind, relay_url, event, status, attempts, last_error, created_at, published_at
+		FROM outbox_events
+		WHERE status = 'pending' AND attempts < $3 AND created_at >= $1 AND created_at < $2
+		ORDER BY created_at, id`,
+		from, to, domain.OutboxMaxAttempts,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get pending outbox events: %w", err)
+	}
+
+	return events, nil
+}
+
+// MarkOutboxEventPublished records the delivery of an event of the outbox.
+func (r *relayRepository) MarkOutboxEventPublished(ctx context.Context, eventID string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET status = 'published', attempts = attempts + 1, last_error = NULL, published_at = CURRENT_TIMESTAMP
+		WHERE event_id = $1`,
+		eventID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event %s as published: %w", eventID, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("outbox event %s not found: %w", eventID, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// MarkOutboxEventFailed records a failed delivery attempt of an event of the outbox, which stays pending
+// until domain.OutboxMaxAttempts attempts failed. Events delivered in the meantime are left as they are.
+func (r *relayRepository) MarkOutboxEventFailed(ctx context.Context, eventID, reason string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET
+			attempts = attempts + 1,
+			last_error = $2,
+			status = CASE WHEN attempts + 1 >= $3 THEN 'failed' ELSE status END
+		WHERE event_id = $1 AND status = 'pending'`,
+		eventID, reason, domain.OutboxMaxAttempts,
+	); err != nil {
+		return fmt.Errorf("failed to record failed delivery of outbox event %s: %w", eventID, err)
+	}
+
+	return nil
+}
+
+// MarkOutboxEventExpired gives up on an event of the outbox that expired before being delivered.
+// Events delivered in the meantime are left as they are.
+func (r *relayRepository) MarkOutboxEventExpired(ctx context.Context, eventID string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET status = 'expired'
+		WHERE event_id = $1 AND status = 'pending'`,
+		eventID,
+	); err != nil {
+		return fmt.Errorf("failed to mark outbox event %s as expired: %w", eventID, err)
+	}
+
+	return nil
+}
+
+// SaveDocument stores the NIP-11 document of a relay as its next version,
+// unless it has the same content hash as the latest version.
+// It tells whether a new version was stored.
+func (r *relayRepository) SaveDocument(ctx context.Context, document domain.RelayDocument) (bool, error) {
+	// A document changing back to a previous version is a change too, only the latest version is compared.
+	res, err := r.db.ExecContext(ctx, `
+		WITH latest AS (
+			SELECT version, content_hash FROM relay_documents
+			WHERE relay_url = $1
+			ORDER BY version DESC
+			LIMIT 1
+		)
+		INSERT INTO relay_documents (relay_url, version, content_hash, document, created_at)
+		SELECT $1, COALESCE((SELECT version FROM latest), 0) + 1, $2, $3, $4
+		WHERE NOT EXISTS (SELECT 1 FROM latest WHERE content_hash = $2)
+		ON CONFLICT (relay_url, version) DO NOTHING`,
+		document.RelayURL,
+		document.ContentHash,
+		string(document.Document),
+		document.CreatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to save document of relay %s: %w", document.RelayURL, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check saved document of relay %s: %w", document.RelayURL, err)
+	}
+
+	return n > 0, nil
+}
+
+// ListDocuments returns the NIP-11 document versions of the given relay, most recent first.
+func (r *relayRepository) ListDocuments(
+	ctx context.Context,
+	url string,
+	opts *repository.DocumentListOption,
+) ([]domain.RelayDocument, error) {
+	var documents []domain.RelayDocument
+
+	query := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select(
+		"relay_url",
+		"version",
+		"content_hash",
+		"document",
+		"created_at",
+	).
+		From("relay_documents").
+		Where(sq.Eq{"relay_url": url}).
+		OrderBy("version DESC")
+
+	if opts != nil {
+		if opts.Limit != nil {
+			query = query.Limit(uint64(*opts.Limit))
+		}
+		if opts.Offset != nil {
+			query = query.Offset(uint64(*opts.Offset))
+		}
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if err := r.db.SelectContext(ctx, &documents, sql, args...); err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+
+	return documents, nil
+}
+
+// SaveDNSCheck stores the result of a DNS resolution check.
+func (r *relayRepository) SaveDNSCheck(ctx context.Context, check domain.DNSCheck) error {
+	query := `
+		INSERT INTO dns_checks (
+			relay_url,
+			created_at,
+			success,
+			error,
+			rtt_dns,
+			ipv4,
+			ipv6,
+			ips,
+			country,
+			geohash
+		)
+		VALUES (
+			:relay_url,
+			:created_at,
+			:success,
+			:error,
+			:rtt_dns,
+			:ipv4,
+			:ipv6,
+			:ips,
+			:country,
+			:geohash
+		)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, check); err != nil {
+		return fmt.Errorf("failed to save dns check: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateObservedLocation stores where the address of the relay was found to be located.
+func (r *relayRepository) UpdateObservedLocation(ctx context.Context, url, country, geohash string) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		"UPDATE relays SET observed_country = $1, observed_geohash = $2 WHERE url = $3",
+		nullIfEmpty(country), nullIfEmpty(geohash), url,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update observed location of relay %s: %w", url, err)
+	}
+
+	return expectAffected(res, url)
+}
+
+// ListFacets returns the distinct NIPs, countries, languages and software declared by relays,
+// along with how many relays declare each of them. Removed relays are left out.
+func (r *relayRepository) ListFacets(ctx context.Context) (domain.RelayFacets, error) {
+	var facets domain.RelayFacets
+
+	queries := []struct {
+		dest  *[]domain.Facet
+		query string
+	}{
+		{
+			dest: &facets.NIPs,
+			query: `SELECT nip::TEXT AS value, COUNT(*) AS count
+				FROM relays, UNNEST(supported_nips) AS nip
+				WHERE status <> 'removed'
+				GROUP BY nip
+				ORDER BY nip`,
+		},
+		{
+			dest: &facets.Countries,
+			query: `SELECT UPPER(country) AS value, COUNT(*) AS count
+				FROM relays, UNNEST(relay_countries) AS country
+				WHERE status <> 'removed' AND country <> ''
+				GROUP BY UPPER(country)
+				ORDER BY count DESC, value`,
+		},
+		{
+			dest: &facets.Languages,
+			query: `SELECT lang AS value, COUNT(*) AS count
+				FROM relays, UNNEST(language_tags) AS lang
+				WHERE status <> 'removed' AND lang <> ''
+				GROUP BY lang
+				ORDER BY count DESC, value`,
+		},
+		{
+			dest: &facets.Software,
+			query: `SELECT software AS value, COUNT(*) AS count
+				FROM relays
+				WHERE status <> 'removed' AND software IS NOT NULL AND software <> ''
+				GROUP BY software
+				ORDER BY count DESC, value`,
+		},
+	}
+
+	for _, q := range queries {
+		if err := r.db.SelectContext(ctx, q.dest, q.query); err != nil {
+			return domain.RelayFacets{}, fmt.Errorf("failed to get relay facets: %w", err)
+		}
+	}
+
+	return facets, nil
+}
+
+// statsColumns are the aggregations used to compute the uptime and latency of relays.
+var statsColumns = []string{
+	"relay_url",
+	"MIN(created_at) AS first_check_at",
+	"COUNT(*) AS total_checks",
+	"COUNT(*) FILTER (WHERE NOT websocket_success) AS failed_checks",
+	"100.0 * COUNT(*) FILTER (WHERE websocket_success) / COUNT(*) AS uptime_percent",
+	"ROUND(AVG(rtt_open))::INTEGER AS avg_rtt_open",
+	"ROUND(AVG(rtt_read))::INTEGER AS avg_rtt_read",
+	"ROUND(AVG(rtt_write))::INTEGER AS avg_rtt_write",
+	"ROUND(AVG(rtt_nip11))::INTEGER AS avg_rtt_nip11",
+}
+
+// GetStats returns the uptime and latency of the given relay computed from the health checks since the given time.
+func (r *relayRepository) GetStats(
+	ctx context.Context,
+	url string,
+	since time.Time,
+) (domain.RelayStats, error) {
+	stats, err := r.ListStats(ctx, []string{url}, since)
+	if err != nil {
+		return domain.RelayStats{}, err
+	}
+
+	// A relay without checks in the window has no stats, return them empty.
+	if len(stats) == 0 {
+		return domain.RelayStats{RelayURL: url}, nil
+	}
+
+	return stats[0], nil
+}
+
+// ListStats returns the uptime and latency of the given relays computed from the health checks since the given time.
+// Relays without checks in the window are not part of the result.
+func (r *relayRepository) ListStats(
+	ctx context.Context,
+	urls []string,
+	since time.Time,
+) ([]domain.RelayStats, error) {
+	var stats []domain.RelayStats
+
+	if len(urls) == 0 {
+		return stats, nil
+	}
+
+	query := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Select(statsColumns...).
+		From("health_checks").
+		Where(sq.Eq{"relay_url": urls}).
+		Where(sq.GtOrEq{"created_at": since}).
+		GroupBy("relay_url")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if err := r.db.SelectContext(ctx, &stats, sql, args...); err != nil {
+		return nil, fmt.Errorf("failed to get relay stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ListSoftwareStats returns the number of relays reporting each software and version,
+// along with their health checks since the given time. Retired and removed relays are left out.
+func (r *relayRepository) ListSoftwareStats(ctx context.Context, since time.Time) ([]domain.SoftwareStats, error) {
+	var stats []domain.SoftwareStats
+
+	if err := r.db.SelectContext(ctx, &stats, `
+		SELECT
+			r.software,
+			COALESCE(r.version, '') AS version,
+			COUNT(DISTINCT r.url) AS relays,
+			COUNT(h.relay_url) AS total_checks,
+			COUNT(h.relay_url) FILTER (WHERE h.websocket_success) AS successful_checks,
+			COALESCE(SUM(h.rtt_open), 0) AS rtt_open_sum,
+			COUNT(h.rtt_open) AS rtt_open_count
+		FROM relays r
+		LEFT JOIN health_checks h ON h.relay_url = r.url AND h.created_at >= $1
+		WHERE r.software IS NOT NULL AND r.software <> '' AND r.status NOT IN ($2, $3)
+		GROUP BY r.software, COALESCE(r.version, '')
+		ORDER BY relays DESC, r.software, version`,
+		since, domain.RelayStatusRetired, domain.RelayStatusRemoved,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get software stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ListNIPSupport returns how many relays currently claim support for each NIP, ordered by NIP.
+// Retired and removed relays are left out.
+func (r *relayRepository) ListNIPSupport(ctx context.Context) ([]domain.NIPSupport, error) {
+	var support []domain.NIPSupport
+
+	if err := r.db.SelectContext(ctx, &support, `
+		WITH claimed AS (
+			SELECT url, supported_nips FROM relays
+			WHERE status NOT IN ($1, $2) AND supported_nips IS NOT NULL
+		)
+		SELECT
+			NOW() AS day,
+			nip::INTEGER AS nip,
+			COUNT(DISTINCT url) AS relays,
+			(SELECT COUNT(*) FROM claimed) AS total
+		FROM claimed, UNNEST(supported_nips) AS nip
+		GROUP BY nip
+		ORDER BY nip`,
+		domain.RelayStatusRetired, domain.RelayStatusRemoved,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get nip support: %w", err)
+	}
+
+	return support, nil
+}
+
+// ListNIPSupportHistory returns how many relays claimed support for each NIP every step since the given time,
+// according to the latest version of their NIP-11 document at that time. Ordered by time, then by NIP.
+// Times before any document was stored are left out. At each step, only the relays checked during that step count,
+// so retiring a relay today doesn't rewrite the past.
+func (r *relayRepository) ListNIPSupportHistory(
+	ctx context.Context,
+	since time.Time,
+	step time.Duration,
+) ([]domain.NIPSupport, error) {
+	var support []domain.NIPSupport
+
+	if err := r.db.SelectContext(ctx, &support, `
+		WITH days AS (
+			SELECT generate_series($1::TIMESTAMPTZ, NOW(), make_interval(secs => $2)) AS day
+		),
+		snapshots AS (
+			SELECT DISTINCT ON (d.day, rd.relay_url) d.day, rd.relay_url, rd.document
+			FROM days d
+			JOIN relay_documents rd ON rd.created_at <= d.day
+			WHERE EXISTS (
+				SELECT 1 FROM health_checks hc
+				WHERE hc.relay_url = rd.relay_url
+				AND hc.created_at > d.day - make_interval(secs => $2)
+				AND hc.created_at <= d.day
+			)
+			ORDER BY d.day, rd.relay_url, rd.version DESC
+		),
+		totals AS (
+			SELECT day, COUNT(*) AS total FROM snapshots GROUP BY day
+		)
+		SELECT s.day, nip::INTEGER AS nip, COUNT(DISTINCT s.relay_url) AS relays, t.total
+		FROM snapshots s
+		JOIN totals t ON t.day = s.day
+		CROSS JOIN LATERAL jsonb_array_elements_text(
+			CASE WHEN jsonb_typeof(s.document->'supported_nips') = 'array'
+				THEN s.document->'supported_nips'
+				ELSE '[]'::JSONB
+			END
+		) AS nip
+		WHERE nip ~ '^[0-9]{1,5}$'
+		GROUP BY s.day, nip, t.total
+		ORDER BY s.day, nip::INTEGER`,
+		since, step.Seconds(),
+	); err != nil {
+		return nil, fmt.Errorf("failed to get nip support history: %w", err)
+	}
+
+	return support, nil
+}
+
+// MergeRelays folds the duplicates of a relay, their health, ssl, dns, auth and nip checks and their NIP-11 document history,
+// into the relay stored at into. When into doesn't exist yet, the most recently updated duplicate is renamed to it.
+func (r *relayRepository) MergeRelays(ctx context.Context, into string, duplicates []string) error {
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	return r.inTx(ctx, func(tx *relayRepository) error {
+		// Health, ssl and dns checks follow the renamed relay thanks to ON UPDATE CASCADE, and so does its document history.
+		if _, err := tx.db.ExecContext(ctx, `
+			UPDATE relays SET url = $1
+			WHERE url = (
+				SELECT url FROM relays
+				WHERE url = ANY($2)
+				ORDER BY updated_at DESC NULLS LAST, url
+				LIMIT 1
+			)
+			AND NOT EXISTS (SELECT 1 FROM relays WHERE url = $1)`,
+			into, pq.Array(duplicates),
+		); err != nil {
+			return fmt.Errorf("failed to rename relay to %s: %w", into, err)
+		}
+
+		if _, err := tx.db.ExecContext(
+			ctx,
+			"UPDATE health_checks SET relay_url = $1 WHERE relay_url = ANY($2)",
+			into, pq.Array(duplicates),
+		); err != nil {
+			return fmt.Errorf("failed to move health checks to %s: %w", into, err)
+		}
+
+		if _, err := tx.db.ExecContext(
+			ctx,
+			"UPDATE ssl_checks SET relay_url = $1 WHERE relay_url = ANY($2)",
+			into, pq.Array(duplicates),
+		); err != nil {
+			return fmt.Errorf("failed to move ssl checks to %s: %w", into, err)
+		}
+
+		if _, err := tx.db.ExecContext(
+			ctx,
+			"UPDATE dns_checks SET relay_url = $1 WHERE relay_url = ANY($2)",
+			into, pq.Array(duplicates),
+		); err != nil {
+			return fmt.Errorf("failed to move dns checks to %s: %w", into, err)
+		}
+
+		if _, err := tx.db.ExecContext(
+			ctx,
+			"UPDATE auth_checks SET relay_url = $1 WHERE relay_url = ANY($2)",
+			into, pq.Array(duplicates),
+		); err != nil {
+			return fmt.Errorf("failed to move auth checks to %s: %w", into, err)
+		}
+
+		if _, err := tx.db.ExecContext(
+			ctx,
+			"UPDATE nip_checks SET relay_url = $1 WHERE relay_url = ANY($2)",
+			into, pq.Array(duplicates),
+		); err != nil {
+			return fmt.Errorf("failed to move nip checks to %s: %w", into, err)
+		}
+
+		// The document histories are interleaved by the time each version was seen, and renumbered.
+		// Versions are made negative first, so none collides with another while they are renumbered.
+		if _, err := tx.db.ExecContext(ctx, `
+			UPDATE relay_documents rd SET relay_url = $1, version = -n.rn
+			FROM (
+				SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS rn
+				FROM relay_documents
+				WHERE relay_url = $1 OR relay_url = ANY($2)
+			) n
+			WHERE rd.id = n.id`,
+			into, pq.Array(duplicates),
+		); err != nil {
+			return fmt.Errorf("failed to move document history to %s: %w", into, err)
+		}
+
+		// A version the same as the one before it, seen on another spelling of the URL, is not a change.
+		if _, err := tx.db.ExecContext(ctx, `
+			DELETE FROM relay_documents
+			WHERE id IN (
+				SELECT id FROM (
+					SELECT id, content_hash, LAG(content_hash) OVER (ORDER BY version DESC) AS previous_hash
+					FROM relay_documents
+					WHERE relay_url = $1 AND version < 0
+				) d
+				WHERE content_hash = previous_hash
+			)`,
+			into,
+		); err != nil {
+			return fmt.Errorf("failed to collapse document history of %s: %w", into, err)
+		}
+
+		if _, err := tx.db.ExecContext(ctx, `
+			UPDATE relay_documents rd SET version = n.rn
+			FROM (
+				SELECT id, ROW_NUMBER() OVER (ORDER BY version DESC) AS rn
+				FROM relay_documents
+				WHERE relay_url = $1 AND version < 0
+			) n
+			WHERE rd.id = n.id`,
+			into,
+		); err != nil {
+			return fmt.Errorf("failed to renumber document history of %s: %w", into, err)
+		}
+
+		if _, err := tx.db.ExecContext(
+			ctx,
+			"DELETE FROM relays WHERE url = ANY($1) AND url <> $2",
+			pq.Array(duplicates), into,
+		); err != nil {
+			return fmt.Errorf("failed to delete duplicates of %s: %w", into, err)
+		}
+
+		return nil
+	})
+}
+
+// SetStatus moves a relay to the given lifecycle status.
+func (r *relayRepository) SetStatus(ctx context.Context, url string, status string) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		"UPDATE relays SET status = $1 WHERE url = $2",
+		status, url,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set status of relay %s: %w", url, err)
+	}
+
+	return expectAffected(res, url)
+}
+
+// RetireDeadRelays retires the active relays that have been checked since deadSince without a single success.
+// Relays added after deadSince are left alone, they haven't been monitored long enough.
+func (r *relayRepository) RetireDeadRelays(ctx context.Context, deadSince time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE relays r SET status = 'retired'
+		WHERE r.status = 'active'
+		AND r.created_at < $1
+		AND EXISTS (
+			SELECT 1 FROM health_checks h
+			WHERE h.relay_url = r.url AND h.created_at >= $1
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM health_checks h
+			WHERE h.relay_url = r.url AND h.created_at >= $1 AND h.websocket_success
+		)`,
+		deadSince,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retire dead relays: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// ReviveRetiredRelays moves back to active the retired relays whose latest check succeeded.
+func (r *relayRepository) ReviveRetiredRelays(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE relays r SET status = 'active'
+		WHERE r.status = 'retired'
+		AND (
+			SELECT h.websocket_success FROM health_checks h
+			WHERE h.relay_url = r.url
+			ORDER BY h.created_at DESC
+			LIMIT 1
+		)`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revive retired relays: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// expectAffected returns sql.ErrNoRows when a statement targeting the given relay didn't touch any row.
+func expectAffected(res sql.Result, url string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("relay %s not found: %w", url, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// nullIfEmpty stores empty strings as NULL.
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
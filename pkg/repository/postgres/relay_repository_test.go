@@ -35,11 +35,41 @@ LIST METHOD TESTS:
   - Scenario: Multiple relays, filter by subset of URLs
   - Expected: Only relays matching the URL filter returned
 
-7. TestList_ComplexDataTypes
+7. TestList_WithAttributeFilters
+  - Purpose: Test filtering by online status, NIP, software and country
+  - Scenario: Relays with different health, NIPs, software and countries
+  - Expected: Only relays matching every filter returned
+
+8. TestList_WithSearchAndClassification
+  - Purpose: Test free text search and classification filters
+  - Scenario: Relays with different names, descriptions and tags
+  - Expected: Only relays matching the search text and class returned
+
+9. TestList_SortByName
+  - Purpose: Test deterministic sorting by a column in both directions
+  - Scenario: Multiple relays sorted by name ascending and descending
+  - Expected: Relays returned in alphabetical and reverse order
+
+10. TestList_ComplexDataTypes
   - Purpose: Test PostgreSQL arrays and custom data types
   - Scenario: Relay with supported_nips array, tags, etc.
   - Expected: Arrays properly serialized/deserialized from database
 
+11. TestList_KeysetPagination
+  - Purpose: Test cursor pagination over ties and NULL sort keys
+  - Scenario: Relays with equal RTTs and relays never checked, walked page by page
+  - Expected: Every relay returned exactly once, cursors from another sort rejected
+
+12. TestList_WithNetworkFilter
+  - Purpose: Test filtering relays by the network they are on
+  - Scenario: Clearnet, Tor and I2P relays
+  - Expected: Only relays whose host is on the requested network returned
+
+13. TestList_FullTextSearch
+  - Purpose: Test full-text search matching and relevance ranking
+  - Scenario: Relays matching a word in their name, description or URL, walked page by page by relevance
+  - Expected: Whole words matched, name matches ranked above description and URL matches
+
 FINDBYURL METHOD TESTS:
 ======================
 1. TestFindByURL_ExistingRelay
@@ -63,6 +93,182 @@ FINDBYURL METHOD TESTS:
   - Scenario: Relay with multiple health checks at different timestamps
   - Expected: Relay with most recent health check data
 
+5. TestFindByURL_AllRTTs
+  - Purpose: Test every round trip time of the latest health check is returned
+  - Scenario: Health check saved with open, read, write and NIP-11 round trips
+  - Expected: FindByURL and List return the four of them
+
+LISTHEALTHCHECKS METHOD TESTS:
+=============================
+1. TestListHealthChecks_TimeRange
+  - Purpose: Test retrieval of the health checks of a relay within a time range
+  - Scenario: Relay with checks inside and outside the range, plus another relay
+  - Expected: Only the relay's checks inside the range, most recent first
+
+2. TestListHealthChecks_Pagination
+  - Purpose: Test limit/offset over the health history
+  - Scenario: Relay with several checks, limit and offset parameters
+  - Expected: Correct page of checks
+
+STATS METHODS TESTS:
+===================
+1. TestGetStats_UptimeAndAverages
+  - Purpose: Test uptime and latency aggregation over a time window
+  - Scenario: Relay with successful and failed checks inside and outside the window
+  - Expected: Only checks inside the window are aggregated
+
+2. TestGetStats_NoChecks
+  - Purpose: Test stats of a relay without checks in the window
+  - Scenario: Relay exists but has no health check records
+  - Expected: Zero valued stats, no error
+
+3. TestListStats_MultipleRelays
+  - Purpose: Test stats aggregation grouped by relay
+  - Scenario: Several relays with checks, filter by subset of URLs
+  - Expected: One stats row per requested relay with checks
+
+4. TestListSoftwareStats_GroupsBySoftwareAndVersion
+  - Purpose: Test relays and their checks are aggregated by reported software and version
+  - Scenario: Relays on two versions of a software, relay without checks, retired relay
+  - Expected: One row per software and version with relay, check and RTT totals, retired relay left out
+
+5. TestListNIPSupport_CurrentAndHistory
+  - Purpose: Test NIP support counts from relays and from their NIP-11 document history
+  - Scenario: Relays claiming different NIPs, a retired relay, a document changing its supported NIPs
+  - Expected: Current counts of non retired relays, history of the relays checked at each point following
+    their latest document then
+
+CREATE AND MERGE METHODS TESTS:
+==============================
+1. TestCreate_NormalizesURL
+  - Purpose: Test relays are stored under their canonical URL
+  - Scenario: Create with uppercase host and trailing slash, again with the canonical URL, then with a non-ws scheme
+  - Expected: Canonical URL stored once, reported as inserted the first time only, invalid scheme rejected
+
+2. TestMergeRelays_IntoExistingRelay
+  - Purpose: Test duplicates are folded into the canonical relay
+  - Scenario: Canonical relay and a duplicate, both with health checks and NIP-11 document versions
+  - Expected: Duplicate deleted, its health checks and documents moved to the canonical relay, versions renumbered by time
+    and consecutive identical versions collapsed
+
+3. TestMergeRelays_RenamesWhenCanonicalMissing
+  - Purpose: Test a duplicate is renamed when the canonical relay doesn't exist
+  - Scenario: Two spellings of a relay, none canonical
+  - Expected: Most recently updated one renamed, all health checks kept
+
+UPDATE METHOD TESTS:
+===================
+1. TestUpdate_StoresFullNIP11Document
+  - Purpose: Test the whole NIP-11 document is persisted and filterable by requirement
+  - Scenario: Relay updated with limitation, fees, retention, payments URL, long tags and languages
+  - Expected: Every field returned by FindByURL, relays listed by their declared requirements
+
+SSL CHECKS METHODS TESTS:
+========================
+1. TestSSLChecks_LatestCheck
+  - Purpose: Test TLS certificate checks are stored and the latest one returned
+  - Scenario: Relay with an old and a recent ssl check, relay never checked
+  - Expected: Most recent check with its certificate details, sql.ErrNoRows for the other relay
+
+2. TestMergeRelays_MovesSSLChecks
+  - Purpose: Test ssl checks of duplicates are folded into the canonical relay
+  - Scenario: Duplicate relay with an ssl check merged into the canonical one
+  - Expected: The ssl check found under the canonical URL
+
+DNS CHECKS METHODS TESTS:
+========================
+1. TestDNSChecks_ObservedLocation
+  - Purpose: Test DNS checks are stored and the observed location kept on the relay
+  - Scenario: DNS check with a geolocated address, location update of an unknown relay
+  - Expected: Observed country and geohash returned with the relay, sql.ErrNoRows for the unknown one
+
+AUTH CHECKS METHODS TESTS:
+=========================
+1. TestAuthChecks_SavedAndMerged
+  - Purpose: Test NIP-42 auth checks are stored and follow merged relays
+  - Scenario: Duplicate relay with an auth check contradicting NIP-11 merged into the canonical one
+  - Expected: The auth check found under the canonical URL with its flags
+
+2. TestAuthChecks_FindLatest
+  - Purpose: Test only the most recent auth check of a relay is returned
+  - Scenario: Relay checked twice, gated at first then open, relay never checked
+  - Expected: The later check with its NIP-11 comparison, sql.ErrNoRows for the unchecked relay
+
+NIP CHECKS METHODS TESTS:
+========================
+1. TestNIPChecks_LatestPerNIP
+  - Purpose: Test NIP conformance results are stored and only the latest one per NIP returned
+  - Scenario: Duplicate relay probed twice merged into the canonical one, relay never probed
+  - Expected: One result per NIP under the canonical URL, ordered by NIP, empty for the other relay
+
+PUBLISHED EVENTS METHODS TESTS:
+==============================
+1. TestPublishedEvents_Saved
+  - Purpose: Test the outcome of publishing an event on every target is stored
+  - Scenario: Event accepted by a monitored relay and refused by a relay that is not monitored
+  - Expected: One row per target with its status, error and attempts
+
+OUTBOX METHODS TESTS:
+====================
+1. TestOutboxEvents_Lifecycle
+  - Purpose: Test events are stored once, listed while pending and marked as delivered
+  - Scenario: Event stored twice, failed delivery, successful delivery, event out of the window
+  - Expected: Attempts and last error recorded, published events no longer listed
+
+2. TestWithTx_RollsBack
+  - Purpose: Test the queries run through WithTx are rolled back when it fails
+  - Scenario: Health check and outbox event stored before an error, then committed
+  - Expected: Nothing stored after the error, everything stored after the commit
+
+3. TestOutboxEvents_LatestAboutRelay
+  - Purpose: Test the most recent event of a kind about a relay is found, delivered or still pending
+  - Scenario: Several 30166 events about two relays, a monitor announcement, an expired event, unknown relay
+  - Expected: The latest 30166 event of the relay not given up on, sql.ErrNoRows for the unknown relay
+
+4. TestOutboxEvents_UnmonitoredRelays
+  - Purpose: Test relays with events that are no longer monitored are found until retracted
+  - Scenario: Active, retired, disabled, removed and merged relays with events, a retracted relay, a relay checked again
+  - Expected: Only the disabled, removed and merged relays whose latest event is newer than their retraction
+
+5. TestOutboxEvents_GivenUpAfterMaxAttempts
+  - Purpose: Test an event no target accepts is given up on instead of staying pending
+  - Scenario: Event failing domain.OutboxMaxAttempts times, then delivered late
+  - Expected: Listed until its last attempt, failed and no longer listed afterwards
+
+6. TestOutboxEvents_Expired
+  - Purpose: Test an event expired before being delivered is given up on
+  - Scenario: Pending event marked as expired, delivered event marked as expired
+  - Expected: The pending event expired and no longer listed, the delivered one untouched
+
+DOCUMENT HISTORY METHODS TESTS:
+==============================
+1. TestDocuments_OnlyChangesCreateVersions
+  - Purpose: Test NIP-11 documents are versioned by content hash
+  - Scenario: Same document saved twice, then a new one, then the first one again, paginated listing
+  - Expected: Three versions numbered in order, most recent first, duplicates ignored
+
+LIFECYCLE METHODS TESTS:
+=======================
+1. TestSetStatus_FiltersList
+  - Purpose: Test status changes and the Statuses list filter
+  - Scenario: Relays disabled and enabled, list filtered by status, unknown relay
+  - Expected: Only relays in the requested statuses returned, sql.ErrNoRows for unknown relays
+
+2. TestSetStatus_RemovedKeepsHistory
+  - Purpose: Test removed relays are kept, with their history, but left out of the statistics
+  - Scenario: Relay claiming NIPs with health checks removed
+  - Expected: Relay and checks kept, relay listed only when asked for, left out of the NIP support
+
+3. TestRetireDeadRelays
+  - Purpose: Test active relays failing every check in the period are retired
+  - Scenario: Dead relay, relay with one success, new relay, relay never checked
+  - Expected: Only the dead relay monitored for long enough is retired
+
+4. TestReviveRetiredRelays
+  - Purpose: Test retired relays come back once their latest check succeeds
+  - Scenario: Retired relays with a successful and a failed latest check
+  - Expected: Only the relay whose latest check succeeded is active again
+
 TESTING APPROACH:
 ================
 - Uses testcontainers with PostgreSQL 15 for real database testing
@@ -84,8 +290,10 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -102,6 +310,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
 )
 
@@ -185,6 +394,13 @@ func (suite *RelayRepositoryTestSuite) runMigrations(dsn string) error {
 
 func (suite *RelayRepositoryTestSuite) cleanTables() {
 	// Clean in reverse order due to foreign keys
+	suite.db.MustExec("DELETE FROM relay_documents")
+	suite.db.MustExec("DELETE FROM nip_checks")
+	suite.db.MustExec("DELETE FROM published_events")
+	suite.db.MustExec("DELETE FROM outbox_events")
+	suite.db.MustExec("DELETE FROM auth_checks")
+	suite.db.MustExec("DELETE FROM dns_checks")
+	suite.db.MustExec("DELETE FROM ssl_checks")
 	suite.db.MustExec("DELETE FROM health_checks")
 	suite.db.MustExec("DELETE FROM relays")
 }
@@ -344,6 +560,160 @@ func (suite *RelayRepositoryTestSuite) TestList_WithURLFilter() {
 	assert.Len(suite.T(), relays, 2)
 }
 
+func (suite *RelayRepositoryTestSuite) TestList_WithAttributeFilters() {
+	suite.seedRelay("wss://relay1.example.com", "Relay 1")
+	suite.seedRelay("wss://relay2.example.com", "Relay 2")
+	suite.seedRelay("wss://relay3.example.com", "Relay 3")
+
+	suite.db.MustExec(`UPDATE relays SET supported_nips = '{1,11,50}', software = 'git+https://github.com/hoytech/strfry.git', relay_countries = '{US}' WHERE url = 'wss://relay1.example.com'`)
+
+	now := time.Now()
+	suite.seedHealthCheck("wss://relay1.example.com", now, true)
+	suite.seedHealthCheck("wss://relay2.example.com", now, false)
+
+	online := true
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{Online: &online})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://relay1.example.com", relays[0].URL)
+
+	// Offline includes the relays never checked.
+	offline := false
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Online: &offline})
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), relays, 2)
+
+	nip := 50
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{
+		NIP:      &nip,
+		Software: "STRFRY",
+		Country:  "us",
+	})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://relay1.example.com", relays[0].URL)
+
+	// Countries declared in lower case match too.
+	suite.db.MustExec(`UPDATE relays SET relay_countries = '{de}' WHERE url = 'wss://relay3.example.com'`)
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Country: "DE"})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://relay3.example.com", relays[0].URL)
+}
+
+func (suite *RelayRepositoryTestSuite) TestList_WithSearchAndClassification() {
+	suite.seedRelay("wss://damus.example.com", "Damus")
+	suite.seedRelay("wss://paid.example.com", "Paid Relay")
+	suite.seedRelay("wss://nos.example.com", "Nos")
+
+	suite.db.MustExec(`UPDATE relays SET tags = '{paid}' WHERE url = 'wss://paid.example.com'`)
+
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{Search: "DAMUS"})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://damus.example.com", relays[0].URL)
+
+	// The seeded descriptions are "Test relay <name>".
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Search: "test relay"})
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), relays, 3)
+
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Classification: "Paid"})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://paid.example.com", relays[0].URL)
+
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Classification: "Public"})
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), relays, 2)
+}
+
+func (suite *RelayRepositoryTestSuite) TestList_WithNetworkFilter() {
+	suite.seedRelay("wss://relay.example.com", "Clearnet")
+	suite.seedRelay("wss://onion.example.com", "Not Tor")
+	suite.seedRelay("ws://abcdef.onion", "Tor")
+	suite.seedRelay("ws://abcdef.onion:8080/inbox", "Tor Inbox")
+	suite.seedRelay("ws://relay.i2p", "I2P")
+
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{Network: relayurl.NetworkTor})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 2)
+	assert.Equal(suite.T(), "ws://abcdef.onion", relays[0].URL)
+	assert.Equal(suite.T(), "ws://abcdef.onion:8080/inbox", relays[1].URL)
+
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Network: relayurl.NetworkI2P})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "ws://relay.i2p", relays[0].URL)
+
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Network: relayurl.NetworkClearnet})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 2)
+	assert.Equal(suite.T(), "wss://onion.example.com", relays[0].URL)
+	assert.Equal(suite.T(), "wss://relay.example.com", relays[1].URL)
+}
+
+func (suite *RelayRepositoryTestSuite) TestList_FullTextSearch() {
+	suite.seedRelay("wss://relay.example.com", "Coffee")
+	suite.seedRelay("wss://brew.example.com", "Brew")
+	suite.seedRelay("wss://coffee.example.com", "Beans")
+	suite.seedRelay("wss://tea.example.com", "Tea")
+
+	suite.db.MustExec(`UPDATE relays SET description = 'For coffee lovers' WHERE url = 'wss://brew.example.com'`)
+
+	var urls []string
+	limit := 1
+	opts := &repository.ListOption{Search: "Coffee", Sort: repository.SortByRelevance, Limit: &limit}
+	for {
+		relays, err := suite.repo.List(suite.ctx, opts)
+		require.NoError(suite.T(), err)
+
+		for _, relay := range relays {
+			urls = append(urls, relay.URL)
+		}
+
+		if opts.Cursor = repository.NextCursor(relays, opts); opts.Cursor == "" {
+			break
+		}
+	}
+
+	assert.Equal(suite.T(), []string{
+		"wss://relay.example.com",
+		"wss://brew.example.com",
+		"wss://coffee.example.com",
+	}, urls)
+
+	// Every word has to match, whole.
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{Search: "coffee lovers"})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://brew.example.com", relays[0].URL)
+
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Search: "coff"})
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), relays)
+
+	_, err = suite.repo.List(suite.ctx, &repository.ListOption{Sort: repository.SortByRelevance})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RelayRepositoryTestSuite) TestList_SortByName() {
+	suite.seedRelay("wss://b.example.com", "Bravo")
+	suite.seedRelay("wss://a.example.com", "alpha")
+	suite.seedRelay("wss://c.example.com", "Charlie")
+
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{Sort: repository.SortByName})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 3)
+	assert.Equal(suite.T(), "alpha", *relays[0].Name)
+	assert.Equal(suite.T(), "Charlie", *relays[2].Name)
+
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Sort: repository.SortByName, Desc: true})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 3)
+	assert.Equal(suite.T(), "Charlie", *relays[0].Name)
+}
+
 func (suite *RelayRepositoryTestSuite) TestList_ComplexDataTypes() {
 	// Create relay with complex data types
 	namePtr := "Complex Relay"
@@ -367,6 +737,56 @@ func (suite *RelayRepositoryTestSuite) TestList_ComplexDataTypes() {
 	assert.Equal(suite.T(), pq.Int64Array{1, 2, 11, 42, 50}, relays[0].SupportedNIPs)
 }
 
+func (suite *RelayRepositoryTestSuite) TestList_KeysetPagination() {
+	urls := []string{
+		"wss://a.example.com",
+		"wss://b.example.com",
+		"wss://c.example.com",
+		"wss://d.example.com",
+		"wss://e.example.com",
+	}
+	for i, url := range urls {
+		suite.seedRelay(url, fmt.Sprintf("Relay %d", i))
+	}
+	// Same RTT for the first three, the rest were never checked and sort last.
+	for _, url := range urls[:3] {
+		suite.seedHealthCheck(url, time.Now(), true)
+	}
+
+	for _, desc := range []bool{false, true} {
+		limit := 2
+		opts := &repository.ListOption{Limit: &limit, Sort: repository.SortByRTT, Desc: desc}
+
+		var seen []string
+		for page := 0; page < 5; page++ {
+			relays, err := suite.repo.List(suite.ctx, opts)
+			require.NoError(suite.T(), err)
+
+			for _, r := range relays {
+				seen = append(seen, r.URL)
+			}
+
+			opts.Cursor = repository.NextCursor(relays, opts)
+			if opts.Cursor == "" {
+				break
+			}
+		}
+
+		assert.Equal(suite.T(), urls, seen)
+	}
+
+	limit := 2
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{Limit: &limit})
+	require.NoError(suite.T(), err)
+
+	_, err = suite.repo.List(suite.ctx, &repository.ListOption{
+		Limit:  &limit,
+		Sort:   repository.SortByName,
+		Cursor: repository.NextCursor(relays, &repository.ListOption{Limit: &limit}),
+	})
+	assert.ErrorIs(suite.T(), err, repository.ErrInvalidCursor)
+}
+
 // FindByURL method tests
 func (suite *RelayRepositoryTestSuite) TestFindByURL_ExistingRelay() {
 	// Seed relay and health check
@@ -418,6 +838,1065 @@ func (suite *RelayRepositoryTestSuite) TestFindByURL_LatestHealthCheck() {
 	assert.True(suite.T(), *relay.WebsocketSuccess) // Should get latest
 }
 
+func (suite *RelayRepositoryTestSuite) TestFindByURL_AllRTTs() {
+	suite.seedRelay("wss://test.example.com", "Test Relay")
+
+	now := time.Now()
+	err := suite.repo.SaveHealthCheck(suite.ctx, domain.HealthCheck{
+		RelayURL:         "wss://test.example.com",
+		CreatedAt:        &now,
+		WebsocketSuccess: &[]bool{true}[0],
+		RTTOpen:          &[]int{120}[0],
+		RTTRead:          &[]int{340}[0],
+		RTTWrite:         &[]int{560}[0],
+		RTTNIP11:         &[]int{80}[0],
+	})
+	require.NoError(suite.T(), err)
+
+	relay, err := suite.repo.FindByURL(suite.ctx, "wss://test.example.com")
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), relay.HealthCheck)
+	assert.Equal(suite.T(), 120, *relay.RTTOpen)
+	assert.Equal(suite.T(), 340, *relay.RTTRead)
+	assert.Equal(suite.T(), 560, *relay.RTTWrite)
+	assert.Equal(suite.T(), 80, *relay.RTTNIP11)
+
+	relays, err := suite.repo.List(suite.ctx, nil)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	require.NotNil(suite.T(), relays[0].HealthCheck)
+	assert.Equal(suite.T(), 340, *relays[0].RTTRead)
+	assert.Equal(suite.T(), 560, *relays[0].RTTWrite)
+}
+
+// ListHealthChecks method tests
+func (suite *RelayRepositoryTestSuite) TestListHealthChecks_TimeRange() {
+	suite.seedRelay("wss://test.example.com", "Test Relay")
+	suite.seedRelay("wss://other.example.com", "Other Relay")
+
+	now := time.Now()
+	suite.seedHealthCheck("wss://test.example.com", now.Add(-3*time.Hour), false)
+	suite.seedHealthCheck("wss://test.example.com", now.Add(-1*time.Hour), true)
+	suite.seedHealthCheck("wss://test.example.com", now.Add(-48*time.Hour), true) // Outside the range
+	suite.seedHealthCheck("wss://other.example.com", now.Add(-1*time.Hour), true)
+
+	since := now.Add(-24 * time.Hour)
+	checks, err := suite.repo.ListHealthChecks(
+		suite.ctx,
+		"wss://test.example.com",
+		&repository.HealthCheckListOption{Since: &since},
+	)
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), checks, 2)
+	assert.True(suite.T(), *checks[0].WebsocketSuccess)  // Most recent first
+	assert.False(suite.T(), *checks[1].WebsocketSuccess) // Older
+}
+
+func (suite *RelayRepositoryTestSuite) TestListHealthChecks_Pagination() {
+	suite.seedRelay("wss://test.example.com", "Test Relay")
+
+	now := time.Now()
+	for i := 1; i <= 5; i++ {
+		suite.seedHealthCheck("wss://test.example.com", now.Add(-time.Duration(i)*time.Hour), true)
+	}
+
+	limit := 2
+	offset := 4
+	checks, err := suite.repo.ListHealthChecks(
+		suite.ctx,
+		"wss://test.example.com",
+		&repository.HealthCheckListOption{Limit: &limit, Offset: &offset},
+	)
+
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), checks, 1) // Only the oldest check is left
+}
+
+// Stats methods tests
+func (suite *RelayRepositoryTestSuite) TestGetStats_UptimeAndAverages() {
+	suite.seedRelay("wss://test.example.com", "Test Relay")
+
+	now := time.Now()
+	suite.seedHealthCheck("wss://test.example.com", now.Add(-1*time.Hour), true)
+	suite.seedHealthCheck("wss://test.example.com", now.Add(-2*time.Hour), true)
+	suite.seedHealthCheck("wss://test.example.com", now.Add(-3*time.Hour), true)
+	suite.seedHealthCheck("wss://test.example.com", now.Add(-4*time.Hour), false)
+	suite.seedHealthCheck("wss://test.example.com", now.Add(-48*time.Hour), false) // Outside the window
+
+	stats, err := suite.repo.GetStats(suite.ctx, "wss://test.example.com", now.Add(-24*time.Hour))
+
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, stats.TotalChecks)
+	assert.Equal(suite.T(), 1, stats.FailedChecks)
+	assert.InDelta(suite.T(), 75.0, stats.UptimePercent, 0.01)
+	require.NotNil(suite.T(), stats.AvgRTTOpen)
+	assert.Equal(suite.T(), 100, *stats.AvgRTTOpen)
+	require.NotNil(suite.T(), stats.AvgRTTNIP11)
+	assert.Equal(suite.T(), 50, *stats.AvgRTTNIP11)
+}
+
+func (suite *RelayRepositoryTestSuite) TestGetStats_NoChecks() {
+	suite.seedRelay("wss://test.example.com", "Test Relay")
+
+	stats, err := suite.repo.GetStats(suite.ctx, "wss://test.example.com", time.Now().Add(-24*time.Hour))
+
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "wss://test.example.com", stats.RelayURL)
+	assert.Zero(suite.T(), stats.TotalChecks)
+	assert.Nil(suite.T(), stats.AvgRTTOpen)
+}
+
+func (suite *RelayRepositoryTestSuite) TestListStats_MultipleRelays() {
+	suite.seedRelay("wss://relay1.example.com", "Relay 1")
+	suite.seedRelay("wss://relay2.example.com", "Relay 2")
+	suite.seedRelay("wss://relay3.example.com", "Relay 3")
+
+	now := time.Now()
+	suite.seedHealthCheck("wss://relay1.example.com", now.Add(-1*time.Hour), true)
+	suite.seedHealthCheck("wss://relay2.example.com", now.Add(-1*time.Hour), false)
+	suite.seedHealthCheck("wss://relay3.example.com", now.Add(-1*time.Hour), true)
+
+	urls := []string{"wss://relay1.example.com", "wss://relay2.example.com"}
+	stats, err := suite.repo.ListStats(suite.ctx, urls, now.Add(-24*time.Hour))
+
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), stats, 2)
+
+	for _, s := range stats {
+		switch s.RelayURL {
+		case "wss://relay1.example.com":
+			assert.InDelta(suite.T(), 100.0, s.UptimePercent, 0.01)
+		case "wss://relay2.example.com":
+			assert.InDelta(suite.T(), 0.0, s.UptimePercent, 0.01)
+		default:
+			suite.T().Errorf("unexpected relay %s", s.RelayURL)
+		}
+	}
+}
+
+// Create and MergeRelays method tests
+func (suite *RelayRepositoryTestSuite) TestListSoftwareStats_GroupsBySoftwareAndVersion() {
+	for _, url := range []string{"wss://a.example.com", "wss://b.example.com", "wss://c.example.com", "wss://d.example.com"} {
+		suite.seedRelay(url, url)
+	}
+	suite.db.MustExec("UPDATE relays SET version = '1.0.1' WHERE url = 'wss://c.example.com'")
+	suite.db.MustExec("UPDATE relays SET status = 'retired' WHERE url = 'wss://d.example.com'")
+
+	now := time.Now()
+	suite.seedHealthCheck("wss://a.example.com", now.Add(-time.Hour), true)
+	suite.seedHealthCheck("wss://a.example.com", now.Add(-2*time.Hour), false)
+	suite.seedHealthCheck("wss://a.example.com", now.Add(-48*time.Hour), false)
+	suite.seedHealthCheck("wss://c.example.com", now.Add(-time.Hour), true)
+	suite.seedHealthCheck("wss://d.example.com", now.Add(-time.Hour), false)
+
+	stats, err := suite.repo.ListSoftwareStats(suite.ctx, now.Add(-24*time.Hour))
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), stats, 2)
+
+	// b was never checked, it counts as a relay but adds no checks.
+	assert.Equal(suite.T(), domain.SoftwareStats{
+		Software:         "test-software",
+		Version:          "1.0.0",
+		Relays:           2,
+		TotalChecks:      2,
+		SuccessfulChecks: 1,
+		RTTOpenSum:       200,
+		RTTOpenCount:     2,
+	}, stats[0])
+	assert.Equal(suite.T(), "1.0.1", stats[1].Version)
+	assert.Equal(suite.T(), 1, stats[1].Relays)
+	assert.Equal(suite.T(), 1, stats[1].SuccessfulChecks)
+}
+
+func (suite *RelayRepositoryTestSuite) TestListNIPSupport_CurrentAndHistory() {
+	suite.seedRelay("wss://a.example.com", "A")
+	suite.seedRelay("wss://b.example.com", "B")
+	suite.seedRelay("wss://c.example.com", "C")
+	suite.db.MustExec("UPDATE relays SET supported_nips = '{1,42}' WHERE url = 'wss://b.example.com'")
+	suite.db.MustExec("UPDATE relays SET status = 'retired' WHERE url = 'wss://c.example.com'")
+
+	support, err := suite.repo.ListNIPSupport(suite.ctx)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), support, 4)
+	for _, s := range support {
+		assert.Equal(suite.T(), 2, s.Total)
+	}
+	assert.Equal(suite.T(), []int{1, 2, 11, 42}, []int{support[0].NIP, support[1].NIP, support[2].NIP, support[3].NIP})
+	assert.Equal(suite.T(), 2, support[0].Relays)
+	assert.Equal(suite.T(), 1, support[3].Relays)
+
+	now := time.Now()
+	for _, doc := range []struct {
+		url    string
+		body   string
+		seenAt time.Time
+	}{
+		{"wss://a.example.com", `{"supported_nips": [1, 11]}`, now.Add(-10 * 24 * time.Hour)},
+		{"wss://a.example.com", `{"supported_nips": [1, "42"]}`, now.Add(-3 * 24 * time.Hour)},
+		{"wss://b.example.com", `{"supported_nips": null}`, now.Add(-3 * 24 * time.Hour)},
+		// Retired since, but still counted while it was checked.
+		{"wss://c.example.com", `{"supported_nips": [1, 50]}`, now.Add(-10 * 24 * time.Hour)},
+	} {
+		_, err := suite.repo.SaveDocument(suite.ctx, domain.NewRelayDocument(doc.url, []byte(doc.body), doc.seenAt))
+		require.NoError(suite.T(), err)
+	}
+
+	suite.seedHealthCheck("wss://a.example.com", now.Add(-8*24*time.Hour), true)
+	suite.seedHealthCheck("wss://a.example.com", now.Add(-24*time.Hour), true)
+	suite.seedHealthCheck("wss://b.example.com", now.Add(-24*time.Hour), false)
+	suite.seedHealthCheck("wss://c.example.com", now.Add(-12*24*time.Hour), false)
+
+	history, err := suite.repo.ListNIPSupportHistory(suite.ctx, now.Add(-14*24*time.Hour), 7*24*time.Hour)
+	require.NoError(suite.T(), err)
+
+	// Nothing 14 days ago, relays a and c 7 days ago, relays a and b today.
+	require.Len(suite.T(), history, 5)
+	assert.Equal(suite.T(), []int{1, 11, 50}, []int{history[0].NIP, history[1].NIP, history[2].NIP})
+	assert.Equal(suite.T(), 2, history[0].Total)
+	assert.Equal(suite.T(), 2, history[0].Relays)
+	assert.Equal(suite.T(), []int{1, 42}, []int{history[3].NIP, history[4].NIP})
+	assert.Equal(suite.T(), 2, history[3].Total)
+	assert.Equal(suite.T(), 1, history[3].Relays)
+	assert.Equal(suite.T(), 1, history[4].Relays)
+	assert.True(suite.T(), history[3].Day.After(history[0].Day))
+}
+
+func (suite *RelayRepositoryTestSuite) TestCreate_NormalizesURL() {
+	created, err := suite.repo.Create(suite.ctx, domain.Relay{URL: "WSS://Relay.Example.com/"})
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), created)
+
+	relay, err := suite.repo.FindByURL(suite.ctx, "wss://relay.example.com")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "wss://relay.example.com", relay.URL)
+
+	// Already stored, left untouched.
+	created, err = suite.repo.Create(suite.ctx, domain.Relay{URL: "wss://relay.example.com"})
+	require.NoError(suite.T(), err)
+	assert.False(suite.T(), created)
+
+	_, err = suite.repo.Create(suite.ctx, domain.Relay{URL: "https://relay.example.com"})
+	assert.ErrorIs(suite.T(), err, relayurl.ErrInvalid)
+}
+
+func (suite *RelayRepositoryTestSuite) TestMergeRelays_IntoExistingRelay() {
+	suite.seedRelay("wss://relay.example.com", "Canonical")
+	suite.seedRelay("wss://relay.example.com/", "Duplicate")
+
+	now := time.Now()
+	suite.seedHealthCheck("wss://relay.example.com", now.Add(-2*time.Hour), true)
+	suite.seedHealthCheck("wss://relay.example.com/", now.Add(-1*time.Hour), false)
+
+	for _, doc := range []struct {
+		url    string
+		body   string
+		seenAt time.Time
+	}{
+		{"wss://relay.example.com", `{"name": "Canonical"}`, now.Add(-3 * time.Hour)},
+		{"wss://relay.example.com/", `{"name": "Duplicate"}`, now.Add(-2 * time.Hour)},
+		{"wss://relay.example.com", `{"name": "Canonical v2"}`, now.Add(-1 * time.Hour)},
+		// The same document seen under the other spelling right after, not a change once merged.
+		{"wss://relay.example.com/", `{"name": "Canonical v2"}`, now.Add(-30 * time.Minute)},
+	} {
+		_, err := suite.repo.SaveDocument(suite.ctx, domain.NewRelayDocument(doc.url, []byte(doc.body), doc.seenAt))
+		require.NoError(suite.T(), err)
+	}
+
+	err := suite.repo.MergeRelays(suite.ctx, "wss://relay.example.com", []string{"wss://relay.example.com/"})
+	require.NoError(suite.T(), err)
+
+	relays, err := suite.repo.List(suite.ctx, nil)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "Canonical", *relays[0].Name)
+
+	checks, err := suite.repo.ListHealthChecks(suite.ctx, "wss://relay.example.com", nil)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), checks, 2)
+
+	documents, err := suite.repo.ListDocuments(suite.ctx, "wss://relay.example.com", nil)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), documents, 3)
+	for i, name := range []string{"Canonical v2", "Duplicate", "Canonical"} {
+		assert.Equal(suite.T(), 3-i, documents[i].Version)
+		assert.JSONEq(suite.T(), fmt.Sprintf(`{"name": %q}`, name), string(documents[i].Document))
+	}
+}
+
+func (suite *RelayRepositoryTestSuite) TestMergeRelays_RenamesWhenCanonicalMissing() {
+	suite.seedRelay("WSS://Relay.Example.com", "Older")
+	suite.seedRelay("wss://relay.example.com:443/", "Newer")
+
+	_, err := suite.db.Exec(
+		"UPDATE relays SET updated_at = updated_at - INTERVAL '1 day' WHERE url = $1",
+		"WSS://Relay.Example.com",
+	)
+	require.NoError(suite.T(), err)
+
+	now := time.Now()
+	suite.seedHealthCheck("WSS://Relay.Example.com", now.Add(-2*time.Hour), true)
+	suite.seedHealthCheck("wss://relay.example.com:443/", now.Add(-1*time.Hour), true)
+
+	err = suite.repo.MergeRelays(
+		suite.ctx,
+		"wss://relay.example.com",
+		[]string{"WSS://Relay.Example.com", "wss://relay.example.com:443/"},
+	)
+	require.NoError(suite.T(), err)
+
+	relays, err := suite.repo.List(suite.ctx, nil)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://relay.example.com", relays[0].URL)
+	assert.Equal(suite.T(), "Newer", *relays[0].Name)
+
+	checks, err := suite.repo.ListHealthChecks(suite.ctx, "wss://relay.example.com", nil)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), checks, 2)
+}
+
+// SSL checks method tests
+func (suite *RelayRepositoryTestSuite) TestUpdate_StoresFullNIP11Document() {
+	suite.seedRelay("wss://paid.example.com", "Paid")
+	suite.seedRelay("wss://free.example.com", "Free")
+
+	longTag := "a-topic-longer-than-the-fifty-characters-tags-used-to-be-limited-to"
+	err := suite.repo.Update(suite.ctx, domain.Relay{
+		URL:            "wss://paid.example.com",
+		Name:           &[]string{"Paid"}[0],
+		Tags:           pq.StringArray{"paid", longTag},
+		LanguageTags:   pq.StringArray{"en", "es-419"},
+		RelayCountries: pq.StringArray{"DE"},
+		Limitation: &domain.RelayLimitation{
+			MaxMessageLength: 65536,
+			PaymentRequired:  true,
+			RestrictedWrites: true,
+			MinPowDifficulty: 16,
+		},
+		Fees: &domain.RelayFees{
+			Admission: []domain.AdmissionFee{{Amount: 21000, Unit: "msats"}},
+		},
+		Retention:   domain.RelayRetention{{Kinds: [][]int{{1}}, Count: 1000}},
+		PaymentsURL: &[]string{"https://paid.example.com/pay"}[0],
+	})
+	require.NoError(suite.T(), err)
+
+	relay, err := suite.repo.FindByURL(suite.ctx, "wss://paid.example.com")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pq.StringArray{"paid", longTag}, relay.Tags)
+	assert.Equal(suite.T(), pq.StringArray{"en", "es-419"}, relay.LanguageTags)
+	assert.Equal(suite.T(), pq.StringArray{"DE"}, relay.RelayCountries)
+	require.NotNil(suite.T(), relay.Limitation)
+	assert.Equal(suite.T(), 65536, relay.Limitation.MaxMessageLength)
+	assert.True(suite.T(), relay.Limitation.PowRequired())
+	require.NotNil(suite.T(), relay.Fees)
+	assert.Equal(suite.T(), 21000, relay.Fees.Admission[0].Amount)
+	assert.Equal(suite.T(), 1000, relay.Retention[0].Count)
+	assert.Equal(suite.T(), "https://paid.example.com/pay", *relay.PaymentsURL)
+
+	// Relays declaring nothing have no limitation and don't match any requirement.
+	relay, err = suite.repo.FindByURL(suite.ctx, "wss://free.example.com")
+	require.NoError(suite.T(), err)
+	assert.Nil(suite.T(), relay.Limitation)
+	assert.Nil(suite.T(), relay.Fees)
+
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{Requirements: []string{"payment", "pow"}})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://paid.example.com", relays[0].URL)
+
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{Requirements: []string{"!writes"}})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://free.example.com", relays[0].URL)
+}
+
+func (suite *RelayRepositoryTestSuite) TestSSLChecks_LatestCheck() {
+	suite.seedRelay("wss://relay.example.com", "Relay")
+	suite.seedRelay("wss://unchecked.example.com", "Unchecked")
+
+	now := time.Now()
+	older := now.Add(-2 * time.Hour)
+	expiresAt := now.AddDate(0, 0, 10).UTC().Truncate(time.Second)
+	valid := true
+	days := 10
+	issuer := "Test CA"
+
+	err := suite.repo.SaveSSLCheck(suite.ctx, domain.SSLCheck{
+		RelayURL:  "wss://relay.example.com",
+		CreatedAt: &older,
+		Success:   false,
+		Error:     &[]string{"handshake failed"}[0],
+	})
+	require.NoError(suite.T(), err)
+
+	err = suite.repo.SaveSSLCheck(suite.ctx, domain.SSLCheck{
+		RelayURL:     "wss://relay.example.com",
+		CreatedAt:    &now,
+		Success:      true,
+		Valid:        &valid,
+		ChainValid:   &valid,
+		Issuer:       &issuer,
+		ExpiresAt:    &expiresAt,
+		DaysToExpiry: &days,
+		ExpiringSoon: &valid,
+	})
+	require.NoError(suite.T(), err)
+
+	check, err := suite.repo.FindLatestSSLCheck(suite.ctx, "wss://relay.example.com")
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), check.Success)
+	assert.Nil(suite.T(), check.Error)
+	assert.True(suite.T(), *check.Valid)
+	assert.Equal(suite.T(), "Test CA", *check.Issuer)
+	assert.Equal(suite.T(), 10, *check.DaysToExpiry)
+	assert.True(suite.T(), *check.ExpiringSoon)
+	assert.True(suite.T(), expiresAt.Equal(*check.ExpiresAt))
+
+	_, err = suite.repo.FindLatestSSLCheck(suite.ctx, "wss://unchecked.example.com")
+	assert.ErrorIs(suite.T(), err, sql.ErrNoRows)
+}
+
+func (suite *RelayRepositoryTestSuite) TestMergeRelays_MovesSSLChecks() {
+	suite.seedRelay("wss://relay.example.com", "Canonical")
+	suite.seedRelay("wss://relay.example.com/", "Duplicate")
+
+	now := time.Now()
+	err := suite.repo.SaveSSLCheck(suite.ctx, domain.SSLCheck{
+		RelayURL:  "wss://relay.example.com/",
+		CreatedAt: &now,
+		Success:   true,
+	})
+	require.NoError(suite.T(), err)
+
+	err = suite.repo.MergeRelays(suite.ctx, "wss://relay.example.com", []string{"wss://relay.example.com/"})
+	require.NoError(suite.T(), err)
+
+	check, err := suite.repo.FindLatestSSLCheck(suite.ctx, "wss://relay.example.com")
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), check.Success)
+}
+
+// DNS checks method tests
+func (suite *RelayRepositoryTestSuite) TestDNSChecks_ObservedLocation() {
+	suite.seedRelay("wss://relay.example.com", "Relay")
+
+	now := time.Now()
+	err := suite.repo.SaveDNSCheck(suite.ctx, domain.DNSCheck{
+		RelayURL:  "wss://relay.example.com",
+		CreatedAt: &now,
+		Success:   true,
+		RTTDNS:    &[]int{12}[0],
+		IPv4:      true,
+		IPs:       pq.StringArray{"203.0.113.7"},
+		Country:   &[]string{"DE"}[0],
+		Geohash:   &[]string{"u33dc0"}[0],
+	})
+	require.NoError(suite.T(), err)
+
+	var count int
+	err = suite.db.Get(&count, "SELECT COUNT(*) FROM dns_checks WHERE ipv4 AND NOT ipv6")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, count)
+
+	err = suite.repo.UpdateObservedLocation(suite.ctx, "wss://relay.example.com", "DE", "u33dc0")
+	require.NoError(suite.T(), err)
+
+	relay, err := suite.repo.FindByURL(suite.ctx, "wss://relay.example.com")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "DE", *relay.ObservedCountry)
+	assert.Equal(suite.T(), "u33dc0", *relay.ObservedGeohash)
+
+	err = suite.repo.UpdateObservedLocation(suite.ctx, "wss://unknown.example.com", "DE", "u33dc0")
+	assert.ErrorIs(suite.T(), err, sql.ErrNoRows)
+}
+
+func (suite *RelayRepositoryTestSuite) TestAuthChecks_SavedAndMerged() {
+	suite.seedRelay("wss://relay.example.com", "Relay")
+	suite.seedRelay("wss://Relay.example.com/", "Duplicate")
+
+	now := time.Now()
+	err := suite.repo.SaveAuthCheck(suite.ctx, domain.AuthCheck{
+		RelayURL:          "wss://Relay.example.com/",
+		CreatedAt:         &now,
+		Success:           true,
+		ChallengeReceived: true,
+		Authenticated:     true,
+		WriteAuthRequired: true,
+		NIP11AuthRequired: &[]bool{false}[0],
+		NIP11Mismatch:     &[]bool{true}[0],
+	})
+	require.NoError(suite.T(), err)
+
+	err = suite.repo.MergeRelays(suite.ctx, "wss://relay.example.com", []string{"wss://Relay.example.com/"})
+	require.NoError(suite.T(), err)
+
+	var check domain.AuthCheck
+	err = suite.db.Get(&check, `
+		SELECT relay_url, created_at, success, error, challenge_received, authenticated,
+			read_auth_required, write_auth_required, nip11_auth_required, nip11_mismatch
+		FROM auth_checks`)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "wss://relay.example.com", check.RelayURL)
+	assert.True(suite.T(), check.AuthRequired())
+	assert.False(suite.T(), check.ReadAuthRequired)
+	assert.True(suite.T(), *check.NIP11Mismatch)
+}
+
+func (suite *RelayRepositoryTestSuite) TestAuthChecks_FindLatest() {
+	suite.seedRelay("wss://relay.example.com", "Relay")
+
+	earlier := time.Now().Add(-time.Hour)
+	now := time.Now()
+
+	err := suite.repo.SaveAuthCheck(suite.ctx, domain.AuthCheck{
+		RelayURL:          "wss://relay.example.com",
+		CreatedAt:         &earlier,
+		Success:           true,
+		ReadAuthRequired:  true,
+		NIP11AuthRequired: &[]bool{true}[0],
+		NIP11Mismatch:     &[]bool{false}[0],
+	})
+	require.NoError(suite.T(), err)
+
+	err = suite.repo.SaveAuthCheck(suite.ctx, domain.AuthCheck{
+		RelayURL:          "wss://relay.example.com",
+		CreatedAt:         &now,
+		Success:           true,
+		NIP11AuthRequired: &[]bool{true}[0],
+		NIP11Mismatch:     &[]bool{true}[0],
+	})
+	require.NoError(suite.T(), err)
+
+	check, err := suite.repo.FindLatestAuthCheck(suite.ctx, "wss://relay.example.com")
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), check.Success)
+	assert.False(suite.T(), check.AuthRequired())
+	assert.True(suite.T(), *check.NIP11AuthRequired)
+	assert.True(suite.T(), *check.NIP11Mismatch)
+
+	_, err = suite.repo.FindLatestAuthCheck(suite.ctx, "wss://unchecked.example.com")
+	assert.ErrorIs(suite.T(), err, sql.ErrNoRows)
+}
+
+func (suite *RelayRepositoryTestSuite) TestNIPChecks_LatestPerNIP() {
+	suite.seedRelay("wss://relay.example.com", "Relay")
+	suite.seedRelay("wss://Relay.example.com/", "Duplicate")
+	suite.seedRelay("wss://other.example.com", "Other")
+
+	earlier := time.Now().Add(-time.Hour)
+	now := time.Now()
+
+	err := suite.repo.SaveNIPChecks(suite.ctx, []domain.NIPCheck{
+		{RelayURL: "wss://Relay.example.com/", CreatedAt: &earlier, NIP: 9, Result: domain.NIPCheckPass},
+		{RelayURL: "wss://Relay.example.com/", CreatedAt: &earlier, NIP: 1, Result: domain.NIPCheckPass},
+	})
+	require.NoError(suite.T(), err)
+
+	err = suite.repo.SaveNIPChecks(suite.ctx, []domain.NIPCheck{
+		{
+			RelayURL:  "wss://Relay.example.com/",
+			CreatedAt: &now,
+			NIP:       9,
+			Result:    domain.NIPCheckFail,
+			Detail:    &[]string{"deleted event still served"}[0],
+		},
+	})
+	require.NoError(suite.T(), err)
+
+	// Nothing to store is not an error.
+	require.NoError(suite.T(), suite.repo.SaveNIPChecks(suite.ctx, nil))
+
+	err = suite.repo.MergeRelays(suite.ctx, "wss://relay.example.com", []string{"wss://Relay.example.com/"})
+	require.NoError(suite.T(), err)
+
+	checks, err := suite.repo.ListLatestNIPChecks(suite.ctx, "wss://relay.example.com")
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), checks, 2)
+	assert.Equal(suite.T(), 1, checks[0].NIP)
+	assert.Equal(suite.T(), domain.NIPCheckPass, checks[0].Result)
+	assert.Nil(suite.T(), checks[0].Detail)
+	assert.Equal(suite.T(), 9, checks[1].NIP)
+	assert.Equal(suite.T(), domain.NIPCheckFail, checks[1].Result)
+	assert.Equal(suite.T(), "deleted event still served", *checks[1].Detail)
+
+	checks, err = suite.repo.ListLatestNIPChecks(suite.ctx, "wss://other.example.com")
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), checks)
+}
+
+func (suite *RelayRepositoryTestSuite) TestPublishedEvents_Saved() {
+	suite.seedRelay("wss://relay.example.com", "Relay")
+
+	now := time.Now()
+	id := "f7234bd4c1394dda46d09f35bd384dd30cc552ad5541990f98844fb06676e9ca"
+
+	err := suite.repo.SavePublishedEvents(suite.ctx, []domain.PublishedEvent{
+		{
+			EventID:   id,
+			Kind:      30166,
+			RelayURL:  "wss://relay.example.com",
+			Status:    domain.PublishStatusPublished,
+			Attempts:  1,
+			CreatedAt: &now,
+		},
+		{
+			EventID:   id,
+			Kind:      30166,
+			RelayURL:  "wss://unmonitored.example.com",
+			Status:    domain.PublishStatusFailed,
+			Error:     &[]string{"msg: blocked: not allowed"}[0],
+			Attempts:  1,
+			CreatedAt: &now,
+		},
+	})
+	require.NoError(suite.T(), err)
+
+	// Nothing to store is not an error.
+	require.NoError(suite.T(), suite.repo.SavePublishedEvents(suite.ctx, nil))
+
+	var events []domain.PublishedEvent
+	err = suite.db.Select(&events, `
+		SELECT event_id, kind, relay_url, status, error, attempts, created_at
+		FROM published_events
+		ORDER BY relay_url`)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), events, 2)
+	assert.Equal(suite.T(), domain.PublishStatusPublished, events[0].Status)
+	assert.Nil(suite.T(), events[0].Error)
+	assert.Equal(suite.T(), "wss://unmonitored.example.com", events[1].RelayURL)
+	assert.Equal(suite.T(), domain.PublishStatusFailed, events[1].Status)
+	assert.Equal(suite.T(), "msg: blocked: not allowed", *events[1].Error)
+}
+
+// Outbox method tests
+func (suite *RelayRepositoryTestSuite) TestOutboxEvents_Lifecycle() {
+	now := time.Now()
+	relayURL := "wss://relay.example.com"
+	id := "f7234bd4c1394dda46d09f35bd384dd30cc552ad5541990f98844fb06676e9ca"
+	old := "0d8e6b1d8b1c1cc3b7b6fdb6c5c1ea0d9e52ec5b5d9e52ec5b5d9e52ec5b5d9e"
+
+	for _, event := range []domain.OutboxEvent{
+		{EventID: id, Kind: 30166, RelayURL: &relayURL, Event: []byte(`{"id": "first"}`), CreatedAt: &now},
+		// Storing the same event again keeps the first copy.
+		{EventID: id, Kind: 30166, RelayURL: &relayURL, Event: []byte(`{"id": "second"}`), CreatedAt: &now},
+		{EventID: old, Kind: 10166, Event: []byte(`{}`), CreatedAt: &[]time.Time{now.Add(-48 * time.Hour)}[0]},
+	} {
+		require.NoError(suite.T(), suite.repo.SaveOutboxEvent(suite.ctx, event))
+	}
+
+	event, err := suite.repo.FindOutboxEvent(suite.ctx, id)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), domain.OutboxStatusPending, event.Status)
+	assert.Equal(suite.T(), relayURL, *event.RelayURL)
+	assert.JSONEq(suite.T(), `{"id": "first"}`, string(event.Event))
+
+	_, err = suite.repo.FindOutboxEvent(suite.ctx, "unknown")
+	require.ErrorIs(suite.T(), err, sql.ErrNoRows)
+
+	pending, err := suite.repo.ListPendingOutboxEvents(suite.ctx, now.Add(-24*time.Hour), now.Add(time.Minute))
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), pending, 1)
+	assert.Equal(suite.T(), id, pending[0].EventID)
+
+	require.NoError(suite.T(), suite.repo.MarkOutboxEventFailed(suite.ctx, id, "msg: blocked: not allowed"))
+	require.NoError(suite.T(), suite.repo.MarkOutboxEventPublished(suite.ctx, id))
+	// A late failure doesn't undo the delivery.
+	require.NoError(suite.T(), suite.repo.MarkOutboxEventFailed(suite.ctx, id, "timeout"))
+
+	event, err = suite.repo.FindOutboxEvent(suite.ctx, id)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), domain.OutboxStatusPublished, event.Status)
+	assert.Equal(suite.T(), 2, event.Attempts)
+	assert.Nil(suite.T(), event.LastError)
+	assert.NotNil(suite.T(), event.PublishedAt)
+
+	pending, err = suite.repo.ListPendingOutboxEvents(suite.ctx, now.Add(-24*time.Hour), now.Add(time.Minute))
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), pending)
+
+	err = suite.repo.MarkOutboxEventPublished(suite.ctx, "unknown")
+	require.ErrorIs(suite.T(), err, sql.ErrNoRows)
+}
+
+func (suite *RelayRepositoryTestSuite) TestWithTx_RollsBack() {
+	suite.seedRelay("wss://relay.example.com", "Relay")
+
+	now := time.Now()
+	relayURL := "wss://relay.example.com"
+	id := "f7234bd4c1394dda46d09f35bd384dd30cc552ad5541990f98844fb06676e9ca"
+
+	store := func(tx repository.RelayRepository) error {
+		if err := tx.SaveHealthCheck(suite.ctx, domain.HealthCheck{
+			RelayURL:         relayURL,
+			CreatedAt:        &now,
+			WebsocketSuccess: &[]bool{true}[0],
+		}); err != nil {
+			return err
+		}
+
+		return tx.SaveOutboxEvent(suite.ctx, domain.OutboxEvent{
+			EventID:   id,
+			Kind:      30166,
+			RelayURL:  &relayURL,
+			Event:     []byte(`{}`),
+			CreatedAt: &now,
+		})
+	}
+
+	errFailed := fmt.Errorf("failed")
+	err := suite.repo.WithTx(suite.ctx, func(tx repository.RelayRepository) error {
+		if err := store(tx); err != nil {
+			return err
+		}
+
+		return errFailed
+	})
+	require.ErrorIs(suite.T(), err, errFailed)
+
+	var count int
+	require.NoError(suite.T(), suite.db.Get(&count, "SELECT COUNT(*) FROM health_checks"))
+	assert.Equal(suite.T(), 0, count)
+
+	_, err = suite.repo.FindOutboxEvent(suite.ctx, id)
+	require.ErrorIs(suite.T(), err, sql.ErrNoRows)
+
+	require.NoError(suite.T(), suite.repo.WithTx(suite.ctx, store))
+
+	require.NoError(suite.T(), suite.db.Get(&count, "SELECT COUNT(*) FROM health_checks"))
+	assert.Equal(suite.T(), 1, count)
+
+	_, err = suite.repo.FindOutboxEvent(suite.ctx, id)
+	require.NoError(suite.T(), err)
+}
+
+func (suite *RelayRepositoryTestSuite) TestOutboxEvents_LatestAboutRelay() {
+	now := time.Now()
+	relayURL := "wss://relay.example.com"
+	otherURL := "wss://other.example.com"
+	id := func(c string) string { return strings.Repeat(c, 64) }
+
+	for i, event := range []domain.OutboxEvent{
+		{EventID: id("a"), Kind: 30166, RelayURL: &relayURL, CreatedAt: &[]time.Time{now.Add(-time.Hour)}[0]},
+		{EventID: id("b"), Kind: 30166, RelayURL: &relayURL, CreatedAt: &now},
+		{EventID: id("c"), Kind: 30166, RelayURL: &otherURL, CreatedAt: &[]time.Time{now.Add(time.Minute)}[0]},
+		{EventID: id("d"), Kind: 10166, CreatedAt: &[]time.Time{now.Add(time.Minute)}[0]},
+	} {
+		event.Event = []byte(fmt.Sprintf(`{"n": %d}`, i))
+		require.NoError(suite.T(), suite.repo.SaveOutboxEvent(suite.ctx, event))
+	}
+
+	// Delivered events count as well.
+	require.NoError(suite.T(), suite.repo.MarkOutboxEventPublished(suite.ctx, id("b")))
+
+	event, err := suite.repo.FindLatestOutboxEvent(suite.ctx, relayURL, 30166)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), id("b"), event.EventID)
+	assert.JSONEq(suite.T(), `{"n": 1}`, string(event.Event))
+
+	// Events given up on don't.
+	later := now.Add(time.Hour)
+	require.NoError(suite.T(), suite.repo.SaveOutboxEvent(suite.ctx, domain.OutboxEvent{
+		EventID: id("e"), Kind: 30166, RelayURL: &relayURL, CreatedAt: &later, Event: []byte(`{"n": 4}`),
+	}))
+	require.NoError(suite.T(), suite.repo.MarkOutboxEventExpired(suite.ctx, id("e")))
+
+	event, err = suite.repo.FindLatestOutboxEvent(suite.ctx, relayURL, 30166)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), id("b"), event.EventID)
+
+	_, err = suite.repo.FindLatestOutboxEvent(suite.ctx, "wss://unknown.example.com", 30166)
+	require.ErrorIs(suite.T(), err, sql.ErrNoRows)
+}
+
+func (suite *RelayRepositoryTestSuite) TestOutboxEvents_UnmonitoredRelays() {
+	suite.seedRelay("wss://active.example.com", "Active")
+	suite.seedRelay("wss://retired.example.com", "Retired")
+	suite.seedRelay("wss://disabled.example.com", "Disabled")
+	suite.seedRelay("wss://retracted.example.com", "Retracted")
+	suite.seedRelay("wss://rechecked.example.com", "Rechecked")
+	suite.seedRelay("wss://removed.example.com", "Removed")
+
+	for url, status := range map[string]string{
+		"wss://retired.example.com":   domain.RelayStatusRetired,
+		"wss://disabled.example.com":  domain.RelayStatusDisabled,
+		"wss://retracted.example.com": domain.RelayStatusDisabled,
+		"wss://rechecked.example.com": domain.RelayStatusDisabled,
+		"wss://removed.example.com":   domain.RelayStatusRemoved,
+	} {
+		require.NoError(suite.T(), suite.repo.SetStatus(suite.ctx, url, status))
+	}
+
+	now := time.Now()
+	n := 0
+	save := func(url string, kind int, createdAt time.Time) {
+		n++
+		require.NoError(suite.T(), suite.repo.SaveOutboxEvent(suite.ctx, domain.OutboxEvent{
+			EventID:   fmt.Sprintf("%064x", n),
+			Kind:      kind,
+			RelayURL:  &url,
+			Event:     []byte(`{}`),
+			CreatedAt: &createdAt,
+		}))
+	}
+
+	save("wss://active.example.com", 30166, now)
+	save("wss://retired.example.com", 30166, now)
+	save("wss://disabled.example.com", 30166, now)
+	save("wss://removed.example.com", 30166, now)
+	// Merged into another relay, its row is gone.
+	save("wss://merged.example.com", 30166, now)
+	save("wss://retracted.example.com", 30166, now.Add(-time.Hour))
+	save("wss://retracted.example.com", 5, now)
+	// Checked again after its retraction, then disabled once more.
+	save("wss://rechecked.example.com", 5, now.Add(-time.Hour))
+	save("wss://rechecked.example.com", 30166, now)
+
+	urls, err := suite.repo.ListUnmonitoredRelaysWithEvents(suite.ctx)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{
+		"wss://disabled.example.com",
+		"wss://merged.example.com",
+		"wss://rechecked.example.com",
+		"wss://removed.example.com",
+	}, urls)
+}
+
+func (suite *RelayRepositoryTestSuite) TestOutboxEvents_GivenUpAfterMaxAttempts() {
+	now := time.Now()
+	id := "f7234bd4c1394dda46d09f35bd384dd30cc552ad5541990f98844fb06676e9ca"
+
+	require.NoError(suite.T(), suite.repo.SaveOutboxEvent(suite.ctx, domain.OutboxEvent{
+		EventID:   id,
+		Kind:      30166,
+		Event:     []byte(`{}`),
+		CreatedAt: &now,
+	}))
+
+	for i := 1; i <= domain.OutboxMaxAttempts; i++ {
+		pending, err := suite.repo.ListPendingOutboxEvents(suite.ctx, now.Add(-time.Hour), now.Add(time.Minute))
+		require.NoError(suite.T(), err)
+		require.Len(suite.T(), pending, 1, "attempt %d", i)
+
+		require.NoError(suite.T(), suite.repo.MarkOutboxEventFailed(suite.ctx, id, "msg: blocked: not allowed"))
+	}
+
+	event, err := suite.repo.FindOutboxEvent(suite.ctx, id)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), domain.OutboxStatusFailed, event.Status)
+	assert.Equal(suite.T(), domain.OutboxMaxAttempts, event.Attempts)
+
+	pending, err := suite.repo.ListPendingOutboxEvents(suite.ctx, now.Add(-time.Hour), now.Add(time.Minute))
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), pending)
+
+	// Further failures are not counted.
+	require.NoError(suite.T(), suite.repo.MarkOutboxEventFailed(suite.ctx, id, "timeout"))
+
+	event, err = suite.repo.FindOutboxEvent(suite.ctx, id)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), domain.OutboxMaxAttempts, event.Attempts)
+}
+
+func (suite *RelayRepositoryTestSuite) TestOutboxEvents_Expired() {
+	now := time.Now()
+	pendingID := strings.Repeat("a", 64)
+	publishedID := strings.Repeat("b", 64)
+
+	for _, id := range []string{pendingID, publishedID} {
+		require.NoError(suite.T(), suite.repo.SaveOutboxEvent(suite.ctx, domain.OutboxEvent{
+			EventID:   id,
+			Kind:      30166,
+			Event:     []byte(`{}`),
+			CreatedAt: &now,
+		}))
+	}
+	require.NoError(suite.T(), suite.repo.MarkOutboxEventPublished(suite.ctx, publishedID))
+
+	require.NoError(suite.T(), suite.repo.MarkOutboxEventExpired(suite.ctx, pendingID))
+	require.NoError(suite.T(), suite.repo.MarkOutboxEventExpired(suite.ctx, publishedID))
+
+	event, err := suite.repo.FindOutboxEvent(suite.ctx, pendingID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), domain.OutboxStatusExpired, event.Status)
+
+	event, err = suite.repo.FindOutboxEvent(suite.ctx, publishedID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), domain.OutboxStatusPublished, event.Status)
+
+	pending, err := suite.repo.ListPendingOutboxEvents(suite.ctx, now.Add(-time.Hour), now.Add(time.Minute))
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), pending)
+}
+
+// Document history method tests
+func (suite *RelayRepositoryTestSuite) TestDocuments_OnlyChangesCreateVersions() {
+	suite.seedRelay("wss://relay.example.com", "Relay")
+	suite.seedRelay("wss://other.example.com", "Other")
+
+	now := time.Now()
+	v1 := `{"name": "Relay", "software": "strfry", "version": "1.0.0"}`
+	v2 := `{"name": "Relay", "software": "strfry", "version": "1.0.1"}`
+
+	for i, doc := range []string{v1, v1, v2, v1} {
+		created, err := suite.repo.SaveDocument(suite.ctx, domain.NewRelayDocument(
+			"wss://relay.example.com",
+			[]byte(doc),
+			now.Add(time.Duration(i)*time.Hour),
+		))
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), i != 1, created, "document %d", i)
+	}
+
+	documents, err := suite.repo.ListDocuments(suite.ctx, "wss://relay.example.com", nil)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), documents, 3)
+	assert.Equal(suite.T(), []int{3, 2, 1}, []int{documents[0].Version, documents[1].Version, documents[2].Version})
+	assert.Equal(suite.T(), documents[0].ContentHash, documents[2].ContentHash)
+	assert.JSONEq(suite.T(), v2, string(documents[1].Document))
+
+	changes, err := domain.DiffDocuments(documents[2].Document, documents[1].Document)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), changes, 1)
+	assert.Equal(suite.T(), "version", changes[0].Path)
+	assert.JSONEq(suite.T(), `"1.0.0"`, string(changes[0].Old))
+	assert.JSONEq(suite.T(), `"1.0.1"`, string(changes[0].New))
+
+	limit, offset := 1, 1
+	documents, err = suite.repo.ListDocuments(suite.ctx, "wss://relay.example.com", &repository.DocumentListOption{
+		Limit:  &limit,
+		Offset: &offset,
+	})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), documents, 1)
+	assert.Equal(suite.T(), 2, documents[0].Version)
+
+	documents, err = suite.repo.ListDocuments(suite.ctx, "wss://other.example.com", nil)
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), documents)
+}
+
+// Lifecycle method tests
+func (suite *RelayRepositoryTestSuite) TestSetStatus_FiltersList() {
+	suite.seedRelay("wss://relay1.example.com", "Relay 1")
+	suite.seedRelay("wss://relay2.example.com", "Relay 2")
+
+	err := suite.repo.SetStatus(suite.ctx, "wss://relay2.example.com", domain.RelayStatusDisabled)
+	require.NoError(suite.T(), err)
+
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{
+		Statuses: []string{domain.RelayStatusActive},
+	})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://relay1.example.com", relays[0].URL)
+	assert.Equal(suite.T(), domain.RelayStatusActive, relays[0].Status)
+
+	err = suite.repo.SetStatus(suite.ctx, "wss://relay2.example.com", domain.RelayStatusActive)
+	require.NoError(suite.T(), err)
+
+	relays, err = suite.repo.List(suite.ctx, &repository.ListOption{
+		Statuses: []string{domain.RelayStatusActive},
+	})
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), relays, 2)
+
+	err = suite.repo.SetStatus(suite.ctx, "wss://unknown.example.com", domain.RelayStatusDisabled)
+	assert.ErrorIs(suite.T(), err, sql.ErrNoRows)
+}
+
+func (suite *RelayRepositoryTestSuite) TestSetStatus_RemovedKeepsHistory() {
+	suite.seedRelay("wss://relay.example.com", "Relay")
+	suite.seedRelay("wss://removed.example.com", "Removed")
+	suite.seedHealthCheck("wss://removed.example.com", time.Now(), true)
+
+	_, err := suite.db.Exec("UPDATE relays SET supported_nips = '{1, 11}'")
+	require.NoError(suite.T(), err)
+
+	err = suite.repo.SetStatus(suite.ctx, "wss://removed.example.com", domain.RelayStatusRemoved)
+	require.NoError(suite.T(), err)
+
+	var count int
+	err = suite.db.Get(&count, "SELECT COUNT(*) FROM health_checks")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, count)
+
+	relay, err := suite.repo.FindByURL(suite.ctx, "wss://removed.example.com")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), domain.RelayStatusRemoved, relay.Status)
+
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{Statuses: domain.VisibleRelayStatuses})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://relay.example.com", relays[0].URL)
+
+	support, err := suite.repo.ListNIPSupport(suite.ctx)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), support, 2)
+	for _, s := range support {
+		assert.Equal(suite.T(), 1, s.Relays)
+		assert.Equal(suite.T(), 1, s.Total)
+	}
+}
+
+func (suite *RelayRepositoryTestSuite) TestRetireDeadRelays() {
+	now := time.Now()
+
+	suite.seedRelay("wss://dead.example.com", "Dead")
+	suite.seedRelay("wss://flaky.example.com", "Flaky")
+	suite.seedRelay("wss://new.example.com", "New")
+	suite.seedRelay("wss://unchecked.example.com", "Unchecked")
+
+	_, err := suite.db.Exec(
+		"UPDATE relays SET created_at = $1 WHERE url <> 'wss://new.example.com'",
+		now.AddDate(0, 0, -30),
+	)
+	require.NoError(suite.T(), err)
+
+	suite.seedHealthCheck("wss://dead.example.com", now.AddDate(0, 0, -10), true)
+	suite.seedHealthCheck("wss://dead.example.com", now.AddDate(0, 0, -2), false)
+	suite.seedHealthCheck("wss://dead.example.com", now.Add(-1*time.Hour), false)
+	suite.seedHealthCheck("wss://flaky.example.com", now.AddDate(0, 0, -2), true)
+	suite.seedHealthCheck("wss://flaky.example.com", now.Add(-1*time.Hour), false)
+	suite.seedHealthCheck("wss://new.example.com", now.Add(-1*time.Hour), false)
+
+	retired, err := suite.repo.RetireDeadRelays(suite.ctx, now.AddDate(0, 0, -7))
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), retired)
+
+	relays, err := suite.repo.List(suite.ctx, &repository.ListOption{
+		Statuses: []string{domain.RelayStatusRetired},
+	})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), relays, 1)
+	assert.Equal(suite.T(), "wss://dead.example.com", relays[0].URL)
+}
+
+func (suite *RelayRepositoryTestSuite) TestReviveRetiredRelays() {
+	now := time.Now()
+
+	suite.seedRelay("wss://back.example.com", "Back")
+	suite.seedRelay("wss://still-dead.example.com", "Still dead")
+
+	for _, url := range []string{"wss://back.example.com", "wss://still-dead.example.com"} {
+		require.NoError(suite.T(), suite.repo.SetStatus(suite.ctx, url, domain.RelayStatusRetired))
+	}
+
+	suite.seedHealthCheck("wss://back.example.com", now.Add(-2*time.Hour), false)
+	suite.seedHealthCheck("wss://back.example.com", now.Add(-1*time.Hour), true)
+	suite.seedHealthCheck("wss://still-dead.example.com", now.Add(-2*time.Hour), true)
+	suite.seedHealthCheck("wss://still-dead.example.com", now.Add(-1*time.Hour), false)
+
+	revived, err := suite.repo.ReviveRetiredRelays(suite.ctx)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), revived)
+
+	relay, err := suite.repo.FindByURL(suite.ctx, "wss://back.example.com")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), domain.RelayStatusActive, relay.Status)
+}
+
 // Run the test suite
 func TestRelayRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(RelayRepositoryTestSuite))
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
+)
+
+const (
+	// NIPTrendPeriod is how far back the adoption trend of NIPs goes.
+	NIPTrendPeriod = 90 * 24 * time.Hour
+	// NIPTrendStep is the time between two points of the adoption trend of NIPs.
+	NIPTrendStep = 7 * 24 * time.Hour
+)
+
+// NIPSupportMatrix represents how many relays claim support for each NIP, now and over time.
+type NIPSupportMatrix struct {
+	// Relays is the number of relays with a NIP-11 document.
+	Relays int
+	// NIPs are ordered by NIP number.
+	NIPs []NIPAdoption
+}
+
+// NIPAdoption represents the relays claiming support for a NIP.
+type NIPAdoption struct {
+	NIP          int
+	Relays       int
+	SharePercent float64
+	// Trend is the support of the NIP every NIPTrendStep over NIPTrendPeriod, oldest first.
+	// It only goes as far back as the NIP-11 document history does.
+	Trend []NIPTrendPoint
+}
+
+// NIPTrendPoint is the support of a NIP at a point in time.
+type NIPTrendPoint struct {
+	Day          time.Time
+	Relays       int
+	SharePercent float64
+}
+
+// RelayWithStats is a relay along with its uptime and latency over a window.
+type RelayWithStats struct {
+	domain.Relay
+	Stats domain.RelayStats
+}
+
+// GetNIPSupportMatrix returns how many relays claim support for each NIP, along with its trend.
+func (rs *relayService) GetNIPSupportMatrix(ctx context.Context) (NIPSupportMatrix, error) {
+	rs.logger.Info("Fetching NIP support matrix")
+
+	current, err := rs.relayRepo.ListNIPSupport(ctx)
+	if err != nil {
+		rs.logger.Error("Failed to fetch NIP support", slog.String("error", err.Error()))
+		return NIPSupportMatrix{}, fmt.Errorf("could not find nip support: %w", err)
+	}
+
+	history, err := rs.relayRepo.ListNIPSupportHistory(ctx, time.Now().Add(-NIPTrendPeriod), NIPTrendStep)
+	if err != nil {
+		rs.logger.Error("Failed to fetch NIP support history", slog.String("error", err.Error()))
+		return NIPSupportMatrix{}, fmt.Errorf("could not find nip support history: %w", err)
+	}
+
+	return buildNIPSupportMatrix(current, history), nil
+}
+
+// buildNIPSupportMatrix lines up the history of every NIP on the same points in time,
+// NIPs nobody claimed at some point have no support rather than a missing point.
+func buildNIPSupportMatrix(current, history []domain.NIPSupport) NIPSupportMatrix {
+	var matrix NIPSupportMatrix
+
+	adoptions := map[int]*NIPAdoption{}
+	adoption := func(nip int) *NIPAdoption {
+		a, ok := adoptions[nip]
+		if !ok {
+			a = &NIPAdoption{NIP: nip}
+			adoptions[nip] = a
+		}
+		return a
+	}
+
+	for _, s := range current {
+		matrix.Relays = s.Total
+
+		a := adoption(s.NIP)
+		a.Relays = s.Relays
+		a.SharePercent = sharePercent(s.Relays, s.Total)
+	}
+
+	var days []time.Time
+	totals := map[time.Time]int{}
+	supported := map[int]map[time.Time]int{}
+
+	for _, s := range history {
+		if _, ok := totals[s.Day]; !ok {
+			days = append(days, s.Day)
+		}
+		totals[s.Day] = s.Total
+
+		adoption(s.NIP)
+		if supported[s.NIP] == nil {
+			supported[s.NIP] = map[time.Time]int{}
+		}
+		supported[s.NIP][s.Day] = s.Relays
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+
+	for nip, a := range adoptions {
+		for _, day := range days {
+			relays := supported[nip][day]
+			a.Trend = append(a.Trend, NIPTrendPoint{
+				Day:          day,
+				Relays:       relays,
+				SharePercent: sharePercent(relays, totals[day]),
+			})
+		}
+
+		matrix.NIPs = append(matrix.NIPs, *a)
+	}
+
+	sort.Slice(matrix.NIPs, func(i, j int) bool {
+		return matrix.NIPs[i].NIP < matrix.NIPs[j].NIP
+	})
+
+	return matrix
+}
+
+// GetRelaysByNIP returns the active relays claiming support for the given NIP, most available over the window first.
+func (rs *relayService) GetRelaysByNIP(
+	ctx context.Context,
+	nip int,
+	window StatsWindow,
+	page *Page,
+) ([]RelayWithStats, error) {
+	rs.logger.Info("Fetching relays by NIP",
+		slog.Int("nip", nip),
+		slog.String("window", string(window)),
+	)
+
+	// Uptime isn't a column relays can be sorted by, rank all of them here.
+	relays, err := rs.relayRepo.List(ctx, &repository.ListOption{
+		NIP:      &nip,
+		Statuses: []string{domain.RelayStatusActive},
+	})
+	if err != nil {
+		rs.logger.Error("Failed to fetch relays by NIP", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("could not find relays supporting nip %d: %w", nip, err)
+	}
+
+	urls := make([]string, len(relays))
+	for i, r := range relays {
+		urls[i] = r.URL
+	}
+
+	stats, err := rs.GetRelaysStats(ctx, urls, window)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]RelayWithStats, len(relays))
+	for i, r := range relays {
+		s, ok := stats[r.URL]
+		if !ok {
+			s = domain.RelayStats{RelayURL: r.URL}
+		}
+		ranked[i] = RelayWithStats{Relay: r, Stats: s}
+	}
+
+	// Relays without checks in the window go last, the URL breaks ties so pages are stable.
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i].Stats, ranked[j].Stats
+		if (a.TotalChecks > 0) != (b.TotalChecks > 0) {
+			return a.TotalChecks > 0
+		}
+		if a.UptimePercent != b.UptimePercent {
+			return a.UptimePercent > b.UptimePercent
+		}
+		return ranked[i].URL < ranked[j].URL
+	})
+
+	if page == nil {
+		return ranked, nil
+	}
+
+	if page.Offset >= len(ranked) {
+		return []RelayWithStats{}, nil
+	}
+
+	return ranked[page.Offset:min(page.Offset+page.Limit, len(ranked))], nil
+}
+
+// sharePercent returns part as a percentage of total, zero when total is zero.
+func sharePercent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return 100 * float64(part) / float64(total)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+)
+
+func TestBuildNIPSupportMatrix(t *testing.T) {
+	day1 := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(NIPTrendStep)
+
+	matrix := buildNIPSupportMatrix(
+		[]domain.NIPSupport{
+			{NIP: 1, Relays: 4, Total: 4},
+			{NIP: 42, Relays: 1, Total: 4},
+		},
+		[]domain.NIPSupport{
+			{Day: day1, NIP: 1, Relays: 2, Total: 2},
+			{Day: day1, NIP: 50, Relays: 1, Total: 2},
+			{Day: day2, NIP: 1, Relays: 4, Total: 4},
+			{Day: day2, NIP: 42, Relays: 1, Total: 4},
+		},
+	)
+
+	require.Equal(t, 4, matrix.Relays)
+	require.Len(t, matrix.NIPs, 3)
+
+	nip1 := matrix.NIPs[0]
+	require.Equal(t, 1, nip1.NIP)
+	require.InDelta(t, 100, nip1.SharePercent, 0.001)
+	require.Equal(t, []NIPTrendPoint{
+		{Day: day1, Relays: 2, SharePercent: 100},
+		{Day: day2, Relays: 4, SharePercent: 100},
+	}, nip1.Trend)
+
+	// Not claimed anymore, only found in the history.
+	nip50 := matrix.NIPs[2]
+	require.Equal(t, 50, nip50.NIP)
+	require.Zero(t, nip50.Relays)
+	require.Equal(t, []NIPTrendPoint{
+		{Day: day1, Relays: 1, SharePercent: 50},
+		{Day: day2, Relays: 0, SharePercent: 0},
+	}, nip50.Trend)
+
+	// Not claimed before, its trend starts at zero.
+	nip42 := matrix.NIPs[1]
+	require.Equal(t, 0, nip42.Trend[0].Relays)
+	require.InDelta(t, 25, nip42.Trend[1].SharePercent, 0.001)
+}
@@ -2,22 +2,77 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
 
 	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/relayurl"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/repository"
 )
 
 type RelayService interface {
 	GetRelayByURL(context.Context, string) (domain.Relay, error)
 	GetRelays(context.Context, *RelayFilters) ([]domain.Relay, error)
+	GetRelayStats(context.Context, string, StatsWindow) (domain.RelayStats, error)
+	GetRelayFacets(context.Context) (domain.RelayFacets, error)
+	GetHealthHistory(context.Context, string, StatsWindow, *Page) ([]domain.HealthCheck, error)
+	GetRelaysStats(context.Context, []string, StatsWindow) (map[string]domain.RelayStats, error)
+	GetLatestSSLCheck(context.Context, string) (domain.SSLCheck, error)
+	GetLatestAuthCheck(context.Context, string) (domain.AuthCheck, error)
+	GetLatestNIPChecks(context.Context, string) ([]domain.NIPCheck, error)
+	GetDocumentHistory(context.Context, string, *Page) ([]DocumentVersion, error)
+	GetSoftwareInventory(context.Context, StatsWindow) (SoftwareInventory, error)
+	GetNIPSupportMatrix(context.Context) (NIPSupportMatrix, error)
+	GetRelaysByNIP(context.Context, int, StatsWindow, *Page) ([]RelayWithStats, error)
 }
 
 type RelayFilters struct {
 	Limit  *int
 	Offset *int
 	URLs   []string
+
+	Online         *bool
+	NIP            *int
+	Software       string
+	Country        string
+	Search         string
+	Classification string
+	Language       string
+	Network        string
+	Requirements   []string
+
+	Sort   string
+	Desc   bool
+	Cursor string
+}
+
+// NextCursor returns the cursor of the page following relays, listed with filters.
+// It's empty when there are no more relays.
+func NextCursor(relays []domain.Relay, filters *RelayFilters) string {
+	if filters == nil {
+		return ""
+	}
+
+	return repository.NextCursor(relays, &repository.ListOption{
+		Limit: filters.Limit,
+		Sort:  filters.Sort,
+		Desc:  filters.Desc,
+	})
+}
+
+// Page selects a slice of a list, a nil Page means the whole list.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// DocumentVersion is a version of the NIP-11 document of a relay, along with what changed since the previous version.
+type DocumentVersion struct {
+	domain.RelayDocument
+
+	// Changes is nil for the first version ever seen.
+	Changes []domain.DocumentChange
 }
 
 type relayService struct {
@@ -35,7 +90,12 @@ func NewRelayService(
 	}
 }
 
-func (rs *relayService) GetRelayByURL(ctx context.Context, url string) (domain.Relay, error) {
+func (rs *relayService) GetRelayByURL(ctx context.Context, rawURL string) (domain.Relay, error) {
+	url, err := relayurl.Normalize(rawURL)
+	if err != nil {
+		return domain.Relay{}, err
+	}
+
 	rs.logger.Info("Fetching relay", slog.String("url", url))
 
 	r, err := rs.relayRepo.FindByURL(ctx, url)
@@ -47,6 +107,11 @@ func (rs *relayService) GetRelayByURL(ctx context.Context, url string) (domain.R
 		return domain.Relay{}, fmt.Errorf("could not find relay %s: %w", url, err)
 	}
 
+	// Removed relays are only kept so discovery doesn't add them back.
+	if r.Status == domain.RelayStatusRemoved {
+		return domain.Relay{}, fmt.Errorf("could not find relay %s: %w", url, sql.ErrNoRows)
+	}
+
 	rs.logger.Info("Successfully fetched relay",
 		slog.String("url", url),
 		slog.String("name", func() string {
@@ -72,6 +137,21 @@ func (rs *relayService) GetRelays(
 		Limit:  filters.Limit,
 		Offset: filters.Offset,
 		URLs:   filters.URLs,
+
+		Online:         filters.Online,
+		NIP:            filters.NIP,
+		Software:       filters.Software,
+		Country:        filters.Country,
+		Search:         filters.Search,
+		Classification: filters.Classification,
+		Language:       filters.Language,
+		Network:        filters.Network,
+		Requirements:   filters.Requirements,
+		Statuses:       domain.VisibleRelayStatuses,
+
+		Sort:   filters.Sort,
+		Desc:   filters.Desc,
+		Cursor: filters.Cursor,
 	}
 
 	// Safe structured logging with nil checks
@@ -85,6 +165,39 @@ func (rs *relayService) GetRelays(
 	if filters.Offset != nil {
 		logAttrs = append(logAttrs, slog.Int("offset", *filters.Offset))
 	}
+	if filters.Online != nil {
+		logAttrs = append(logAttrs, slog.Bool("online", *filters.Online))
+	}
+	if filters.NIP != nil {
+		logAttrs = append(logAttrs, slog.Int("nip", *filters.NIP))
+	}
+	if filters.Software != "" {
+		logAttrs = append(logAttrs, slog.String("software", filters.Software))
+	}
+	if filters.Country != "" {
+		logAttrs = append(logAttrs, slog.String("country", filters.Country))
+	}
+	if filters.Search != "" {
+		logAttrs = append(logAttrs, slog.String("search", filters.Search))
+	}
+	if filters.Classification != "" {
+		logAttrs = append(logAttrs, slog.String("classification", filters.Classification))
+	}
+	if filters.Language != "" {
+		logAttrs = append(logAttrs, slog.String("language", filters.Language))
+	}
+	if filters.Network != "" {
+		logAttrs = append(logAttrs, slog.String("network", filters.Network))
+	}
+	if len(filters.Requirements) > 0 {
+		logAttrs = append(logAttrs, slog.Any("requirements", filters.Requirements))
+	}
+	if filters.Sort != "" {
+		logAttrs = append(logAttrs, slog.String("sort", filters.Sort), slog.Bool("desc", filters.Desc))
+	}
+	if filters.Cursor != "" {
+		logAttrs = append(logAttrs, slog.String("cursor", filters.Cursor))
+	}
 
 	rs.logger.LogAttrs(ctx, slog.LevelInfo, "Fetching relays", logAttrs...)
 
@@ -98,3 +211,195 @@ func (rs *relayService) GetRelays(
 
 	return relays, nil
 }
+
+func (rs *relayService) GetRelayStats(
+	ctx context.Context,
+	url string,
+	window StatsWindow,
+) (domain.RelayStats, error) {
+	rs.logger.Info("Fetching relay stats",
+		slog.String("url", url),
+		slog.String("window", string(window)),
+	)
+
+	stats, err := rs.relayRepo.GetStats(ctx, url, window.Since())
+	if err != nil {
+		rs.logger.Error("Failed to fetch relay stats",
+			slog.String("url", url),
+			slog.String("error", err.Error()),
+		)
+		return domain.RelayStats{}, fmt.Errorf("could not compute stats for relay %s: %w", url, err)
+	}
+
+	return stats, nil
+}
+
+// GetRelaysStats returns the stats of the given relays indexed by relay URL.
+// Relays without checks in the window are missing from the map.
+func (rs *relayService) GetRelaysStats(
+	ctx context.Context,
+	urls []string,
+	window StatsWindow,
+) (map[string]domain.RelayStats, error) {
+	rs.logger.Info("Fetching relays stats",
+		slog.Int("url_count", len(urls)),
+		slog.String("window", string(window)),
+	)
+
+	stats, err := rs.relayRepo.ListStats(ctx, urls, window.Since())
+	if err != nil {
+		rs.logger.Error("Failed to fetch relays stats", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("could not compute stats for relays: %w", err)
+	}
+
+	statsByURL := make(map[string]domain.RelayStats, len(stats))
+	for _, s := range stats {
+		statsByURL[s.RelayURL] = s
+	}
+
+	return statsByURL, nil
+}
+
+// GetHealthHistory returns the health checks of the given relay within the window, most recent first.
+func (rs *relayService) GetHealthHistory(
+	ctx context.Context,
+	url string,
+	window StatsWindow,
+	page *Page,
+) ([]domain.HealthCheck, error) {
+	url, err := relayurl.Normalize(url)
+	if err != nil {
+		return nil, err
+	}
+
+	since := window.Since()
+	opts := repository.HealthCheckListOption{
+		Since: &since,
+	}
+
+	if page != nil {
+		opts.Limit = &page.Limit
+		opts.Offset = &page.Offset
+	}
+
+	rs.logger.Info("Fetching relay health history",
+		slog.String("url", url),
+		slog.String("window", string(window)),
+	)
+
+	checks, err := rs.relayRepo.ListHealthChecks(ctx, url, &opts)
+	if err != nil {
+		rs.logger.Error("Failed to fetch relay health history",
+			slog.String("url", url),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("could not find health history for relay %s: %w", url, err)
+	}
+
+	return checks, nil
+}
+
+// GetDocumentHistory returns the NIP-11 document versions of the given relay, most recent first,
+// each one diffed against the version before it.
+func (rs *relayService) GetDocumentHistory(ctx context.Context, url string, page *Page) ([]DocumentVersion, error) {
+	url, err := relayurl.Normalize(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts repository.DocumentListOption
+	if page != nil {
+		// One more version, to diff the oldest one of the page against.
+		limit := page.Limit + 1
+		opts.Limit = &limit
+		opts.Offset = &page.Offset
+	}
+
+	rs.logger.Info("Fetching relay document history", slog.String("url", url))
+
+	documents, err := rs.relayRepo.ListDocuments(ctx, url, &opts)
+	if err != nil {
+		rs.logger.Error("Failed to fetch relay document history",
+			slog.String("url", url),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("could not find document history for relay %s: %w", url, err)
+	}
+
+	versions := make([]DocumentVersion, 0, len(documents))
+	for i, doc := range documents {
+		if page != nil && i == page.Limit {
+			break
+		}
+
+		version := DocumentVersion{RelayDocument: doc}
+		if i+1 < len(documents) {
+			version.Changes, err = domain.DiffDocuments(documents[i+1].Document, doc.Document)
+			if err != nil {
+				return nil, fmt.Errorf("could not diff version %d of relay %s: %w", doc.Version, url, err)
+			}
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// GetLatestSSLCheck returns the most recent TLS certificate check of the given relay.
+// It wraps sql.ErrNoRows when the relay has never been checked, like plain ws:// relays.
+func (rs *relayService) GetLatestSSLCheck(ctx context.Context, url string) (domain.SSLCheck, error) {
+	url, err := relayurl.Normalize(url)
+	if err != nil {
+		return domain.SSLCheck{}, err
+	}
+
+	check, err := rs.relayRepo.FindLatestSSLCheck(ctx, url)
+	if err != nil {
+		return domain.SSLCheck{}, fmt.Errorf("could not find ssl check for relay %s: %w", url, err)
+	}
+
+	return check, nil
+}
+
+// GetLatestAuthCheck returns the most recent NIP-42 authentication check of the given relay.
+// It wraps sql.ErrNoRows when the relay has never been checked.
+func (rs *relayService) GetLatestAuthCheck(ctx context.Context, url string) (domain.AuthCheck, error) {
+	url, err := relayurl.Normalize(url)
+	if err != nil {
+		return domain.AuthCheck{}, err
+	}
+
+	check, err := rs.relayRepo.FindLatestAuthCheck(ctx, url)
+	if err != nil {
+		return domain.AuthCheck{}, fmt.Errorf("could not find auth check for relay %s: %w", url, err)
+	}
+
+	return check, nil
+}
+
+// GetLatestNIPChecks returns the latest conformance probe result of every NIP probed on the given relay.
+// It is empty when the relay was never probed.
+func (rs *relayService) GetLatestNIPChecks(ctx context.Context, url string) ([]domain.NIPCheck, error) {
+	url, err := relayurl.Normalize(url)
+	if err != nil {
+		return nil, err
+	}
+
+	checks, err := rs.relayRepo.ListLatestNIPChecks(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("could not find nip checks for relay %s: %w", url, err)
+	}
+
+	return checks, nil
+}
+
+func (rs *relayService) GetRelayFacets(ctx context.Context) (domain.RelayFacets, error) {
+	facets, err := rs.relayRepo.ListFacets(ctx)
+	if err != nil {
+		rs.logger.Error("Failed to fetch relay facets", slog.String("error", err.Error()))
+		return domain.RelayFacets{}, fmt.Errorf("could not find relay facets: %w", err)
+	}
+
+	return facets, nil
+}
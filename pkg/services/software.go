@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/software"
+)
+
+// SoftwareInventory represents the relay implementations found across the monitored relays.
+type SoftwareInventory struct {
+	Window StatsWindow
+	Relays int
+	// Implementations are ordered by number of relays, most used first.
+	Implementations []SoftwareImplementation
+}
+
+// SoftwareImplementation represents the relays running an implementation, whatever version they run.
+type SoftwareImplementation struct {
+	SoftwareUsage
+
+	Name string
+	// LatestVersion is the most recent version seen on any relay, empty when no version can be compared.
+	LatestVersion  string
+	OutdatedRelays int
+	// Versions are ordered from the most recent, versions that can't be compared last.
+	Versions []SoftwareVersion
+}
+
+// SoftwareVersion represents the relays running a version of an implementation.
+type SoftwareVersion struct {
+	SoftwareUsage
+
+	Version string
+	// Outdated is true when a more recent version of the implementation was seen.
+	Outdated bool
+}
+
+// SoftwareUsage is the number of relays running some software and how healthy they were over the window.
+type SoftwareUsage struct {
+	Relays        int
+	TotalChecks   int
+	UptimePercent float64 // zero without checks in the window
+	AvgRTTOpen    *int
+
+	successfulChecks int
+	rttOpenSum       int64
+	rttOpenCount     int
+}
+
+// add folds the stats of more relays into the usage, the average RTT is weighted by checks.
+func (u *SoftwareUsage) add(stats domain.SoftwareStats) {
+	u.Relays += stats.Relays
+	u.TotalChecks += stats.TotalChecks
+	u.successfulChecks += stats.SuccessfulChecks
+	u.rttOpenSum += stats.RTTOpenSum
+	u.rttOpenCount += stats.RTTOpenCount
+
+	if u.TotalChecks > 0 {
+		u.UptimePercent = 100 * float64(u.successfulChecks) / float64(u.TotalChecks)
+	}
+	if u.rttOpenCount > 0 {
+		avg := int(math.Round(float64(u.rttOpenSum) / float64(u.rttOpenCount)))
+		u.AvgRTTOpen = &avg
+	}
+}
+
+// GetSoftwareInventory groups relays by the canonical name of the software they report and its version,
+// along with their uptime and latency over the window.
+func (rs *relayService) GetSoftwareInventory(ctx context.Context, window StatsWindow) (SoftwareInventory, error) {
+	rs.logger.Info("Fetching software inventory", slog.String("window", string(window)))
+
+	stats, err := rs.relayRepo.ListSoftwareStats(ctx, window.Since())
+	if err != nil {
+		rs.logger.Error("Failed to fetch software inventory", slog.String("error", err.Error()))
+		return SoftwareInventory{}, fmt.Errorf("could not find software inventory: %w", err)
+	}
+
+	return buildSoftwareInventory(window, stats), nil
+}
+
+// buildSoftwareInventory merges the stats of the different spellings of the same software and version.
+func buildSoftwareInventory(window StatsWindow, stats []domain.SoftwareStats) SoftwareInventory {
+	inventory := SoftwareInventory{Window: window}
+
+	implementations := map[string]*SoftwareImplementation{}
+	versions := map[string]map[string]*SoftwareVersion{}
+
+	for _, s := range stats {
+		name := software.Name(s.Software)
+
+		impl, ok := implementations[name]
+		if !ok {
+			impl = &SoftwareImplementation{Name: name}
+			implementations[name] = impl
+			versions[name] = map[string]*SoftwareVersion{}
+		}
+		impl.add(s)
+
+		version := software.Version(s.Version)
+		v, ok := versions[name][version]
+		if !ok {
+			v = &SoftwareVersion{Version: version}
+			versions[name][version] = v
+		}
+		v.add(s)
+
+		inventory.Relays += s.Relays
+	}
+
+	for name, impl := range implementations {
+		for _, v := range versions[name] {
+			impl.Versions = append(impl.Versions, *v)
+
+			if !software.Comparable(v.Version) {
+				continue
+			}
+			if cmp, _ := software.Compare(v.Version, impl.LatestVersion); impl.LatestVersion == "" || cmp > 0 {
+				impl.LatestVersion = v.Version
+			}
+		}
+
+		// Most recent first, versions that can't be compared last.
+		sort.Slice(impl.Versions, func(i, j int) bool {
+			a, b := impl.Versions[i].Version, impl.Versions[j].Version
+			if cmp, ok := software.Compare(a, b); ok && cmp != 0 {
+				return cmp > 0
+			}
+			if software.Comparable(a) != software.Comparable(b) {
+				return software.Comparable(a)
+			}
+			return a < b
+		})
+
+		for i, v := range impl.Versions {
+			if cmp, ok := software.Compare(v.Version, impl.LatestVersion); ok && cmp < 0 {
+				impl.Versions[i].Outdated = true
+				impl.OutdatedRelays += v.Relays
+			}
+		}
+
+		inventory.Implementations = append(inventory.Implementations, *impl)
+	}
+
+	sort.Slice(inventory.Implementations, func(i, j int) bool {
+		a, b := inventory.Implementations[i], inventory.Implementations[j]
+		if a.Relays != b.Relays {
+			return a.Relays > b.Relays
+		}
+		return a.Name < b.Name
+	})
+
+	return inventory
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
+)
+
+func TestBuildSoftwareInventory(t *testing.T) {
+	inventory := buildSoftwareInventory(StatsWindowDay, []domain.SoftwareStats{
+		{Software: "git+https://github.com/hoytech/strfry.git", Version: "1.0.4", Relays: 3, TotalChecks: 10, SuccessfulChecks: 10, RTTOpenSum: 1000, RTTOpenCount: 10},
+		{Software: "https://github.com/hoytech/strfry", Version: "v1.0.4", Relays: 1, TotalChecks: 10, SuccessfulChecks: 5, RTTOpenSum: 3000, RTTOpenCount: 10},
+		{Software: "https://github.com/hoytech/strfry", Version: "0.9.6", Relays: 2},
+		{Software: "https://github.com/hoytech/strfry", Version: "master", Relays: 1},
+		{Software: "https://github.com/scsibug/nostr-rs-relay", Version: "0.8.9", Relays: 1, TotalChecks: 4, SuccessfulChecks: 4},
+	})
+
+	require.Equal(t, 8, inventory.Relays)
+	require.Len(t, inventory.Implementations, 2)
+
+	strfry := inventory.Implementations[0]
+	require.Equal(t, "strfry", strfry.Name)
+	require.Equal(t, 7, strfry.Relays)
+	require.Equal(t, "1.0.4", strfry.LatestVersion)
+	require.Equal(t, 2, strfry.OutdatedRelays)
+	require.InDelta(t, 75, strfry.UptimePercent, 0.001)
+	require.Equal(t, 200, *strfry.AvgRTTOpen)
+
+	require.Len(t, strfry.Versions, 3)
+	require.Equal(t, "1.0.4", strfry.Versions[0].Version)
+	require.Equal(t, 4, strfry.Versions[0].Relays)
+	require.False(t, strfry.Versions[0].Outdated)
+	require.Equal(t, "0.9.6", strfry.Versions[1].Version)
+	require.True(t, strfry.Versions[1].Outdated)
+	require.Nil(t, strfry.Versions[1].AvgRTTOpen)
+	require.Equal(t, "master", strfry.Versions[2].Version)
+	require.False(t, strfry.Versions[2].Outdated)
+
+	rsRelay := inventory.Implementations[1]
+	require.Equal(t, "nostr-rs-relay", rsRelay.Name)
+	require.Equal(t, 0, rsRelay.OutdatedRelays)
+	require.Nil(t, rsRelay.AvgRTTOpen)
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// StatsWindow is the time window over which relay stats are aggregated.
+type StatsWindow string
+
+const (
+	StatsWindowDay   StatsWindow = "24h"
+	StatsWindowWeek  StatsWindow = "7d"
+	StatsWindowMonth StatsWindow = "30d"
+)
+
+// StatsWindows lists the supported windows, in the order they are offered to users.
+var StatsWindows = []StatsWindow{StatsWindowDay, StatsWindowWeek, StatsWindowMonth}
+
+// ParseStatsWindow returns the StatsWindow matching the given string.
+// An empty string defaults to the last 24 hours.
+func ParseStatsWindow(s string) (StatsWindow, error) {
+	if s == "" {
+		return StatsWindowDay, nil
+	}
+
+	for _, w := range StatsWindows {
+		if string(w) == s {
+			return w, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported stats window %q", s)
+}
+
+// Duration returns the length of the window.
+func (w StatsWindow) Duration() time.Duration {
+	switch w {
+	case StatsWindowWeek:
+		return 7 * 24 * time.Hour
+	case StatsWindowMonth:
+		return 30 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// Since returns the start of the window relative to now.
+func (w StatsWindow) Since() time.Time {
+	return time.Now().Add(-w.Duration())
+}
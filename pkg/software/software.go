@@ -0,0 +1,154 @@
+// Package software turns the software and version relays report in NIP-11 into comparable values,
+// so relays running the same implementation are grouped together no matter how they spell it.
+package software
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unknown is the name of the implementation of relays not reporting any software.
+const Unknown = "unknown"
+
+// implementations maps the names relay implementations are known by to their canonical name.
+var implementations = map[string]string{
+	"strfry":         "strfry",
+	"nostr-rs-relay": "nostr-rs-relay",
+	"nostr_rs_relay": "nostr-rs-relay",
+	"khatru":         "khatru",
+	"nostream":       "nostream",
+	"chorus":         "chorus",
+	"haven":          "haven",
+	"rnostr":         "rnostr",
+	"nosflare":       "nosflare",
+	"wot-relay":      "wot-relay",
+	"realy":          "realy",
+	"nostrcheck":     "nostrcheck",
+	"nostr-relay":    "nostr-relay",
+	"bostr":          "bostr",
+	"gnost-relay":    "gnost-relay",
+	"immortal":       "immortal",
+	"grain":          "grain",
+}
+
+// knownNames are the keys of implementations, longest first, so "nostr-rs-relay" wins over "nostr-relay".
+var knownNames = func() []string {
+	names := make([]string, 0, len(implementations))
+	for name := range implementations {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+}()
+
+// Name returns the canonical name of the implementation reported as raw, usually a repository URL
+// like "git+https://github.com/hoytech/strfry.git".
+// Implementations it doesn't know are named after the last segment of their URL.
+func Name(raw string) string {
+	s := strings.ToLower(strings.TrimSpace(raw))
+	s = strings.TrimPrefix(s, "git+")
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	s = strings.TrimRight(s, "/")
+	s = strings.TrimSuffix(s, ".git")
+
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		s = s[i+1:]
+	}
+
+	if s == "" {
+		return Unknown
+	}
+
+	if name, ok := implementations[s]; ok {
+		return name
+	}
+
+	// Forks and decorated names, e.g. "strfry-custom".
+	for _, known := range knownNames {
+		if strings.Contains(s, known) {
+			return implementations[known]
+		}
+	}
+
+	return s
+}
+
+// Version returns the version reported as raw without its "v" prefix, empty when not reported.
+func Version(raw string) string {
+	v := strings.TrimSpace(raw)
+	if len(v) > 1 && (v[0] == 'v' || v[0] == 'V') && v[1] >= '0' && v[1] <= '9' {
+		v = v[1:]
+	}
+
+	return v
+}
+
+// Compare compares two versions by their leading dotted numbers, ignoring suffixes like "-rc1" or "-7-g7196547".
+// It returns -1, 0 or 1, and false when any of them doesn't start with a number.
+func Compare(a, b string) (int, bool) {
+	pa, ok := parse(a)
+	if !ok {
+		return 0, false
+	}
+
+	pb, ok := parse(b)
+	if !ok {
+		return 0, false
+	}
+
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+	}
+
+	return 0, true
+}
+
+// Comparable tells whether a version starts with a number, so it can be compared with others.
+func Comparable(version string) bool {
+	_, ok := parse(version)
+	return ok
+}
+
+// parse returns the leading dotted numbers of a version, "1.0.4-rc1" gives [1 0 4].
+func parse(version string) ([]int, bool) {
+	version = Version(version)
+
+	end := 0
+	for end < len(version) && (version[end] == '.' || (version[end] >= '0' && version[end] <= '9')) {
+		end++
+	}
+
+	var parts []int
+	for _, p := range strings.Split(strings.Trim(version[:end], "."), ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+
+	return parts, true
+}
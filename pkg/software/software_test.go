@@ -0,0 +1,59 @@
+package software
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestName(t *testing.T) {
+	tests := map[string]string{
+		"git+https://github.com/hoytech/strfry.git":       "strfry",
+		"https://github.com/hoytech/strfry":               "strfry",
+		"https://git.sr.ht/~gheartsfield/nostr-rs-relay/": "nostr-rs-relay",
+		"https://github.com/scsibug/nostr-rs-relay":       "nostr-rs-relay",
+		"khatru":                            "khatru",
+		"https://github.com/fiatjaf/khatru": "khatru",
+		"git+https://github.com/Cameri/nostream.git": "nostream",
+		"https://github.com/someone/strfry-fork":     "strfry",
+		"https://github.com/someone/new-relay":       "new-relay",
+		"":                                           Unknown,
+		"  ":                                         Unknown,
+	}
+
+	for raw, want := range tests {
+		require.Equal(t, want, Name(raw), raw)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	require.Equal(t, "1.0.4", Version("v1.0.4"))
+	require.Equal(t, "1.0.4", Version(" 1.0.4 "))
+	require.Equal(t, "version-x", Version("version-x"))
+	require.Equal(t, "", Version(""))
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.4", "1.0.4", 0},
+		{"1.0.3", "1.0.4", -1},
+		{"1.0.10", "1.0.9", 1},
+		{"v1.0", "1.0.0", 0},
+		{"0.9.6-7-g7196547", "0.9.7", -1},
+		{"1.0.4-rc1", "1.0.4", 0},
+	}
+
+	for _, tt := range tests {
+		got, ok := Compare(tt.a, tt.b)
+		require.True(t, ok, "%s vs %s", tt.a, tt.b)
+		require.Equal(t, tt.want, got, "%s vs %s", tt.a, tt.b)
+	}
+
+	_, ok := Compare("latest", "1.0.0")
+	require.False(t, ok)
+	_, ok = Compare("1.0.0", "")
+	require.False(t, ok)
+}
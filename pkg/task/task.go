@@ -3,26 +3,37 @@ package task
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/jmoiron/sqlx"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip40"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/sys/unix"
 
+	"github.com/danvergara/nostrich_watch_monitor/pkg/discovery"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/domain"
 	"github.com/danvergara/nostrich_watch_monitor/pkg/healthcheck"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/publisher"
+	"github.com/danvergara/nostrich_watch_monitor/pkg/repository/postgres"
 )
 
 const (
 	TypeHealthCheck         = "relay:healthcheck"
 	TypeMonitorAnnouncement = "relay:announcement"
+	TypeRelayDiscovery      = "relay:discovery"
+	TypeRelayPublish        = "relay:publish"
+	TypeRelayRetract        = "relay:retract"
 )
 
 // Metric variables.
@@ -53,12 +64,16 @@ var (
 )
 
 type TasKHandler struct {
-	db           *sqlx.DB
-	timeout      time.Duration
-	privateKey   string // For signing test events
-	logger       *slog.Logger
-	redisHost    string
-	monitorRelay string
+	db         *sqlx.DB
+	timeout    time.Duration
+	privateKey string // For signing test events
+	logger     *slog.Logger
+	redisHost  string
+	checks     *healthcheck.Registry
+	policy     healthcheck.ChangePolicy // For skipping events that report nothing new
+	proxy      *url.URL                 // For checking relays on Tor, I2P and Lokinet
+	publisher  *publisher.Publisher     // Shared by every task, closed when the worker stops
+	client     *asynq.Client            // For scheduling the delivery of the events in the outbox
 }
 
 func NewTaskHandler(
@@ -67,15 +82,27 @@ func NewTaskHandler(
 	privateKey string,
 	logger *slog.Logger,
 	redisHost string,
-	monitorRelayURL string,
+	publishRelays []string,
+	checks *healthcheck.Registry,
+	proxy *url.URL,
+	policy healthcheck.ChangePolicy,
 ) *TasKHandler {
 	return &TasKHandler{
-		db:           db,
-		timeout:      timeout,
-		privateKey:   privateKey,
-		logger:       logger,
-		redisHost:    redisHost,
-		monitorRelay: monitorRelayURL,
+		db:         db,
+		timeout:    timeout,
+		privateKey: privateKey,
+		logger:     logger,
+		redisHost:  redisHost,
+		checks:     checks,
+		proxy:      proxy,
+		policy:     policy,
+		publisher: publisher.NewPublisher(
+			publishRelays,
+			publisher.WithTimeout(timeout),
+			publisher.WithLogger(logger),
+			publisher.WithDB(db),
+		),
+		client: asynq.NewClient(asynq.RedisClientOpt{Addr: redisHost}),
 	}
 }
 
@@ -106,6 +133,9 @@ func (th *TasKHandler) Run() error {
 	mux.Use(metricsMiddleware)
 	mux.HandleFunc(TypeHealthCheck, th.HandleRelayHealthCheckTask)
 	mux.HandleFunc(TypeMonitorAnnouncement, th.HandleMonitorAnnouncementTask)
+	mux.HandleFunc(TypeRelayDiscovery, th.HandleRelayDiscoveryTask)
+	mux.HandleFunc(TypeRelayPublish, th.HandleRelayPublishTask)
+	mux.HandleFunc(TypeRelayRetract, th.HandleRelayRetractTask)
 
 	if err := srv.Start(mux); err != nil {
 		th.logger.Error("Failed to start worker server", slog.Any("error", err.Error()))
@@ -126,8 +156,18 @@ func (th *TasKHandler) Run() error {
 	}
 
 	srv.Stop()
+	// Let the tasks in progress finish before closing the connection they publish through.
+	srv.Shutdown()
 	th.logger.Info("worker server stopped")
 
+	if err := th.publisher.Close(); err != nil {
+		th.logger.Error("Failed to close the publisher", slog.Any("error", err.Error()))
+	}
+
+	if err := th.client.Close(); err != nil {
+		th.logger.Error("Failed to close the task client", slog.Any("error", err.Error()))
+	}
+
 	return nil
 }
 
@@ -135,12 +175,32 @@ func (th *TasKHandler) Run() error {
 type RelayHealthCheckTaskPayload struct {
 	// URL of the relay
 	RelayURL string
+	// How often the relay is checked, zero when unknown
+	Frequency time.Duration
 }
 
 type RelayMonitorAnnouncementTaskPayload struct {
 	Frequency string
 }
 
+// Payload of the task crawling seed relays for new relays to monitor.
+type RelayDiscoveryTaskPayload struct {
+	// URLs of the relays crawled for relay lists and other monitors' events
+	SeedRelays []string
+}
+
+// Payload of the task delivering an event of the outbox to the publish targets.
+type RelayPublishTaskPayload struct {
+	// ID of the signed event, stored in the outbox
+	EventID string
+}
+
+// Payload of the task retracting the events about a relay no longer monitored.
+type RelayRetractTaskPayload struct {
+	// URL of the relay
+	RelayURL string
+}
+
 func (th *TasKHandler) HandleRelayHealthCheckTask(ctx context.Context, t *asynq.Task) error {
 	var r RelayHealthCheckTaskPayload
 
@@ -153,7 +213,11 @@ func (th *TasKHandler) HandleRelayHealthCheckTask(ctx context.Context, t *asynq.
 		healthcheck.WithTimeout(th.timeout),
 		healthcheck.WithPrivateKey(th.privateKey),
 		healthcheck.WithLogger(th.logger),
-		healthcheck.WithMonitorRelay(th.monitorRelay),
+		healthcheck.WithDispatcher(taskDispatcher{client: th.client}),
+		healthcheck.WithChangePolicy(th.policy),
+		healthcheck.WithFrequency(r.Frequency),
+		healthcheck.WithRegistry(th.checks),
+		healthcheck.WithProxy(th.proxy),
 	)
 	if err := rc.CheckRelay(ctx, r.RelayURL); err != nil {
 		return err
@@ -175,19 +239,152 @@ func (th *TasKHandler) HandleMonitorAnnouncementTask(ctx context.Context, t *asy
 		healthcheck.WithTimeout(th.timeout),
 		healthcheck.WithPrivateKey(th.privateKey),
 		healthcheck.WithLogger(th.logger),
-		healthcheck.WithMonitorRelay(th.monitorRelay),
+		healthcheck.WithPublisher(th.publisher),
+		healthcheck.WithRegistry(th.checks),
 	)
 
-	// convert the timeout to seconds with no decimals and then to string to be passed to the creationg of the 10166 event.
-	timeoutInSeconds := fmt.Sprintf("%d", int64(th.timeout.Seconds()))
+	if err := rc.Publish10166Event(ctx, r.Frequency); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (th *TasKHandler) HandleRelayDiscoveryTask(ctx context.Context, t *asynq.Task) error {
+	var r RelayDiscoveryTaskPayload
+
+	if err := json.Unmarshal(t.Payload(), &r); err != nil {
+		return err
+	}
+
+	d := discovery.NewDiscoverer(
+		discovery.WithDB(th.db),
+		discovery.WithSeedRelays(r.SeedRelays),
+		discovery.WithLogger(th.logger),
+	)
 
-	if err := rc.Publish10166Event(ctx, r.Frequency, timeoutInSeconds); err != nil {
+	result, err := d.Discover(ctx)
+	if err != nil {
 		return err
 	}
 
+	th.logger.Info(
+		"[*] relay discovery",
+		slog.Int("events", result.Events),
+		slog.Int("candidates", result.Candidates),
+		slog.Int("created", result.Created),
+	)
+
 	return nil
 }
 
+// HandleRelayPublishTask delivers an event of the outbox to the publish targets.
+// Events already delivered are skipped, so the task can be enqueued again safely.
+func (th *TasKHandler) HandleRelayPublishTask(ctx context.Context, t *asynq.Task) error {
+	var r RelayPublishTaskPayload
+
+	if err := json.Unmarshal(t.Payload(), &r); err != nil {
+		return err
+	}
+
+	relayRepo := postgres.NewRelayRepository(th.db)
+
+	event, err := relayRepo.FindOutboxEvent(ctx, r.EventID)
+	if err != nil {
+		return err
+	}
+
+	if event.Status == domain.OutboxStatusPublished {
+		th.logger.Info("[*] event already published", slog.String("event_id", r.EventID))
+		return nil
+	}
+
+	if event.Status == domain.OutboxStatusFailed || event.Status == domain.OutboxStatusExpired {
+		th.logger.Warn("[*] event given up on", slog.String("event_id", r.EventID), slog.String("status", event.Status))
+		return nil
+	}
+
+	var ev nostr.Event
+	if err := json.Unmarshal(event.Event, &ev); err != nil {
+		// Retrying won't make the stored event any more valid.
+		return fmt.Errorf("failed to decode outbox event %s: %w: %w", r.EventID, err, asynq.SkipRetry)
+	}
+
+	// Relays drop expired events (NIP-40), and a newer event replaces it anyway.
+	if expiration := nip40.GetExpiration(ev.Tags); expiration != -1 && expiration <= nostr.Now() {
+		th.logger.Warn("[*] event expired before being published", slog.String("event_id", r.EventID))
+
+		if err := relayRepo.MarkOutboxEventExpired(ctx, r.EventID); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	if err := th.publisher.Publish(ctx, ev); err != nil {
+		var publishErr *publisher.PublishError
+		if errors.As(err, &publishErr) {
+			th.logger.Error(
+				"[*] event not published",
+				slog.String("event_id", r.EventID),
+				slog.Int("kind", ev.Kind),
+				slog.String("error", publishErr.Error()),
+			)
+		}
+
+		if err := relayRepo.MarkOutboxEventFailed(ctx, r.EventID, err.Error()); err != nil {
+			th.logger.Error(
+				"[*] failed to record the failed publish",
+				slog.String("event_id", r.EventID),
+				slog.String("error", err.Error()),
+			)
+		}
+
+		return err
+	}
+
+	if err := relayRepo.MarkOutboxEventPublished(ctx, r.EventID); err != nil {
+		return err
+	}
+
+	th.logger.Info("[*] event published", slog.String("event_id", r.EventID), slog.Int("kind", ev.Kind))
+
+	return nil
+}
+
+// HandleRelayRetractTask retracts the events about a relay the monitor no longer monitors.
+func (th *TasKHandler) HandleRelayRetractTask(ctx context.Context, t *asynq.Task) error {
+	var r RelayRetractTaskPayload
+
+	if err := json.Unmarshal(t.Payload(), &r); err != nil {
+		return err
+	}
+
+	rc := healthcheck.NewRelayChecker(
+		healthcheck.WithDB(th.db),
+		healthcheck.WithPrivateKey(th.privateKey),
+		healthcheck.WithLogger(th.logger),
+		healthcheck.WithDispatcher(taskDispatcher{client: th.client}),
+	)
+
+	if err := rc.RetractRelay(ctx, r.RelayURL); err != nil {
+		return err
+	}
+
+	th.logger.Info("[*] retracted the events about the relay", slog.String("nostr_relay", r.RelayURL))
+
+	return nil
+}
+
+// taskDispatcher schedules the delivery of the events stored in the outbox by enqueuing relay:publish tasks.
+type taskDispatcher struct {
+	client *asynq.Client
+}
+
+func (d taskDispatcher) Dispatch(ctx context.Context, eventID string) error {
+	return EnqueueRelayPublishTask(ctx, d.client, eventID)
+}
+
 func metricsMiddleware(next asynq.Handler) asynq.Handler {
 	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
 		inProgressGauge.WithLabelValues(t.Type()).Inc()
@@ -201,8 +398,8 @@ func metricsMiddleware(next asynq.Handler) asynq.Handler {
 	})
 }
 
-func NewRelayHealthCheckTask(relayURL string) (*asynq.Task, error) {
-	payload, err := json.Marshal(RelayHealthCheckTaskPayload{RelayURL: relayURL})
+func NewRelayHealthCheckTask(relayURL string, frequency time.Duration) (*asynq.Task, error) {
+	payload, err := json.Marshal(RelayHealthCheckTaskPayload{RelayURL: relayURL, Frequency: frequency})
 	if err != nil {
 		return nil, err
 	}
@@ -218,3 +415,55 @@ func NewTaskMonitorAnnouncement(frequency string) (*asynq.Task, error) {
 
 	return asynq.NewTask(TypeMonitorAnnouncement, payload), nil
 }
+
+func NewRelayDiscoveryTask(seedRelays []string) (*asynq.Task, error) {
+	payload, err := json.Marshal(RelayDiscoveryTaskPayload{SeedRelays: seedRelays})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TypeRelayDiscovery, payload), nil
+}
+
+// NewRelayPublishTask returns the task delivering an event of the outbox.
+// The task id is derived from the event id, so an event can't be enqueued twice while its task is pending,
+// and it's retried until the event is given up on.
+func NewRelayPublishTask(eventID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(RelayPublishTaskPayload{EventID: eventID})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(
+		TypeRelayPublish,
+		payload,
+		asynq.TaskID(TypeRelayPublish+":"+eventID),
+		asynq.MaxRetry(domain.OutboxMaxAttempts-1),
+	), nil
+}
+
+// EnqueueRelayPublishTask enqueues the delivery of an event of the outbox,
+// an event whose delivery is already pending is not enqueued again.
+func EnqueueRelayPublishTask(ctx context.Context, client *asynq.Client, eventID string) error {
+	publishTask, err := NewRelayPublishTask(eventID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.EnqueueContext(ctx, publishTask); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("failed to enqueue the publish of event %s: %w", eventID, err)
+	}
+
+	return nil
+}
+
+// NewRelayRetractTask returns the task retracting the events about a relay no longer monitored.
+// The task id is derived from the relay URL, so a relay can't be retracted twice at once.
+func NewRelayRetractTask(relayURL string) (*asynq.Task, error) {
+	payload, err := json.Marshal(RelayRetractTaskPayload{RelayURL: relayURL})
+	if err != nil {
+		return nil, err
+	}
+
+	return asynq.NewTask(TypeRelayRetract, payload, asynq.TaskID(TypeRelayRetract+":"+relayURL)), nil
+}
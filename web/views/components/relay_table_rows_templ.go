@@ -13,7 +13,7 @@ import (
 	"github.com/danvergara/nostrich_watch_monitor/pkg/presentation"
 )
 
-func RelayTableRows(relays []presentation.RelayTableViewModel, nextOffset int) templ.Component {
+func RelayTableRows(relays []presentation.RelayTableViewModel, filters presentation.RelayFiltersViewModel, nextCursor string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -41,26 +41,50 @@ func RelayTableRows(relays []presentation.RelayTableViewModel, nextOffset int) t
 					return templ_7745c5c3_Err
 				}
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, " <!-- Minimal height trigger element --> <tr id=\"load-more-trigger\" class=\"border-0\" hx-get=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, " ")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var2 string
-			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/api/relays?offset=%d&limit=10", nextOffset))
-			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/views/components/relay_table_rows.templ`, Line: 17, Col: 78}
-			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
-			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" hx-trigger=\"intersect once\" hx-target=\"#relay-table-body\" hx-swap=\"beforeend\" hx-indicator=\"#loading-indicator\"><td colspan=\"6\" class=\"p-1 h-1\"></td></tr>")
-			if templ_7745c5c3_Err != nil {
-				return templ_7745c5c3_Err
+			if nextCursor != "" {
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<!-- Minimal height trigger element --> <tr id=\"load-more-trigger\" class=\"border-0\" hx-get=\"")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				var templ_7745c5c3_Var2 string
+				templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(relayRowsURL(filters, nextCursor))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/views/components/relay_table_rows.templ`, Line: 18, Col: 54}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\" hx-trigger=\"intersect once\" hx-target=\"#relay-table-body\" hx-swap=\"beforeend\" hx-indicator=\"#loading-indicator\"><td colspan=\"7\" class=\"p-1 h-1\"></td></tr>")
+				if templ_7745c5c3_Err != nil {
+					return templ_7745c5c3_Err
+				}
 			}
 		}
 		return nil
 	})
 }
 
+// relayRowsURL returns the rows endpoint URL of the page following cursor, keeping the applied filters.
+func relayRowsURL(filters presentation.RelayFiltersViewModel, cursor string) string {
+	q := filters.Query()
+	q.Set("cursor", cursor)
+	q.Set("limit", fmt.Sprintf("%d", filters.Limit))
+
+	return "/api/relays?" + q.Encode()
+}
+
+// relaySortURL returns the dashboard URL sorted by the given column.
+// Sorting by the current column again reverses the order.
+func relaySortURL(filters presentation.RelayFiltersViewModel, sort string) string {
+	filters.Desc = filters.Sort == sort && !filters.Desc
+	filters.Sort = sort
+
+	return "/?" + filters.Query().Encode()
+}
+
 var _ = templruntime.GeneratedTemplate
@@ -12,7 +12,7 @@ import (
 	"github.com/danvergara/nostrich_watch_monitor/pkg/presentation"
 )
 
-func RelayTable(relays []presentation.RelayTableViewModel) templ.Component {
+func RelayTable(relays []presentation.RelayTableViewModel, filters presentation.RelayFiltersViewModel, nextCursor string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -33,15 +33,78 @@ func RelayTable(relays []presentation.RelayTableViewModel) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"bg-white dark:bg-gray-900 rounded-lg border border-gray-200 dark:border-gray-700 overflow-hidden\"><div class=\"overflow-x-auto max-h-[600px] overflow-y-auto\"><table class=\"w-full min-w-[1200px]\"><thead class=\"bg-gray-50 dark:bg-gray-800 sticky top-0 z-10\"><tr><th class=\"px-2 py-3 text-center text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center justify-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Status</span></button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Relay</span> <svg class=\"w-4 h-4 text-gray-400\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M8 9l4-4 4 4m0 6l-4 4-4-4\"></path></svg></button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Connection</span> <svg class=\"w-4 h-4 text-gray-400\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M8 9l4-4 4 4m0 6l-4 4-4-4\"></path></svg></button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>NIP-11</span></button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Type</span></button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Last Check</span></button></th></tr></thead> <tbody id=\"relay-table-body\" class=\"divide-y divide-gray-200 dark:divide-gray-700\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"bg-white dark:bg-gray-900 rounded-lg border border-gray-200 dark:border-gray-700 overflow-hidden\"><div class=\"overflow-x-auto max-h-[600px] overflow-y-auto\"><table class=\"w-full min-w-[1200px]\"><thead class=\"bg-gray-50 dark:bg-gray-800 sticky top-0 z-10\"><tr><th class=\"px-2 py-3 text-center text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center justify-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Status</span></button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button hx-get=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = RelayTableRows(relays, 10).Render(ctx, templ_7745c5c3_Buffer)
+		var templ_7745c5c3_Var2 string
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(relaySortURL(filters, "name"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/views/components/relay_table.templ`, Line: 20, Col: 70}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" hx-target=\"body\" hx-push-url=\"true\" class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Relay</span>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = SortIndicator(filters, "name").Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button hx-get=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var3 string
+		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(relaySortURL(filters, "rtt"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/views/components/relay_table.templ`, Line: 32, Col: 69}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\" hx-target=\"body\" hx-push-url=\"true\" class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Connection</span>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = SortIndicator(filters, "rtt").Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Uptime (24h)</span></button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>NIP-11</span></button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Type</span></button></th><th class=\"px-2 py-3 text-left text-sm md:text-base font-medium text-gray-500 dark:text-gray-400 uppercase tracking-wider\"><button hx-get=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var4 string
+		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(relaySortURL(filters, "last_check"))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/views/components/relay_table.templ`, Line: 58, Col: 76}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "\" hx-target=\"body\" hx-push-url=\"true\" class=\"flex items-center space-x-1 hover:text-gray-700 dark:hover:text-gray-300\"><span>Last Check</span>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = SortIndicator(filters, "last_check").Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</button></th></tr></thead> <tbody id=\"relay-table-body\" class=\"divide-y divide-gray-200 dark:divide-gray-700\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = RelayTableRows(relays, filters, nextCursor).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</tbody></table><!-- Loading indicator outside table --><div id=\"loading-indicator\" class=\"htmx-indicator text-center py-4\"><div class=\"flex items-center justify-center space-x-2\"><div class=\"animate-spin rounded-full h-4 w-4 border-b-2 border-blue-500\"></div><span class=\"text-gray-400\">Loading more relays...</span></div></div></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</tbody></table><!-- Loading indicator outside table --><div id=\"loading-indicator\" class=\"htmx-indicator text-center py-4\"><div class=\"flex items-center justify-center space-x-2\"><div class=\"animate-spin rounded-full h-4 w-4 border-b-2 border-blue-500\"></div><span class=\"text-gray-400\">Loading more relays...</span></div></div></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -49,4 +112,45 @@ func RelayTable(relays []presentation.RelayTableViewModel) templ.Component {
 	})
 }
 
+func SortIndicator(filters presentation.RelayFiltersViewModel, sort string) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var5 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var5 == nil {
+			templ_7745c5c3_Var5 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		if filters.Sort != sort {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<svg class=\"w-4 h-4 text-gray-400\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M8 9l4-4 4 4m0 6l-4 4-4-4\"></path></svg>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else if filters.Desc {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<svg class=\"w-4 h-4 text-purple-400\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M19 9l-7 7-7-7\"></path></svg>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<svg class=\"w-4 h-4 text-purple-400\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M5 15l7-7 7 7\"></path></svg>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		return nil
+	})
+}
+
 var _ = templruntime.GeneratedTemplate
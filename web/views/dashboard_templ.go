@@ -13,7 +13,7 @@ import (
 	"github.com/danvergara/nostrich_watch_monitor/web/views/components"
 )
 
-func Dashboard(relays []presentation.RelayTableViewModel) templ.Component {
+func Dashboard(relays []presentation.RelayTableViewModel, filters presentation.RelayFiltersViewModel, nextCursor string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -58,7 +58,11 @@ func Dashboard(relays []presentation.RelayTableViewModel) templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = components.RelayTable(relays).Render(ctx, templ_7745c5c3_Buffer)
+			templ_7745c5c3_Err = components.RelayFilters(filters).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = components.RelayTable(relays, filters, nextCursor).Render(ctx, templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}